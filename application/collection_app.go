@@ -0,0 +1,59 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+)
+
+type collectionApp struct {
+	cr repository.CollectionRepository
+}
+
+var _ CollectionAppInterface = &collectionApp{}
+
+func NewCollectionApp(cr repository.CollectionRepository) *collectionApp {
+	return &collectionApp{cr: cr}
+}
+
+type CollectionAppInterface interface {
+	SaveCollection(*entity.Collection) (*entity.Collection, map[string]string)
+	GetCollection(uint64) (*entity.Collection, error)
+	GetCollectionsByUser(uint64, bool) ([]entity.Collection, error)
+	UpdateCollection(*entity.Collection) (*entity.Collection, map[string]string)
+	DeleteCollection(uint64) error
+	AddCollectionItem(*entity.CollectionItem) (*entity.CollectionItem, map[string]string)
+	RemoveCollectionItem(uint64, uint64) error
+	ReorderCollectionItems(uint64, []uint64) map[string]string
+}
+
+func (cApp *collectionApp) SaveCollection(collection *entity.Collection) (*entity.Collection, map[string]string) {
+	return cApp.cr.SaveCollection(collection)
+}
+
+func (cApp *collectionApp) GetCollection(collectionId uint64) (*entity.Collection, error) {
+	return cApp.cr.GetCollection(collectionId)
+}
+
+func (cApp *collectionApp) GetCollectionsByUser(userId uint64, publicOnly bool) ([]entity.Collection, error) {
+	return cApp.cr.GetCollectionsByUser(userId, publicOnly)
+}
+
+func (cApp *collectionApp) UpdateCollection(collection *entity.Collection) (*entity.Collection, map[string]string) {
+	return cApp.cr.UpdateCollection(collection)
+}
+
+func (cApp *collectionApp) DeleteCollection(collectionId uint64) error {
+	return cApp.cr.DeleteCollection(collectionId)
+}
+
+func (cApp *collectionApp) AddCollectionItem(item *entity.CollectionItem) (*entity.CollectionItem, map[string]string) {
+	return cApp.cr.AddCollectionItem(item)
+}
+
+func (cApp *collectionApp) RemoveCollectionItem(collectionId uint64, foodId uint64) error {
+	return cApp.cr.RemoveCollectionItem(collectionId, foodId)
+}
+
+func (cApp *collectionApp) ReorderCollectionItems(collectionId uint64, foodIds []uint64) map[string]string {
+	return cApp.cr.ReorderCollectionItems(collectionId, foodIds)
+}
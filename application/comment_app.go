@@ -0,0 +1,39 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+)
+
+type commentApp struct {
+	cr repository.CommentRepository
+}
+
+var _ CommentAppInterface = &commentApp{}
+
+func NewCommentApp(cr repository.CommentRepository) *commentApp {
+	return &commentApp{cr: cr}
+}
+
+type CommentAppInterface interface {
+	SaveComment(*entity.Comment) (*entity.Comment, map[string]string)
+	GetComment(uint64) (*entity.Comment, error)
+	GetCommentThreads(uint64, *entity.Pagination) ([]*entity.Comment, error)
+	UpdateComment(*entity.Comment) (*entity.Comment, map[string]string)
+}
+
+func (cApp *commentApp) SaveComment(comment *entity.Comment) (*entity.Comment, map[string]string) {
+	return cApp.cr.SaveComment(comment)
+}
+
+func (cApp *commentApp) GetComment(commentId uint64) (*entity.Comment, error) {
+	return cApp.cr.GetComment(commentId)
+}
+
+func (cApp *commentApp) GetCommentThreads(foodId uint64, page *entity.Pagination) ([]*entity.Comment, error) {
+	return cApp.cr.GetCommentThreads(foodId, page)
+}
+
+func (cApp *commentApp) UpdateComment(comment *entity.Comment) (*entity.Comment, map[string]string) {
+	return cApp.cr.UpdateComment(comment)
+}
@@ -0,0 +1,55 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+	"time"
+)
+
+type dataExportApp struct {
+	er repository.DataExportRepository
+}
+
+var _ DataExportAppInterface = &dataExportApp{}
+
+func NewDataExportApp(er repository.DataExportRepository) *dataExportApp {
+	return &dataExportApp{er: er}
+}
+
+type DataExportAppInterface interface {
+	SaveDataExport(*entity.DataExport) (*entity.DataExport, map[string]string)
+	GetDataExport(uint64) (*entity.DataExport, error)
+	GetDataExportByPath(string) (*entity.DataExport, error)
+	GetActiveDataExport(uint64) (*entity.DataExport, error)
+	ClaimDataExport() (*entity.DataExport, error)
+	UpdateDataExport(*entity.DataExport) error
+	DeleteExpiredDataExports(time.Time) ([]entity.DataExport, error)
+}
+
+func (eApp *dataExportApp) SaveDataExport(export *entity.DataExport) (*entity.DataExport, map[string]string) {
+	return eApp.er.SaveDataExport(export)
+}
+
+func (eApp *dataExportApp) GetDataExport(exportId uint64) (*entity.DataExport, error) {
+	return eApp.er.GetDataExport(exportId)
+}
+
+func (eApp *dataExportApp) GetDataExportByPath(path string) (*entity.DataExport, error) {
+	return eApp.er.GetDataExportByPath(path)
+}
+
+func (eApp *dataExportApp) GetActiveDataExport(userId uint64) (*entity.DataExport, error) {
+	return eApp.er.GetActiveDataExport(userId)
+}
+
+func (eApp *dataExportApp) ClaimDataExport() (*entity.DataExport, error) {
+	return eApp.er.ClaimDataExport()
+}
+
+func (eApp *dataExportApp) UpdateDataExport(export *entity.DataExport) error {
+	return eApp.er.UpdateDataExport(export)
+}
+
+func (eApp *dataExportApp) DeleteExpiredDataExports(before time.Time) ([]entity.DataExport, error) {
+	return eApp.er.DeleteExpiredDataExports(before)
+}
@@ -0,0 +1,34 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+)
+
+type favoriteApp struct {
+	fr repository.FavoriteRepository
+}
+
+var _ FavoriteAppInterface = &favoriteApp{}
+
+func NewFavoriteApp(fr repository.FavoriteRepository) *favoriteApp {
+	return &favoriteApp{fr: fr}
+}
+
+type FavoriteAppInterface interface {
+	SaveFavorite(*entity.Favorite) (*entity.Favorite, map[string]string)
+	GetFavoritesByUser(uint64) ([]entity.Favorite, error)
+	DeleteFavorite(uint64, uint64) error
+}
+
+func (fApp *favoriteApp) SaveFavorite(favorite *entity.Favorite) (*entity.Favorite, map[string]string) {
+	return fApp.fr.SaveFavorite(favorite)
+}
+
+func (fApp *favoriteApp) GetFavoritesByUser(userId uint64) ([]entity.Favorite, error) {
+	return fApp.fr.GetFavoritesByUser(userId)
+}
+
+func (fApp *favoriteApp) DeleteFavorite(userId uint64, foodId uint64) error {
+	return fApp.fr.DeleteFavorite(userId, foodId)
+}
@@ -3,23 +3,40 @@ package application
 import (
 	"learning-golang-ddd/domain/entity"
 	"learning-golang-ddd/domain/repository"
+	"time"
 )
 
 type foodApp struct {
 	fr repository.FoodRepository
+	nr repository.NutritionRepository
 }
 
 var _ FoodAppInterface = &foodApp{}
 
+func NewFoodApp(fr repository.FoodRepository, nr repository.NutritionRepository) *foodApp {
+	return &foodApp{fr: fr, nr: nr}
+}
+
 type FoodAppInterface interface {
 	SaveFood(*entity.Food) (*entity.Food, map[string]string)
 	GetAllFood() ([]entity.Food, error)
+	GetAllFoodByFilter(*entity.FoodFilter) ([]entity.Food, error)
+	GetFoodsByUser(uint64) ([]entity.Food, error)
+	GetFoodsChangedSince(time.Time) ([]entity.Food, error)
 	GetFood(uint64) (*entity.Food, error)
+	FoodTitleExists(string) (bool, error)
 	UpdateFood(*entity.Food) (*entity.Food, map[string]string)
+	UpdateFoodStatus(*entity.Food) (*entity.Food, map[string]string)
+	PublishScheduledFoods(time.Time) ([]entity.Food, error)
 	DeleteFood(uint64) error
+	GetDeletedFood(uint64) (*entity.Food, error)
+	GetDeletedFoodsByUser(uint64) ([]entity.Food, error)
+	RestoreFood(uint64) error
+	PurgeDeletedFoods(time.Time) ([]entity.Food, error)
 }
 
 func (fApp *foodApp) SaveFood(food *entity.Food) (*entity.Food, map[string]string) {
+	fApp.computeNutrition(food)
 	return fApp.fr.SaveFood(food)
 }
 
@@ -27,14 +44,69 @@ func (fApp *foodApp) GetAllFood() ([]entity.Food, error) {
 	return fApp.fr.GetAllFood()
 }
 
+func (fApp *foodApp) GetAllFoodByFilter(filter *entity.FoodFilter) ([]entity.Food, error) {
+	return fApp.fr.GetAllFoodByFilter(filter)
+}
+
+func (fApp *foodApp) GetFoodsByUser(userId uint64) ([]entity.Food, error) {
+	return fApp.fr.GetFoodsByUser(userId)
+}
+
+func (fApp *foodApp) GetFoodsChangedSince(since time.Time) ([]entity.Food, error) {
+	return fApp.fr.GetFoodsChangedSince(since)
+}
+
 func (fApp *foodApp) GetFood(foodId uint64) (*entity.Food, error) {
 	return fApp.fr.GetFood(foodId)
 }
 
+func (fApp *foodApp) FoodTitleExists(title string) (bool, error) {
+	return fApp.fr.FoodTitleExists(title)
+}
+
 func (fApp *foodApp) UpdateFood(food *entity.Food) (*entity.Food, map[string]string) {
+	fApp.computeNutrition(food)
 	return fApp.fr.UpdateFood(food)
 }
 
+func (fApp *foodApp) UpdateFoodStatus(food *entity.Food) (*entity.Food, map[string]string) {
+	return fApp.fr.UpdateFoodStatus(food)
+}
+
+func (fApp *foodApp) PublishScheduledFoods(now time.Time) ([]entity.Food, error) {
+	return fApp.fr.PublishScheduledFoods(now)
+}
+
 func (fApp *foodApp) DeleteFood(foodId uint64) error {
 	return fApp.fr.DeleteFood(foodId)
 }
+
+func (fApp *foodApp) GetDeletedFood(foodId uint64) (*entity.Food, error) {
+	return fApp.fr.GetDeletedFood(foodId)
+}
+
+func (fApp *foodApp) GetDeletedFoodsByUser(userId uint64) ([]entity.Food, error) {
+	return fApp.fr.GetDeletedFoodsByUser(userId)
+}
+
+func (fApp *foodApp) RestoreFood(foodId uint64) error {
+	return fApp.fr.RestoreFood(foodId)
+}
+
+func (fApp *foodApp) PurgeDeletedFoods(before time.Time) ([]entity.Food, error) {
+	return fApp.fr.PurgeDeletedFoods(before)
+}
+
+// computeNutrition sums up the nutrition of the food's ingredients.
+// Ingredients that are not in the dataset are skipped.
+func (fApp *foodApp) computeNutrition(food *entity.Food) {
+	nutrition := entity.Nutrition{}
+	for _, ingredient := range food.Ingredients {
+		fact, err := fApp.nr.GetNutritionFact(ingredient.Name)
+		if err != nil {
+			continue
+		}
+		nutrition.Add(fact, ingredient.Grams(fact.ServingGrams))
+	}
+	food.Nutrition = nutrition
+}
@@ -0,0 +1,49 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+)
+
+type foodClipApp struct {
+	cr repository.FoodClipRepository
+}
+
+var _ FoodClipAppInterface = &foodClipApp{}
+
+func NewFoodClipApp(cr repository.FoodClipRepository) *foodClipApp {
+	return &foodClipApp{cr: cr}
+}
+
+type FoodClipAppInterface interface {
+	AddClip(*entity.FoodClip) (*entity.FoodClip, map[string]string)
+	GetClip(uint64) (*entity.FoodClip, error)
+	GetClipsByFood(uint64) ([]entity.FoodClip, error)
+	UpdateClip(*entity.FoodClip) (*entity.FoodClip, map[string]string)
+	DeleteClip(uint64) error
+	ReorderClips(uint64, []uint64) map[string]string
+}
+
+func (cApp *foodClipApp) AddClip(clip *entity.FoodClip) (*entity.FoodClip, map[string]string) {
+	return cApp.cr.AddClip(clip)
+}
+
+func (cApp *foodClipApp) GetClip(clipId uint64) (*entity.FoodClip, error) {
+	return cApp.cr.GetClip(clipId)
+}
+
+func (cApp *foodClipApp) GetClipsByFood(foodId uint64) ([]entity.FoodClip, error) {
+	return cApp.cr.GetClipsByFood(foodId)
+}
+
+func (cApp *foodClipApp) UpdateClip(clip *entity.FoodClip) (*entity.FoodClip, map[string]string) {
+	return cApp.cr.UpdateClip(clip)
+}
+
+func (cApp *foodClipApp) DeleteClip(clipId uint64) error {
+	return cApp.cr.DeleteClip(clipId)
+}
+
+func (cApp *foodClipApp) ReorderClips(foodId uint64, clipIds []uint64) map[string]string {
+	return cApp.cr.ReorderClips(foodId, clipIds)
+}
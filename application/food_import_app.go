@@ -0,0 +1,39 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+)
+
+type foodImportApp struct {
+	ir repository.FoodImportRepository
+}
+
+var _ FoodImportAppInterface = &foodImportApp{}
+
+func NewFoodImportApp(ir repository.FoodImportRepository) *foodImportApp {
+	return &foodImportApp{ir: ir}
+}
+
+type FoodImportAppInterface interface {
+	SaveFoodImport(*entity.FoodImport) (*entity.FoodImport, map[string]string)
+	GetFoodImport(uint64) (*entity.FoodImport, error)
+	ClaimFoodImport() (*entity.FoodImport, error)
+	UpdateFoodImport(*entity.FoodImport) error
+}
+
+func (iApp *foodImportApp) SaveFoodImport(foodImport *entity.FoodImport) (*entity.FoodImport, map[string]string) {
+	return iApp.ir.SaveFoodImport(foodImport)
+}
+
+func (iApp *foodImportApp) GetFoodImport(importId uint64) (*entity.FoodImport, error) {
+	return iApp.ir.GetFoodImport(importId)
+}
+
+func (iApp *foodImportApp) ClaimFoodImport() (*entity.FoodImport, error) {
+	return iApp.ir.ClaimFoodImport()
+}
+
+func (iApp *foodImportApp) UpdateFoodImport(foodImport *entity.FoodImport) error {
+	return iApp.ir.UpdateFoodImport(foodImport)
+}
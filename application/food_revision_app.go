@@ -0,0 +1,29 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+)
+
+type foodRevisionApp struct {
+	rr repository.FoodRevisionRepository
+}
+
+var _ FoodRevisionAppInterface = &foodRevisionApp{}
+
+func NewFoodRevisionApp(rr repository.FoodRevisionRepository) *foodRevisionApp {
+	return &foodRevisionApp{rr: rr}
+}
+
+type FoodRevisionAppInterface interface {
+	GetRevisions(uint64) ([]entity.FoodRevision, error)
+	GetRevision(uint64, int) (*entity.FoodRevision, error)
+}
+
+func (rApp *foodRevisionApp) GetRevisions(foodId uint64) ([]entity.FoodRevision, error) {
+	return rApp.rr.GetRevisions(foodId)
+}
+
+func (rApp *foodRevisionApp) GetRevision(foodId uint64, revision int) (*entity.FoodRevision, error) {
+	return rApp.rr.GetRevision(foodId, revision)
+}
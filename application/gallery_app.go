@@ -0,0 +1,85 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+	"time"
+)
+
+type galleryApp struct {
+	gr repository.GalleryRepository
+}
+
+var _ GalleryAppInterface = &galleryApp{}
+
+func NewGalleryApp(gr repository.GalleryRepository) *galleryApp {
+	return &galleryApp{gr: gr}
+}
+
+type GalleryAppInterface interface {
+	AddImage(*entity.GalleryImage) (*entity.GalleryImage, map[string]string)
+	GetImage(uint64) (*entity.GalleryImage, error)
+	GetImagesByFood(uint64) ([]entity.GalleryImage, error)
+	UserHasImage(uint64, string) (bool, error)
+	UpdateImage(*entity.GalleryImage) (*entity.GalleryImage, map[string]string)
+	SetCoverImage(uint64, uint64) map[string]string
+	DeleteImage(uint64) error
+	ReorderImages(uint64, []uint64) map[string]string
+	CountImageRefs(string) (int64, error)
+	CountImageRefsByKey(string) (int64, error)
+	IsImageVisibleTo(string, uint64) (bool, error)
+	LockImage(string, func() error) error
+	HoldImage(string, time.Time) error
+}
+
+func (gApp *galleryApp) AddImage(image *entity.GalleryImage) (*entity.GalleryImage, map[string]string) {
+	return gApp.gr.AddImage(image)
+}
+
+func (gApp *galleryApp) GetImage(imageId uint64) (*entity.GalleryImage, error) {
+	return gApp.gr.GetImage(imageId)
+}
+
+func (gApp *galleryApp) GetImagesByFood(foodId uint64) ([]entity.GalleryImage, error) {
+	return gApp.gr.GetImagesByFood(foodId)
+}
+
+func (gApp *galleryApp) UserHasImage(userId uint64, path string) (bool, error) {
+	return gApp.gr.UserHasImage(userId, path)
+}
+
+func (gApp *galleryApp) UpdateImage(image *entity.GalleryImage) (*entity.GalleryImage, map[string]string) {
+	return gApp.gr.UpdateImage(image)
+}
+
+func (gApp *galleryApp) SetCoverImage(foodId uint64, imageId uint64) map[string]string {
+	return gApp.gr.SetCoverImage(foodId, imageId)
+}
+
+func (gApp *galleryApp) DeleteImage(imageId uint64) error {
+	return gApp.gr.DeleteImage(imageId)
+}
+
+func (gApp *galleryApp) ReorderImages(foodId uint64, imageIds []uint64) map[string]string {
+	return gApp.gr.ReorderImages(foodId, imageIds)
+}
+
+func (gApp *galleryApp) CountImageRefs(path string) (int64, error) {
+	return gApp.gr.CountImageRefs(path)
+}
+
+func (gApp *galleryApp) CountImageRefsByKey(key string) (int64, error) {
+	return gApp.gr.CountImageRefsByKey(key)
+}
+
+func (gApp *galleryApp) IsImageVisibleTo(path string, userId uint64) (bool, error) {
+	return gApp.gr.IsImageVisibleTo(path, userId)
+}
+
+func (gApp *galleryApp) LockImage(key string, fn func() error) error {
+	return gApp.gr.LockImage(key, fn)
+}
+
+func (gApp *galleryApp) HoldImage(path string, until time.Time) error {
+	return gApp.gr.HoldImage(path, until)
+}
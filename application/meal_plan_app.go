@@ -0,0 +1,65 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+	"time"
+)
+
+type mealPlanApp struct {
+	mr repository.MealPlanRepository
+}
+
+var _ MealPlanAppInterface = &mealPlanApp{}
+
+func NewMealPlanApp(mr repository.MealPlanRepository) *mealPlanApp {
+	return &mealPlanApp{mr: mr}
+}
+
+type MealPlanAppInterface interface {
+	SaveMealPlanEntry(*entity.MealPlanEntry) (*entity.MealPlanEntry, map[string]string)
+	GetMealPlanEntry(uint64) (*entity.MealPlanEntry, error)
+	GetMealPlan(uint64, time.Time, time.Time) (*entity.MealPlan, error)
+	UpdateMealPlanEntry(*entity.MealPlanEntry) (*entity.MealPlanEntry, map[string]string)
+	DeleteMealPlanEntry(uint64) error
+	CopyMealPlanWeek(uint64, time.Time, time.Time, bool) ([]entity.MealPlanEntry, error)
+	SaveCalendarFeed(*entity.CalendarFeed) (*entity.CalendarFeed, map[string]string)
+	GetCalendarFeed(string) (*entity.CalendarFeed, error)
+}
+
+func (mApp *mealPlanApp) SaveMealPlanEntry(entry *entity.MealPlanEntry) (*entity.MealPlanEntry, map[string]string) {
+	return mApp.mr.SaveMealPlanEntry(entry)
+}
+
+func (mApp *mealPlanApp) GetMealPlanEntry(entryId uint64) (*entity.MealPlanEntry, error) {
+	return mApp.mr.GetMealPlanEntry(entryId)
+}
+
+// GetMealPlan returns the plan of the user between the two days, both included
+func (mApp *mealPlanApp) GetMealPlan(userId uint64, from time.Time, to time.Time) (*entity.MealPlan, error) {
+	entries, err := mApp.mr.GetMealPlanEntries(userId, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewMealPlan(from, to, entries), nil
+}
+
+func (mApp *mealPlanApp) UpdateMealPlanEntry(entry *entity.MealPlanEntry) (*entity.MealPlanEntry, map[string]string) {
+	return mApp.mr.UpdateMealPlanEntry(entry)
+}
+
+func (mApp *mealPlanApp) DeleteMealPlanEntry(entryId uint64) error {
+	return mApp.mr.DeleteMealPlanEntry(entryId)
+}
+
+func (mApp *mealPlanApp) CopyMealPlanWeek(userId uint64, from time.Time, to time.Time, replace bool) ([]entity.MealPlanEntry, error) {
+	return mApp.mr.CopyMealPlanWeek(userId, entity.WeekStart(from), entity.WeekStart(to), replace)
+}
+
+func (mApp *mealPlanApp) SaveCalendarFeed(feed *entity.CalendarFeed) (*entity.CalendarFeed, map[string]string) {
+	return mApp.mr.SaveCalendarFeed(feed)
+}
+
+func (mApp *mealPlanApp) GetCalendarFeed(token string) (*entity.CalendarFeed, error) {
+	return mApp.mr.GetCalendarFeed(token)
+}
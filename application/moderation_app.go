@@ -0,0 +1,63 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+	"log"
+)
+
+type moderationApp struct {
+	rr repository.ReportRepository
+	kf *entity.KeywordFilter
+}
+
+var _ ModerationAppInterface = &moderationApp{}
+
+func NewModerationApp(rr repository.ReportRepository, kf *entity.KeywordFilter) *moderationApp {
+	return &moderationApp{rr: rr, kf: kf}
+}
+
+type ModerationAppInterface interface {
+	SaveReport(*entity.Report) (*entity.Report, map[string]string)
+	GetReport(uint64) (*entity.Report, error)
+	GetReports(entity.ReportStatus, *entity.Pagination) ([]entity.Report, error)
+	ModerateReport(*entity.Report, entity.ModerationAction, uint64) ([]entity.Report, map[string]string)
+	AutoFlag(entity.ReportTarget, uint64, uint64, ...string)
+}
+
+func (mApp *moderationApp) SaveReport(report *entity.Report) (*entity.Report, map[string]string) {
+	return mApp.rr.SaveReport(report)
+}
+
+func (mApp *moderationApp) GetReport(reportId uint64) (*entity.Report, error) {
+	return mApp.rr.GetReport(reportId)
+}
+
+func (mApp *moderationApp) GetReports(status entity.ReportStatus, page *entity.Pagination) ([]entity.Report, error) {
+	return mApp.rr.GetReports(status, page)
+}
+
+func (mApp *moderationApp) ModerateReport(report *entity.Report, action entity.ModerationAction, moderatorId uint64) ([]entity.Report, map[string]string) {
+	return mApp.rr.ModerateReport(report, action, moderatorId)
+}
+
+// AutoFlag puts the content in the moderation queue when it contains some of the moderation keywords.
+// The content is saved already, so a failure is only logged.
+func (mApp *moderationApp) AutoFlag(target entity.ReportTarget, targetId uint64, authorId uint64, texts ...string) {
+	matches := mApp.kf.Match(texts...)
+	if len(matches) == 0 {
+		return
+	}
+	// an edit should not queue the same content twice
+	flagged, err := mApp.rr.HasOpenReport(target, targetId, entity.ReportReasonKeyword)
+	if err != nil {
+		log.Printf("cannot auto-flag %s %d: %v", target, targetId, err)
+		return
+	}
+	if flagged {
+		return
+	}
+	if _, saveErr := mApp.rr.SaveReport(entity.NewKeywordReport(target, targetId, authorId, matches)); saveErr != nil {
+		log.Printf("cannot auto-flag %s %d: %v", target, targetId, saveErr)
+	}
+}
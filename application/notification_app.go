@@ -0,0 +1,34 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+)
+
+type notificationApp struct {
+	nr repository.NotificationRepository
+}
+
+var _ NotificationAppInterface = &notificationApp{}
+
+func NewNotificationApp(nr repository.NotificationRepository) *notificationApp {
+	return &notificationApp{nr: nr}
+}
+
+type NotificationAppInterface interface {
+	GetNotification(uint64) (*entity.Notification, error)
+	GetNotifications(uint64, *entity.Pagination) ([]entity.Notification, error)
+	MarkNotificationRead(*entity.Notification) map[string]string
+}
+
+func (nApp *notificationApp) GetNotification(notificationId uint64) (*entity.Notification, error) {
+	return nApp.nr.GetNotification(notificationId)
+}
+
+func (nApp *notificationApp) GetNotifications(userId uint64, page *entity.Pagination) ([]entity.Notification, error) {
+	return nApp.nr.GetNotifications(userId, page)
+}
+
+func (nApp *notificationApp) MarkNotificationRead(notification *entity.Notification) map[string]string {
+	return nApp.nr.MarkNotificationRead(notification)
+}
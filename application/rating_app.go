@@ -0,0 +1,34 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+)
+
+type ratingApp struct {
+	rr repository.RatingRepository
+}
+
+var _ RatingAppInterface = &ratingApp{}
+
+func NewRatingApp(rr repository.RatingRepository) *ratingApp {
+	return &ratingApp{rr: rr}
+}
+
+type RatingAppInterface interface {
+	SaveRating(*entity.Rating) (*entity.Rating, map[string]string)
+	GetRating(uint64, uint64) (*entity.Rating, error)
+	DeleteRating(uint64, uint64) error
+}
+
+func (rApp *ratingApp) SaveRating(rating *entity.Rating) (*entity.Rating, map[string]string) {
+	return rApp.rr.SaveRating(rating)
+}
+
+func (rApp *ratingApp) GetRating(foodId uint64, userId uint64) (*entity.Rating, error) {
+	return rApp.rr.GetRating(foodId, userId)
+}
+
+func (rApp *ratingApp) DeleteRating(foodId uint64, userId uint64) error {
+	return rApp.rr.DeleteRating(foodId, userId)
+}
@@ -0,0 +1,44 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+)
+
+type reviewApp struct {
+	rr repository.ReviewRepository
+}
+
+var _ ReviewAppInterface = &reviewApp{}
+
+func NewReviewApp(rr repository.ReviewRepository) *reviewApp {
+	return &reviewApp{rr: rr}
+}
+
+type ReviewAppInterface interface {
+	SaveReview(*entity.Review) (*entity.Review, map[string]string)
+	GetReview(uint64) (*entity.Review, error)
+	GetReviewsByFood(uint64) ([]entity.Review, error)
+	UpdateReview(*entity.Review) (*entity.Review, map[string]string)
+	DeleteReview(uint64) error
+}
+
+func (rApp *reviewApp) SaveReview(review *entity.Review) (*entity.Review, map[string]string) {
+	return rApp.rr.SaveReview(review)
+}
+
+func (rApp *reviewApp) GetReview(reviewId uint64) (*entity.Review, error) {
+	return rApp.rr.GetReview(reviewId)
+}
+
+func (rApp *reviewApp) GetReviewsByFood(foodId uint64) ([]entity.Review, error) {
+	return rApp.rr.GetReviewsByFood(foodId)
+}
+
+func (rApp *reviewApp) UpdateReview(review *entity.Review) (*entity.Review, map[string]string) {
+	return rApp.rr.UpdateReview(review)
+}
+
+func (rApp *reviewApp) DeleteReview(reviewId uint64) error {
+	return rApp.rr.DeleteReview(reviewId)
+}
@@ -0,0 +1,78 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+)
+
+type shoppingListApp struct {
+	sr repository.ShoppingListRepository
+	nr repository.NutritionRepository
+}
+
+var _ ShoppingListAppInterface = &shoppingListApp{}
+
+func NewShoppingListApp(sr repository.ShoppingListRepository, nr repository.NutritionRepository) *shoppingListApp {
+	return &shoppingListApp{sr: sr, nr: nr}
+}
+
+type ShoppingListAppInterface interface {
+	GenerateShoppingList(*entity.ShoppingList, []entity.Food, map[uint64]float64) (*entity.ShoppingList, map[string]string)
+	GetShoppingList(uint64) (*entity.ShoppingList, error)
+	GetShoppingListsByUser(uint64) ([]entity.ShoppingList, error)
+	UpdateShoppingListShare(*entity.ShoppingList) map[string]string
+	DeleteShoppingList(uint64) error
+	SaveShoppingListItem(*entity.ShoppingListItem) (*entity.ShoppingListItem, map[string]string)
+	GetShoppingListItem(uint64) (*entity.ShoppingListItem, error)
+	UpdateShoppingListItem(*entity.ShoppingListItem) (*entity.ShoppingListItem, map[string]string)
+	DeleteShoppingListItem(uint64) error
+}
+
+// GenerateShoppingList merges the ingredients of the foods into the list and saves it.
+// servings tells how many times each food is cooked, by food id.
+func (sApp *shoppingListApp) GenerateShoppingList(list *entity.ShoppingList, foods []entity.Food, servings map[uint64]float64) (*entity.ShoppingList, map[string]string) {
+	for _, food := range foods {
+		for _, ingredient := range food.Ingredients {
+			// the aisle comes from the nutrition dataset, the unknown ingredients end up in "other"
+			aisle := ""
+			if fact, err := sApp.nr.GetNutritionFact(ingredient.Name); err == nil {
+				aisle = fact.Aisle
+			}
+			list.AddIngredient(ingredient, servings[food.ID], aisle)
+		}
+	}
+	list.Tidy()
+	return sApp.sr.SaveShoppingList(list)
+}
+
+func (sApp *shoppingListApp) GetShoppingList(listId uint64) (*entity.ShoppingList, error) {
+	return sApp.sr.GetShoppingList(listId)
+}
+
+func (sApp *shoppingListApp) GetShoppingListsByUser(userId uint64) ([]entity.ShoppingList, error) {
+	return sApp.sr.GetShoppingListsByUser(userId)
+}
+
+func (sApp *shoppingListApp) UpdateShoppingListShare(list *entity.ShoppingList) map[string]string {
+	return sApp.sr.UpdateShoppingListShare(list)
+}
+
+func (sApp *shoppingListApp) DeleteShoppingList(listId uint64) error {
+	return sApp.sr.DeleteShoppingList(listId)
+}
+
+func (sApp *shoppingListApp) SaveShoppingListItem(item *entity.ShoppingListItem) (*entity.ShoppingListItem, map[string]string) {
+	return sApp.sr.SaveShoppingListItem(item)
+}
+
+func (sApp *shoppingListApp) GetShoppingListItem(itemId uint64) (*entity.ShoppingListItem, error) {
+	return sApp.sr.GetShoppingListItem(itemId)
+}
+
+func (sApp *shoppingListApp) UpdateShoppingListItem(item *entity.ShoppingListItem) (*entity.ShoppingListItem, map[string]string) {
+	return sApp.sr.UpdateShoppingListItem(item)
+}
+
+func (sApp *shoppingListApp) DeleteShoppingListItem(itemId uint64) error {
+	return sApp.sr.DeleteShoppingListItem(itemId)
+}
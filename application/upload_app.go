@@ -0,0 +1,59 @@
+package application
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+)
+
+type uploadApp struct {
+	ur repository.UploadRepository
+}
+
+var _ UploadAppInterface = &uploadApp{}
+
+func NewUploadApp(ur repository.UploadRepository) *uploadApp {
+	return &uploadApp{ur: ur}
+}
+
+type UploadAppInterface interface {
+	SaveUpload(*entity.Upload) (*entity.Upload, map[string]string)
+	GetUpload(uint64) (*entity.Upload, error)
+	GetUploadByKey(string) (*entity.Upload, error)
+	UpdateUpload(*entity.Upload) error
+	UpdateUploadOffset(upload *entity.Upload, from int64) error
+	DeleteUpload(uint64) error
+	AttachUpload(uploadId uint64, userId uint64, kind entity.MediaKind) (*entity.Upload, map[string]string)
+	DetachUpload(uint64) error
+}
+
+func (upApp *uploadApp) SaveUpload(upload *entity.Upload) (*entity.Upload, map[string]string) {
+	return upApp.ur.SaveUpload(upload)
+}
+
+func (upApp *uploadApp) GetUpload(uploadId uint64) (*entity.Upload, error) {
+	return upApp.ur.GetUpload(uploadId)
+}
+
+func (upApp *uploadApp) GetUploadByKey(key string) (*entity.Upload, error) {
+	return upApp.ur.GetUploadByKey(key)
+}
+
+func (upApp *uploadApp) UpdateUpload(upload *entity.Upload) error {
+	return upApp.ur.UpdateUpload(upload)
+}
+
+func (upApp *uploadApp) UpdateUploadOffset(upload *entity.Upload, from int64) error {
+	return upApp.ur.UpdateUploadOffset(upload, from)
+}
+
+func (upApp *uploadApp) DeleteUpload(uploadId uint64) error {
+	return upApp.ur.DeleteUpload(uploadId)
+}
+
+func (upApp *uploadApp) AttachUpload(uploadId uint64, userId uint64, kind entity.MediaKind) (*entity.Upload, map[string]string) {
+	return upApp.ur.AttachUpload(uploadId, userId, kind)
+}
+
+func (upApp *uploadApp) DetachUpload(uploadId uint64) error {
+	return upApp.ur.DetachUpload(uploadId)
+}
@@ -3,6 +3,7 @@ package application
 import (
 	"learning-golang-ddd/domain/entity"
 	"learning-golang-ddd/domain/repository"
+	"time"
 )
 
 type userApp struct {
@@ -17,6 +18,9 @@ type UserAppInterface interface {
 	GetUsers() ([]entity.User, error)
 	GetUser(uint64) (*entity.User, error)
 	GetUserByFilter(*entity.User) (*entity.User, map[string]string)
+	DeleteUser(uint64) error
+	RestoreUser(uint64) error
+	PurgeDeletedUsers(time.Time) ([]entity.User, error)
 }
 
 func (uApp *userApp) SaveUser(user *entity.User) (*entity.User, map[string]string) {
@@ -34,3 +38,15 @@ func (uApp *userApp) GetUser(uId uint64) (*entity.User, error) {
 func (uApp *userApp) GetUserByFilter(user *entity.User) (*entity.User, map[string]string) {
 	return uApp.ur.GetUserByFilter(user)
 }
+
+func (uApp *userApp) DeleteUser(uId uint64) error {
+	return uApp.ur.DeleteUser(uId)
+}
+
+func (uApp *userApp) RestoreUser(uId uint64) error {
+	return uApp.ur.RestoreUser(uId)
+}
+
+func (uApp *userApp) PurgeDeletedUsers(before time.Time) ([]entity.User, error) {
+	return uApp.ur.PurgeDeletedUsers(before)
+}
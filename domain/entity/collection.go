@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"html"
+	"strings"
+	"time"
+)
+
+// Collection is a named list of foods, e.g "weeknight dinners"
+type Collection struct {
+	ID        uint64           `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint64           `gorm:"not null;index" json:"user_id"`
+	Name      string           `gorm:"size:100;not null;" json:"name"`
+	IsPublic  bool             `gorm:"not null;default:false" json:"is_public"`
+	Items     []CollectionItem `gorm:"foreignKey:CollectionID" json:"items"`
+	CreatedAt time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+type CollectionItem struct {
+	ID           uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	CollectionID uint64    `gorm:"not null;uniqueIndex:idx_collection_item_food" json:"collection_id"`
+	FoodID       uint64    `gorm:"not null;uniqueIndex:idx_collection_item_food;index" json:"food_id"`
+	Food         *Food     `gorm:"foreignKey:FoodID" json:"food,omitempty"`
+	Position     int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+func (c *Collection) Prepare() {
+	c.Name = html.EscapeString(strings.TrimSpace(c.Name))
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = time.Now()
+}
+
+func (c *Collection) Validate() map[string]string {
+	var errorMessages = make(map[string]string)
+
+	if c.Name == "" || c.Name == "null" {
+		errorMessages["name_required"] = "collection name is required"
+	}
+	if len(c.Name) > 100 {
+		errorMessages["name_too_long"] = "collection name should not be more than 100 characters"
+	}
+	return errorMessages
+}
+
+// CanView reports whether the user can see the collection. Private collections are only visible to their owner.
+func (c *Collection) CanView(userId uint64) bool {
+	return c.IsPublic || c.UserID == userId
+}
+
+// HideFoodsFrom leaves out the foods of the items the user cannot see, e.g a food moved back to draft
+// after it was added. The items are kept, with their food_id.
+func (c *Collection) HideFoodsFrom(userId uint64) {
+	for i := range c.Items {
+		if c.Items[i].Food != nil && !c.Items[i].Food.IsVisibleTo(userId) {
+			c.Items[i].Food = nil
+		}
+	}
+}
+
+// SignURLs returns a copy of the collection with the urls of its foods signed, see Food.SignURLs
+func (c *Collection) SignURLs(sign URLSigner) *Collection {
+	signed := *c
+	if c.Items != nil {
+		signed.Items = make([]CollectionItem, len(c.Items))
+		for i, item := range c.Items {
+			item.Food = item.Food.SignURLs(sign)
+			signed.Items[i] = item
+		}
+	}
+	return &signed
+}
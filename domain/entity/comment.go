@@ -0,0 +1,146 @@
+package entity
+
+import (
+	"html"
+	"strings"
+	"time"
+)
+
+const (
+	// a reply to a comment at this depth is not allowed
+	MaxCommentDepth = 5
+	// comments can only be edited by their author within this window
+	CommentEditWindow = 15 * time.Minute
+
+	deletedCommentBody = "[deleted]"
+	hiddenCommentBody  = "[hidden]"
+)
+
+type Comment struct {
+	ID     uint64 `gorm:"primary_key;auto_increment" json:"id"`
+	FoodID uint64 `gorm:"not null;index" json:"food_id"`
+	UserID uint64 `gorm:"not null;" json:"user_id"`
+	// ParentID is nil for the top level comments
+	ParentID *uint64 `gorm:"index" json:"parent_id"`
+	// RootID is the id of the top level comment of the thread, so a whole thread can be loaded at once
+	RootID    uint64     `gorm:"index" json:"root_id"`
+	Depth     int        `gorm:"not null;default:0" json:"depth"`
+	Body      string     `gorm:"type:text;not null;" json:"body"`
+	Hidden    bool       `gorm:"not null;default:false" json:"hidden"`
+	HiddenBy  *uint64    `json:"-"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	Replies   []*Comment `gorm:"-" json:"replies"`
+}
+
+func (c *Comment) Prepare() {
+	c.Body = html.EscapeString(strings.TrimSpace(c.Body))
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = time.Now()
+}
+
+func (c *Comment) Validate() map[string]string {
+	var errorMessages = make(map[string]string)
+
+	if c.Body == "" || c.Body == "null" {
+		errorMessages["body_required"] = "comment body is required"
+	}
+	if len(c.Body) > 2000 {
+		errorMessages["body_too_long"] = "comment should not be more than 2000 characters"
+	}
+	return errorMessages
+}
+
+// ReplyTo makes the comment a reply of parent
+func (c *Comment) ReplyTo(parent *Comment) map[string]string {
+	var errorMessages = make(map[string]string)
+
+	if parent.FoodID != c.FoodID {
+		errorMessages["invalid_parent"] = "the parent comment belongs to another food"
+		return errorMessages
+	}
+	if parent.IsDeleted() {
+		errorMessages["invalid_parent"] = "cannot reply to a deleted comment"
+		return errorMessages
+	}
+	if parent.Depth+1 > MaxCommentDepth {
+		errorMessages["max_depth"] = "this thread is too deep to reply to"
+		return errorMessages
+	}
+	c.ParentID = &parent.ID
+	c.RootID = parent.RootID
+	c.Depth = parent.Depth + 1
+	return errorMessages
+}
+
+func (c *Comment) CanEdit(userId uint64) map[string]string {
+	var errorMessages = make(map[string]string)
+
+	if c.UserID != userId {
+		errorMessages["not_author"] = "you are not the author of this comment"
+	} else if c.IsDeleted() {
+		errorMessages["comment_deleted"] = "the comment was deleted"
+	} else if time.Since(c.CreatedAt) > CommentEditWindow {
+		errorMessages["edit_window_closed"] = "comments can only be edited within 15 minutes"
+	}
+	return errorMessages
+}
+
+func (c *Comment) IsDeleted() bool {
+	return c.DeletedAt != nil
+}
+
+// SoftDelete keeps the comment in its thread, so the replies are not lost
+func (c *Comment) SoftDelete() {
+	now := time.Now()
+	c.DeletedAt = &now
+}
+
+func (c *Comment) Hide(moderatorId uint64) {
+	c.Hidden = true
+	c.HiddenBy = &moderatorId
+}
+
+func (c *Comment) Unhide() {
+	c.Hidden = false
+	c.HiddenBy = nil
+}
+
+// Redact replaces the body of deleted and hidden comments with a placeholder
+func (c *Comment) Redact() {
+	switch {
+	case c.IsDeleted():
+		c.Body = deletedCommentBody
+		c.UserID = 0
+	case c.Hidden:
+		c.Body = hiddenCommentBody
+	}
+}
+
+// BuildCommentThreads nests the replies under the top level comments they belong to.
+// The replies should be ordered by creation time.
+func BuildCommentThreads(roots []Comment, replies []Comment) []*Comment {
+	byId := make(map[uint64]*Comment, len(roots)+len(replies))
+	threads := make([]*Comment, len(roots))
+	for i := range roots {
+		roots[i].Replies = []*Comment{}
+		roots[i].Redact()
+		threads[i] = &roots[i]
+		byId[roots[i].ID] = &roots[i]
+	}
+	for i := range replies {
+		replies[i].Replies = []*Comment{}
+		replies[i].Redact()
+		byId[replies[i].ID] = &replies[i]
+	}
+	for i := range replies {
+		if replies[i].ParentID == nil {
+			continue
+		}
+		if parent, ok := byId[*replies[i].ParentID]; ok {
+			parent.Replies = append(parent.Replies, &replies[i])
+		}
+	}
+	return threads
+}
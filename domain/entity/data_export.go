@@ -0,0 +1,88 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+type DataExportStatus string
+
+const (
+	DataExportStatusPending DataExportStatus = "pending"
+	DataExportStatusRunning DataExportStatus = "running"
+	DataExportStatusReady   DataExportStatus = "ready"
+	DataExportStatusFailed  DataExportStatus = "failed"
+)
+
+// RunningExportTimeout is how long a running export can go without saving its progress before it is
+// considered dead, e.g the app was stopped while building it. It is built again.
+const RunningExportTimeout = 15 * time.Minute
+
+// DataExport is an archive of everything we hold about a user, built in the background.
+// Once ready, it can be downloaded with its token until it expires.
+type DataExport struct {
+	ID         uint64           `gorm:"primary_key;auto_increment" json:"id"`
+	UserID     uint64           `gorm:"not null;index" json:"user_id"`
+	Status     DataExportStatus `gorm:"size:20;not null;default:pending;index" json:"status"`
+	Path       string           `gorm:"size:255;" json:"-"`
+	Token      string           `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Error      string           `gorm:"size:255;" json:"error,omitempty"`
+	ExpiresAt  *time.Time       `gorm:"index" json:"expires_at"`
+	CreatedAt  time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt  time.Time        `gorm:"default:CURRENT_TIMESTAMP;index" json:"updated_at"`
+	FinishedAt *time.Time       `json:"finished_at"`
+}
+
+// ExportProfile is the user as written to the archive, without the password hash
+type ExportProfile struct {
+	ID        uint64    `json:"id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewDataExport(userId uint64) (*DataExport, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	return &DataExport{
+		UserID:    userId,
+		Status:    DataExportStatusPending,
+		Token:     hex.EncodeToString(token),
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// IsActive reports whether the export is still being built, a user can only have one of those at a time
+func (e *DataExport) IsActive() bool {
+	return e.Status == DataExportStatusPending || e.Status == DataExportStatusRunning
+}
+
+// CanDownload reports whether the archive is ready and its link has not expired
+func (e *DataExport) CanDownload(now time.Time) bool {
+	return e.Status == DataExportStatusReady && e.ExpiresAt != nil && now.Before(*e.ExpiresAt)
+}
+
+// Ready marks the export as downloadable until now+ttl
+func (e *DataExport) Ready(path string, ttl time.Duration) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	e.Status = DataExportStatusReady
+	e.Path = path
+	e.FinishedAt = &now
+	e.ExpiresAt = &expiresAt
+}
+
+// Fail marks the export as failed, it is kept for the same ttl as a ready one so the user can see why
+func (e *DataExport) Fail(reason string, ttl time.Duration) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	e.Status = DataExportStatusFailed
+	e.Error = reason
+	e.FinishedAt = &now
+	e.ExpiresAt = &expiresAt
+}
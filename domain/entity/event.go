@@ -0,0 +1,43 @@
+package entity
+
+import "time"
+
+const (
+	EventFoodPublished = "food.published"
+	EventContentHidden = "content.hidden"
+)
+
+// Event is something that happened in the domain that other systems may want to react to
+type Event struct {
+	Name       string                 `json:"name"`
+	Payload    map[string]interface{} `json:"payload"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+func NewEvent(name string, payload map[string]interface{}) *Event {
+	return &Event{
+		Name:       name,
+		Payload:    payload,
+		OccurredAt: time.Now(),
+	}
+}
+
+func NewFoodPublishedEvent(food *Food) *Event {
+	return NewEvent(EventFoodPublished, map[string]interface{}{
+		"food_id":      food.ID,
+		"user_id":      food.UserID,
+		"title":        food.Title,
+		"published_at": food.PublishedAt,
+	})
+}
+
+// NewContentHiddenEvent is published when a moderator hides reported content
+func NewContentHiddenEvent(report *Report) *Event {
+	return NewEvent(EventContentHidden, map[string]interface{}{
+		"target_type": report.TargetType,
+		"target_id":   report.TargetID,
+		"author_id":   report.AuthorID,
+		"action":      report.Action,
+		"reason":      report.Reason,
+	})
+}
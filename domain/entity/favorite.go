@@ -0,0 +1,33 @@
+package entity
+
+import "time"
+
+type Favorite struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint64    `gorm:"not null;uniqueIndex:idx_favorite_user_food" json:"user_id"`
+	FoodID    uint64    `gorm:"not null;uniqueIndex:idx_favorite_user_food" json:"food_id"`
+	Food      *Food     `gorm:"foreignKey:FoodID" json:"food,omitempty"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// HideFavoriteFoodsFrom leaves out the foods of the favorites the user cannot see anymore, e.g a food moved back to draft
+func HideFavoriteFoodsFrom(favorites []Favorite, userId uint64) {
+	for i := range favorites {
+		if favorites[i].Food != nil && !favorites[i].Food.IsVisibleTo(userId) {
+			favorites[i].Food = nil
+		}
+	}
+}
+
+// SignFavorites returns the favorites with the urls of their foods signed, see Food.SignURLs
+func SignFavorites(favorites []Favorite, sign URLSigner) []Favorite {
+	if favorites == nil {
+		return nil
+	}
+	signed := make([]Favorite, len(favorites))
+	for i, favorite := range favorites {
+		favorite.Food = favorite.Food.SignURLs(sign)
+		signed[i] = favorite
+	}
+	return signed
+}
@@ -1,20 +1,103 @@
 package entity
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"html"
 	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
+// ErrTitleTaken is returned when a food comes back from the trash while another food took its title
+var ErrTitleTaken = errors.New("food title already taken")
+
 type Food struct {
-	ID          uint64     `gorm:"primary_key;auto_increment" json:"id"`
-	UserID      uint64     `gorm:"size:100;not null;" json:"user_id"`
-	Title       string     `gorm:"size:100;not null;unique" json:"title"`
-	Description string     `gorm:"text;not null;" json:"description"`
-	FoodImage   string     `gorm:"size:255;null;" json:"food_image"`
-	CreatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
-	DeletedAt   *time.Time `json:"deleted_at"`
+	ID          uint64         `gorm:"primary_key;auto_increment" json:"id"`
+	UserID      uint64         `gorm:"size:100;not null;" json:"user_id"`
+	Title       string         `gorm:"size:100;not null;uniqueIndex:idx_food_title,where:deleted_at IS NULL" json:"title"`
+	Description string         `gorm:"text;not null;" json:"description"`
+	FoodImage   string         `gorm:"size:255;null;" json:"food_image"`
+	Images      []GalleryImage `gorm:"foreignKey:FoodID" json:"images"`
+	Clips       []FoodClip     `gorm:"foreignKey:FoodID" json:"clips"`
+	Ingredients []Ingredient   `gorm:"foreignKey:FoodID" json:"ingredients"`
+	Nutrition   Nutrition      `gorm:"embedded" json:"nutrition"`
+	Rating      RatingSummary  `gorm:"embedded;embeddedPrefix:rating_" json:"rating"`
+	Status      FoodStatus     `gorm:"size:20;not null;default:published;index" json:"status"`
+	PublishAt   *time.Time     `gorm:"index" json:"publish_at"`
+	PublishedAt *time.Time     `json:"published_at"`
+	// Hidden is set by the moderators, a hidden food is only seen by its owner
+	Hidden    bool           `gorm:"not null;default:false" json:"hidden"`
+	UpdatedBy uint64         `gorm:"not null;default:0" json:"updated_by"`
+	Version   uint64         `gorm:"not null;default:1" json:"version"`
+	CreatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP;index" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+}
+
+// FoodFilter holds the optional criteria used when listing foods
+type FoodFilter struct {
+	MinCalories      *float64
+	MaxCalories      *float64
+	ExcludeAllergens []string
+	// "rating" or "" for the newest first
+	SortBy string
+	// besides the published foods, the viewer also sees their own drafts
+	ViewerID uint64
+}
+
+// MarshalJSON gives the food image with all its sizes instead of its single url, see ImageSet
+func (f Food) MarshalJSON() ([]byte, error) {
+	type food Food
+	return json.Marshal(struct {
+		food
+		FoodImage *ImageSet `json:"food_image"`
+	}{food(f), NewImageSet(f.FoodImage)})
+}
+
+// SignURLs returns a copy of the food with the urls of its images and clips signed when it is not public,
+// they are private in the storage then. A public food is returned as it is.
+func (f *Food) SignURLs(sign URLSigner) *Food {
+	if f == nil || f.IsPublic() {
+		return f
+	}
+	signed := *f
+	signed.FoodImage = sign.Sign(f.FoodImage)
+	signed.Images = SignGalleryImages(f.Images, sign)
+	signed.Clips = SignFoodClips(f.Clips, sign)
+	return &signed
+}
+
+// SignFoods returns the foods with the urls of the ones that are not public signed, see Food.SignURLs
+func SignFoods(foods []Food, sign URLSigner) []Food {
+	if foods == nil {
+		return nil
+	}
+	signed := make([]Food, len(foods))
+	for i := range foods {
+		signed[i] = *foods[i].SignURLs(sign)
+	}
+	return signed
+}
+
+// ImagePaths returns the image of the food, the images of its gallery, and its clips with their posters
+func (f *Food) ImagePaths() []string {
+	paths := []string{f.FoodImage}
+	for _, image := range f.Images {
+		paths = append(paths, image.Path)
+	}
+	for _, clip := range f.Clips {
+		paths = append(paths, clip.Path, clip.PosterPath)
+	}
+	return paths
+}
+
+// ETag identifies the current version of the food, for the ETag and If-Match headers.
+// The version changes with anything the food shows, its rating and its gallery included.
+func (f *Food) ETag() string {
+	return fmt.Sprintf(`"food-%d-v%d"`, f.ID, f.Version)
 }
 
 func (f *Food) BeforeSave() {
@@ -23,6 +106,9 @@ func (f *Food) BeforeSave() {
 
 func (f *Food) Prepare() {
 	f.Title = html.EscapeString(strings.TrimSpace(f.Title))
+	for i := range f.Ingredients {
+		f.Ingredients[i].Prepare()
+	}
 	f.CreatedAt = time.Now()
 	f.UpdatedAt = time.Now()
 }
@@ -30,6 +116,12 @@ func (f *Food) Prepare() {
 func (f *Food) Validate(action string) map[string]string {
 	var errorMessages = make(map[string]string)
 
+	for i := range f.Ingredients {
+		for key, msg := range f.Ingredients[i].Validate() {
+			errorMessages[key] = msg
+		}
+	}
+
 	switch strings.ToLower(action) {
 	case "update":
 		if f.Title == "" || f.Title == "null" {
@@ -48,3 +140,28 @@ func (f *Food) Validate(action string) map[string]string {
 	}
 	return errorMessages
 }
+
+// ValidateFields only validates the given fields, it is used for partial updates
+// where the fields that did not change are already valid.
+func (f *Food) ValidateFields(fields []string) map[string]string {
+	var errorMessages = make(map[string]string)
+	for _, field := range fields {
+		switch field {
+		case "title":
+			if f.Title == "" || f.Title == "null" {
+				errorMessages["title_required"] = "title is required"
+			}
+		case "description":
+			if f.Description == "" || f.Description == "null" {
+				errorMessages["desc_required"] = "description is required"
+			}
+		case "ingredients":
+			for i := range f.Ingredients {
+				for key, msg := range f.Ingredients[i].Validate() {
+					errorMessages[key] = msg
+				}
+			}
+		}
+	}
+	return errorMessages
+}
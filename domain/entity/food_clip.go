@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"encoding/json"
+	"html"
+	"strings"
+	"time"
+)
+
+// FoodClip is a short video of a food, e.g how a step of the recipe is done.
+// Its poster is an image taken from one of its frames, stored like the other images.
+type FoodClip struct {
+	ID          uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	FoodID      uint64    `gorm:"not null;index" json:"food_id"`
+	Path        string    `gorm:"size:255;not null;" json:"-"`
+	PosterPath  string    `gorm:"size:255;" json:"-"`
+	ContentType string    `gorm:"size:100;not null;" json:"-"`
+	DurationMs  int64     `gorm:"not null;default:0" json:"-"`
+	Width       int       `gorm:"not null;default:0" json:"-"`
+	Height      int       `gorm:"not null;default:0" json:"-"`
+	Caption     string    `gorm:"size:255;" json:"caption"`
+	Position    int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// Video is what the clients get for a video: where to stream it from, and its poster with all its sizes
+type Video struct {
+	Src         string    `json:"src"`
+	ContentType string    `json:"content_type"`
+	DurationMs  int64     `json:"duration_ms"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Poster      *ImageSet `json:"poster"`
+}
+
+func NewVideo(src string, contentType string, durationMs int64, width int, height int, poster string) *Video {
+	return &Video{
+		Src:         src,
+		ContentType: contentType,
+		DurationMs:  durationMs,
+		Width:       width,
+		Height:      height,
+		Poster:      NewImageSet(poster),
+	}
+}
+
+// NewFoodClip makes a clip of the video of a confirmed upload
+func NewFoodClip(foodId uint64, upload *Upload, caption string) *FoodClip {
+	return &FoodClip{
+		FoodID:      foodId,
+		Path:        upload.VideoPath,
+		PosterPath:  upload.ImagePath,
+		ContentType: upload.ContentType,
+		DurationMs:  upload.DurationMs,
+		Width:       upload.Width,
+		Height:      upload.Height,
+		Caption:     caption,
+	}
+}
+
+// MarshalJSON gives the video of the clip
+func (c FoodClip) MarshalJSON() ([]byte, error) {
+	type foodClip FoodClip
+	return json.Marshal(struct {
+		foodClip
+		Video *Video `json:"video"`
+	}{foodClip(c), NewVideo(c.Path, c.ContentType, c.DurationMs, c.Width, c.Height, c.PosterPath)})
+}
+
+// SignFoodClips returns the clips with their urls signed, for a food that is not public
+func SignFoodClips(clips []FoodClip, sign URLSigner) []FoodClip {
+	if clips == nil {
+		return nil
+	}
+	signed := make([]FoodClip, len(clips))
+	for i, clip := range clips {
+		clip.Path = sign.Sign(clip.Path)
+		clip.PosterPath = sign.Sign(clip.PosterPath)
+		signed[i] = clip
+	}
+	return signed
+}
+
+func (c *FoodClip) Prepare() {
+	c.Caption = html.EscapeString(strings.TrimSpace(c.Caption))
+	c.CreatedAt = time.Now()
+}
+
+func (c *FoodClip) Validate() map[string]string {
+	var errorMessages = make(map[string]string)
+
+	if len(c.Caption) > 255 {
+		errorMessages["caption_too_long"] = "caption should not be more than 255 characters"
+	}
+	return errorMessages
+}
@@ -0,0 +1,124 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ImportStatus string
+
+const (
+	ImportStatusPending  ImportStatus = "pending"
+	ImportStatusRunning  ImportStatus = "running"
+	ImportStatusFinished ImportStatus = "finished"
+	ImportStatusFailed   ImportStatus = "failed"
+)
+
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+// MaxImportRows is the number of foods a single import can hold
+const MaxImportRows = 1000
+
+// MaxImportSize is the largest csv or ndjson file an import can be made of, see IMPORT_MAX_SIZE
+var MaxImportSize int64 = 8 << 20
+
+// RunningImportTimeout is how long a running import can go without saving its progress before it is
+// considered dead, e.g the app was stopped while running it
+const RunningImportTimeout = 15 * time.Minute
+
+// FoodImport is a batch of foods imported from a csv or ndjson file.
+// The file is kept with the import until it is processed by the background job.
+type FoodImport struct {
+	ID       uint64       `gorm:"primary_key;auto_increment" json:"id"`
+	UserID   uint64       `gorm:"not null;index" json:"user_id"`
+	Format   string       `gorm:"size:10;not null;" json:"format"`
+	DryRun   bool         `gorm:"not null;default:false" json:"dry_run"`
+	Status   ImportStatus `gorm:"size:20;not null;default:pending;index" json:"status"`
+	Payload  string       `gorm:"type:text;not null;" json:"-"`
+	Total    int          `gorm:"not null;default:0" json:"total"`
+	Imported int          `gorm:"not null;default:0" json:"imported"`
+	Failed   int          `gorm:"not null;default:0" json:"failed"`
+	// the row errors as a json array, see RowErrors
+	Errors     string           `gorm:"type:text;" json:"-"`
+	RowErrors  []ImportRowError `gorm:"-" json:"errors"`
+	CreatedAt  time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt  time.Time        `gorm:"default:CURRENT_TIMESTAMP;index" json:"updated_at"`
+	StartedAt  *time.Time       `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at"`
+}
+
+// ImportRowError holds the keyed errors of a single row, the same keys SaveFood responds with
+type ImportRowError struct {
+	Row    int               `json:"row"`
+	Title  string            `json:"title"`
+	Errors map[string]string `json:"errors"`
+}
+
+// ImportRow is a food as read from an import file, before it is validated
+type ImportRow struct {
+	Row         int          `json:"-"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	Image       string       `json:"image"`
+	Status      string       `json:"status"`
+	PublishAt   string       `json:"publish_at"`
+	Ingredients []Ingredient `json:"ingredients"`
+	// the errors found while reading the row, e.g invalid json
+	Errors map[string]string `json:"-"`
+}
+
+func NewFoodImport(userId uint64, format string, dryRun bool, payload string, total int) *FoodImport {
+	return &FoodImport{
+		UserID:    userId,
+		Format:    format,
+		DryRun:    dryRun,
+		Status:    ImportStatusPending,
+		Payload:   payload,
+		Total:     total,
+		CreatedAt: time.Now(),
+	}
+}
+
+// AddRowError records a row that could not be imported
+func (i *FoodImport) AddRowError(row *ImportRow, errs map[string]string) {
+	i.Failed++
+	i.RowErrors = append(i.RowErrors, ImportRowError{Row: row.Row, Title: row.Title, Errors: errs})
+}
+
+// Finish marks the import as done and encodes the row errors for saving
+func (i *FoodImport) Finish(status ImportStatus) {
+	now := time.Now()
+	i.Status = status
+	i.FinishedAt = &now
+	// the file is not needed anymore once it is processed
+	i.Payload = ""
+	i.EncodeErrors()
+}
+
+// Interrupt fails an import that stopped before its end. The rows imported until then stay imported.
+func (i *FoodImport) Interrupt() {
+	i.RowErrors = append(i.RowErrors, ImportRowError{Errors: map[string]string{
+		"import_interrupted": "the import stopped before its end, the rows not listed as imported or failed were not imported",
+	}})
+	i.Finish(ImportStatusFailed)
+}
+
+func (i *FoodImport) EncodeErrors() {
+	if len(i.RowErrors) == 0 {
+		i.Errors = ""
+		return
+	}
+	encoded, _ := json.Marshal(i.RowErrors)
+	i.Errors = string(encoded)
+}
+
+func (i *FoodImport) DecodeErrors() error {
+	i.RowErrors = []ImportRowError{}
+	if i.Errors == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(i.Errors), &i.RowErrors)
+}
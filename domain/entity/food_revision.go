@@ -0,0 +1,101 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FoodRevision is an immutable snapshot of a food, written every time the food is saved
+type FoodRevision struct {
+	ID          uint64 `gorm:"primary_key;auto_increment" json:"id"`
+	FoodID      uint64 `gorm:"not null;uniqueIndex:idx_food_revision" json:"food_id"`
+	Revision    int    `gorm:"not null;uniqueIndex:idx_food_revision" json:"revision"`
+	EditorID    uint64 `gorm:"not null;" json:"editor_id"`
+	Title       string `gorm:"size:100;not null;" json:"title"`
+	Description string `gorm:"text;not null;" json:"description"`
+	FoodImage   string `gorm:"size:255;null;" json:"food_image"`
+	// the ingredients as a json array
+	Ingredients string    `gorm:"type:text;" json:"ingredients"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// SignFoodRevisions returns the revisions with the url of their image signed, the image of an old revision is often private
+func SignFoodRevisions(revisions []FoodRevision, sign URLSigner) []FoodRevision {
+	if revisions == nil {
+		return nil
+	}
+	signed := make([]FoodRevision, len(revisions))
+	for i, revision := range revisions {
+		revision.FoodImage = sign.Sign(revision.FoodImage)
+		signed[i] = revision
+	}
+	return signed
+}
+
+// FieldChange is a single field that differs between two revisions
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// NewFoodRevision snapshots the current state of the food
+func NewFoodRevision(food *Food) *FoodRevision {
+	ingredients := make([]Ingredient, len(food.Ingredients))
+	for i, ingredient := range food.Ingredients {
+		// the ids change every time the ingredients are replaced, they are not part of the snapshot
+		ingredients[i] = Ingredient{Name: ingredient.Name, Quantity: ingredient.Quantity, Unit: ingredient.Unit}
+	}
+	encoded, _ := json.Marshal(ingredients)
+	return &FoodRevision{
+		FoodID:      food.ID,
+		EditorID:    food.UpdatedBy,
+		Title:       food.Title,
+		Description: food.Description,
+		FoodImage:   food.FoodImage,
+		Ingredients: string(encoded),
+		CreatedAt:   time.Now(),
+	}
+}
+
+func (r *FoodRevision) IngredientList() ([]Ingredient, error) {
+	ingredients := []Ingredient{}
+	if r.Ingredients == "" {
+		return ingredients, nil
+	}
+	err := json.Unmarshal([]byte(r.Ingredients), &ingredients)
+	return ingredients, err
+}
+
+// ApplyTo restores the snapshot onto the food
+func (r *FoodRevision) ApplyTo(food *Food) error {
+	ingredients, err := r.IngredientList()
+	if err != nil {
+		return err
+	}
+	food.Title = r.Title
+	food.Description = r.Description
+	food.FoodImage = r.FoodImage
+	food.Ingredients = ingredients
+	return nil
+}
+
+// Diff lists the fields that changed from r to other
+func (r *FoodRevision) Diff(other *FoodRevision) []FieldChange {
+	changes := []FieldChange{}
+	fields := []struct {
+		name     string
+		from, to string
+	}{
+		{"title", r.Title, other.Title},
+		{"description", r.Description, other.Description},
+		{"food_image", r.FoodImage, other.FoodImage},
+		{"ingredients", r.Ingredients, other.Ingredients},
+	}
+	for _, field := range fields {
+		if field.from != field.to {
+			changes = append(changes, FieldChange{Field: field.name, From: field.from, To: field.to})
+		}
+	}
+	return changes
+}
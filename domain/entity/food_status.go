@@ -0,0 +1,76 @@
+package entity
+
+import "time"
+
+type FoodStatus string
+
+const (
+	FoodStatusDraft     FoodStatus = "draft"
+	FoodStatusScheduled FoodStatus = "scheduled"
+	FoodStatusPublished FoodStatus = "published"
+	FoodStatusArchived  FoodStatus = "archived"
+)
+
+// the statuses a food can move to from its current status. A new food has no status yet.
+var foodStatusTransitions = map[FoodStatus][]FoodStatus{
+	"":                  {FoodStatusDraft, FoodStatusScheduled, FoodStatusPublished},
+	FoodStatusDraft:     {FoodStatusScheduled, FoodStatusPublished, FoodStatusArchived},
+	FoodStatusScheduled: {FoodStatusDraft, FoodStatusPublished, FoodStatusArchived},
+	FoodStatusPublished: {FoodStatusDraft, FoodStatusArchived},
+	FoodStatusArchived:  {FoodStatusDraft, FoodStatusPublished},
+}
+
+func (s FoodStatus) CanTransitionTo(next FoodStatus) bool {
+	for _, allowed := range foodStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionTo moves the food to the given status. publishAt is required when scheduling.
+func (f *Food) TransitionTo(status FoodStatus, publishAt *time.Time) map[string]string {
+	var errorMessages = make(map[string]string)
+
+	if _, ok := foodStatusTransitions[status]; !ok || status == "" {
+		errorMessages["invalid_status"] = "status should be one of draft, scheduled, published or archived"
+		return errorMessages
+	}
+	if !f.Status.CanTransitionTo(status) {
+		current := string(f.Status)
+		if current == "" {
+			current = "new"
+		}
+		errorMessages["invalid_transition"] = "a " + current + " food cannot be " + string(status)
+		return errorMessages
+	}
+
+	now := time.Now()
+	switch status {
+	case FoodStatusScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			errorMessages["invalid_publish_at"] = "publish_at should be a date in the future"
+			return errorMessages
+		}
+		f.PublishAt = publishAt
+	case FoodStatusPublished:
+		f.PublishAt = nil
+		f.PublishedAt = &now
+	default:
+		f.PublishAt = nil
+	}
+	f.Status = status
+	return errorMessages
+}
+
+// IsVisibleTo reports whether the user can see the food. Only published foods are public,
+// unless a moderator hid them.
+func (f *Food) IsVisibleTo(userId uint64) bool {
+	return (f.Status == FoodStatusPublished && !f.Hidden) || (userId != 0 && f.UserID == userId)
+}
+
+// IsPublic reports whether anyone can see the food, and so its images
+func (f *Food) IsPublic() bool {
+	return f.Status == FoodStatusPublished && !f.Hidden && !f.DeletedAt.Valid
+}
@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"encoding/json"
+	"html"
+	"strings"
+	"time"
+)
+
+// GalleryImage is one of the images of a food. The cover image is also
+// copied to Food.FoodImage, so the clients that only know about a single image keep working.
+type GalleryImage struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	FoodID    uint64    `gorm:"not null;index" json:"food_id"`
+	Path      string    `gorm:"size:255;not null;" json:"path"`
+	AltText   string    `gorm:"size:255;" json:"alt_text"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	IsCover   bool      `gorm:"not null;default:false" json:"is_cover"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// MarshalJSON adds the sizes of the image to its path
+func (g GalleryImage) MarshalJSON() ([]byte, error) {
+	type galleryImage GalleryImage
+	return json.Marshal(struct {
+		galleryImage
+		Image *ImageSet `json:"image"`
+	}{galleryImage(g), NewImageSet(g.Path)})
+}
+
+// SignGalleryImages returns the images with their urls signed, for a food that is not public
+func SignGalleryImages(images []GalleryImage, sign URLSigner) []GalleryImage {
+	if images == nil {
+		return nil
+	}
+	signed := make([]GalleryImage, len(images))
+	for i, image := range images {
+		image.Path = sign.Sign(image.Path)
+		signed[i] = image
+	}
+	return signed
+}
+
+func (g *GalleryImage) Prepare() {
+	g.AltText = html.EscapeString(strings.TrimSpace(g.AltText))
+	g.CreatedAt = time.Now()
+}
+
+func (g *GalleryImage) Validate() map[string]string {
+	var errorMessages = make(map[string]string)
+
+	if len(g.AltText) > 255 {
+		errorMessages["alt_text_too_long"] = "alt text should not be more than 255 characters"
+	}
+	return errorMessages
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// ImageHoldTTL is how long an image handed out is kept, before the food using it is saved
+const ImageHoldTTL = 15 * time.Minute
+
+// ImageHold counts a stored image, or video, as used for a while: from the moment it is handed out,
+// e.g when the same image is uploaded again, until the food using it is saved
+type ImageHold struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	Path      string    `gorm:"size:255;not null;index" json:"path"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+}
@@ -0,0 +1,175 @@
+package entity
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ImageVariant is a size the uploaded images are resized to, they are never enlarged
+type ImageVariant struct {
+	Name     string
+	MaxWidth int
+}
+
+// ImageFormat is a format every variant is stored in
+type ImageFormat struct {
+	Name        string
+	ContentType string
+	Extension   string
+}
+
+var ImageVariants = []ImageVariant{
+	{Name: "thumbnail", MaxWidth: 160},
+	{Name: "medium", MaxWidth: 640},
+	{Name: "large", MaxWidth: 1280},
+}
+
+var ImageFormats = []ImageFormat{
+	{Name: "webp", ContentType: "image/webp", Extension: ".webp"},
+	{Name: "jpeg", ContentType: "image/jpeg", Extension: ".jpg"},
+}
+
+// the rendition saved as the food image, every browser can show it
+const defaultRendition = "large.jpg"
+
+// the renditions of an image are stored together under <id>_<width>x<height>/, the size being the one of the upload.
+// The sizes of the variants follow from it, so the food only has to keep the url of the default rendition.
+var imageSetPattern = regexp.MustCompile(`(?:^|/)[0-9a-zA-Z-]+_([0-9]+)x([0-9]+)/` + regexp.QuoteMeta(defaultRendition) + `$`)
+
+// URLSigner turns the url of an image into one it can be read with for a while, even when it is private,
+// see fileupload.MediaURL. The handlers sign the urls of the private images they answer with.
+type URLSigner func(src string) string
+
+// Sign returns the signed url of an image. Without a signer, the url is kept as it is.
+func (sign URLSigner) Sign(src string) string {
+	if src == "" || sign == nil {
+		return src
+	}
+	return sign(src)
+}
+
+// ImageRendition is a variant of an image, with its url in each format
+type ImageRendition struct {
+	Width  int               `json:"width"`
+	Height int               `json:"height"`
+	URLs   map[string]string `json:"urls"`
+}
+
+// ImageSet is what the clients get for an image: the default url, and the srcset of each format, by content type,
+// e.g {"image/webp": ".../thumbnail.webp 160w, .../medium.webp 640w"}
+type ImageSet struct {
+	Src      string                    `json:"src"`
+	Srcset   map[string]string         `json:"srcset,omitempty"`
+	Variants map[string]ImageRendition `json:"variants,omitempty"`
+}
+
+// ImageSetDir is the directory the renditions of an upload are stored in
+func ImageSetDir(id string, width int, height int) string {
+	return fmt.Sprintf("%s_%dx%d", id, width, height)
+}
+
+// RenditionKey is where the variant of the image is stored in the given format
+func RenditionKey(dir string, variant ImageVariant, format ImageFormat) string {
+	return dir + "/" + variant.Name + format.Extension
+}
+
+// DefaultRenditionKey is the rendition whose url is saved with the food
+func DefaultRenditionKey(dir string) string {
+	return dir + "/" + defaultRendition
+}
+
+// Size returns the size of the variant of an image of the given size
+func (v ImageVariant) Size(width int, height int) (int, int) {
+	if width <= v.MaxWidth {
+		return width, height
+	}
+	scaled := int(math.Round(float64(height) * float64(v.MaxWidth) / float64(width)))
+	if scaled < 1 {
+		scaled = 1
+	}
+	return v.MaxWidth, scaled
+}
+
+// NewImageSet describes the image saved under the url. The images uploaded before they were resized
+// only have their single url. The query of a signed url is kept on the url of every rendition.
+func NewImageSet(src string) *ImageSet {
+	if src == "" {
+		return nil
+	}
+	set := &ImageSet{Src: src}
+	src, query := src, ""
+	if i := strings.Index(src, "?"); i >= 0 {
+		src, query = src[:i], src[i:]
+	}
+	match := imageSetPattern.FindStringSubmatch(src)
+	if match == nil {
+		return set
+	}
+	width, _ := strconv.Atoi(match[1])
+	height, _ := strconv.Atoi(match[2])
+	base := strings.TrimSuffix(src, defaultRendition)
+
+	set.Srcset = map[string]string{}
+	set.Variants = map[string]ImageRendition{}
+	for _, format := range ImageFormats {
+		candidates := []string{}
+		previousWidth := 0
+		for _, variant := range ImageVariants {
+			w, _ := variant.Size(width, height)
+			// a small upload has the same size in several variants, the srcset lists it once
+			if w != previousWidth {
+				candidates = append(candidates, fmt.Sprintf("%s%s%s%s %dw", base, variant.Name, format.Extension, query, w))
+				previousWidth = w
+			}
+		}
+		set.Srcset[format.ContentType] = strings.Join(candidates, ", ")
+	}
+	for _, variant := range ImageVariants {
+		w, h := variant.Size(width, height)
+		urls := map[string]string{}
+		for _, format := range ImageFormats {
+			urls[format.Name] = base + variant.Name + format.Extension + query
+		}
+		set.Variants[variant.Name] = ImageRendition{Width: w, Height: h, URLs: urls}
+	}
+	return set
+}
+
+// ImageSetKey returns the key of the default rendition of the image the stored file is a rendition of,
+// the key itself when the file is not a rendition, e.g an image uploaded before they were resized
+func ImageSetKey(key string) string {
+	dir, file := path.Split(key)
+	if !imageSetPattern.MatchString(strings.TrimSuffix(dir, "/") + "/" + defaultRendition) {
+		return key
+	}
+	for _, variant := range ImageVariants {
+		for _, format := range ImageFormats {
+			if file == variant.Name+format.Extension {
+				return DefaultRenditionKey(strings.TrimSuffix(dir, "/"))
+			}
+		}
+	}
+	return key
+}
+
+// RenditionURLs returns the urls of all the renditions of the image, or the url itself for an image that was not resized
+func RenditionURLs(src string) []string {
+	set := NewImageSet(src)
+	if set == nil {
+		return nil
+	}
+	if len(set.Variants) == 0 {
+		return []string{src}
+	}
+	urls := []string{}
+	for _, variant := range ImageVariants {
+		for _, format := range ImageFormats {
+			urls = append(urls, set.Variants[variant.Name].URLs[format.Name])
+		}
+	}
+	return urls
+}
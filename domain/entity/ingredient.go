@@ -0,0 +1,112 @@
+package entity
+
+import (
+	"html"
+	"strings"
+)
+
+type Ingredient struct {
+	ID       uint64  `gorm:"primary_key;auto_increment" json:"id"`
+	FoodID   uint64  `gorm:"not null;index" json:"food_id"`
+	Name     string  `gorm:"size:100;not null;" json:"name"`
+	Quantity float64 `gorm:"not null;" json:"quantity"`
+	Unit     string  `gorm:"size:20;not null;" json:"unit"`
+}
+
+// how many grams are in one of each unit. Volumes are converted as if the
+// ingredient had the density of water, which is close enough for cooking.
+var unitToGrams = map[string]float64{
+	"mg":     0.001,
+	"g":      1,
+	"gram":   1,
+	"grams":  1,
+	"kg":     1000,
+	"oz":     28.3495,
+	"lb":     453.592,
+	"ml":     1,
+	"l":      1000,
+	"tsp":    5,
+	"tbsp":   15,
+	"cup":    240,
+	"cups":   240,
+	"piece":  0,
+	"pieces": 0,
+	"pcs":    0,
+}
+
+// how many base units are in one of each unit, for adding up the quantities of a shopping list.
+// Unlike unitToGrams, weights and volumes are kept apart: 2 cups of flour are not 480g.
+var unitToBase = map[string]struct {
+	base   string
+	factor float64
+}{
+	"mg":     {"g", 0.001},
+	"g":      {"g", 1},
+	"gram":   {"g", 1},
+	"grams":  {"g", 1},
+	"kg":     {"g", 1000},
+	"oz":     {"g", 28.3495},
+	"lb":     {"g", 453.592},
+	"ml":     {"ml", 1},
+	"l":      {"ml", 1000},
+	"tsp":    {"ml", 5},
+	"tbsp":   {"ml", 15},
+	"cup":    {"ml", 240},
+	"cups":   {"ml", 240},
+	"piece":  {"pcs", 1},
+	"pieces": {"pcs", 1},
+	"pcs":    {"pcs", 1},
+}
+
+// IsSupportedUnit reports whether the unit can be used for an ingredient
+func IsSupportedUnit(unit string) bool {
+	_, ok := unitToGrams[unit]
+	return ok
+}
+
+// NormalizeQuantity converts the quantity to the base unit of its kind, g, ml or pcs
+func NormalizeQuantity(quantity float64, unit string) (float64, string) {
+	conversion, ok := unitToBase[unit]
+	if !ok {
+		return quantity, unit
+	}
+	return quantity * conversion.factor, conversion.base
+}
+
+func (i *Ingredient) Prepare() {
+	i.Name = html.EscapeString(strings.ToLower(strings.TrimSpace(i.Name)))
+	i.Unit = strings.ToLower(strings.TrimSpace(i.Unit))
+	if i.Unit == "" {
+		i.Unit = "g"
+	}
+}
+
+// IsCountable reports whether the ingredient is counted (e.g "2 eggs")
+// rather than measured, in which case its weight depends on the serving size.
+func (i *Ingredient) IsCountable() bool {
+	return unitToGrams[i.Unit] == 0
+}
+
+// Grams converts the quantity to grams. servingGrams is the weight of one
+// piece and is only used for countable ingredients.
+func (i *Ingredient) Grams(servingGrams float64) float64 {
+	if i.IsCountable() {
+		return i.Quantity * servingGrams
+	}
+	return i.Quantity * unitToGrams[i.Unit]
+}
+
+func (i *Ingredient) Validate() map[string]string {
+	var errorMessages = make(map[string]string)
+
+	if i.Name == "" || i.Name == "null" {
+		errorMessages["ingredient_name_required"] = "ingredient name is required"
+	}
+	if i.Quantity <= 0 {
+		errorMessages["invalid_ingredient_quantity"] = "ingredient quantity should be greater than zero"
+	}
+	if _, ok := unitToGrams[i.Unit]; !ok {
+		errorMessages["invalid_ingredient_unit"] = "ingredient unit is not supported"
+	}
+	return errorMessages
+}
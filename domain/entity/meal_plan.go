@@ -0,0 +1,209 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"html"
+	"strings"
+	"time"
+)
+
+type MealSlot string
+
+const (
+	MealSlotBreakfast MealSlot = "breakfast"
+	MealSlotLunch     MealSlot = "lunch"
+	MealSlotDinner    MealSlot = "dinner"
+	MealSlotSnack     MealSlot = "snack"
+)
+
+// MealSlots are the slots of a day, in the order they are listed
+var MealSlots = []MealSlot{MealSlotBreakfast, MealSlotLunch, MealSlotDinner, MealSlotSnack}
+
+// when the meals of each slot start, used to place the entries in a calendar
+var mealSlotStart = map[MealSlot]time.Duration{
+	MealSlotBreakfast: 8 * time.Hour,
+	MealSlotLunch:     12*time.Hour + 30*time.Minute,
+	MealSlotSnack:     16 * time.Hour,
+	MealSlotDinner:    19 * time.Hour,
+}
+
+// MealDuration is how long a meal lasts in a calendar
+const MealDuration = 45 * time.Minute
+
+// DateLayout is the format of the days of a meal plan
+const DateLayout = "2006-01-02"
+
+// MaxMealPlanDays is the longest range of days that can be read at once
+const MaxMealPlanDays = 62
+
+// MealPlanEntry is a food planned for a meal of a day
+type MealPlanEntry struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint64    `gorm:"not null;index:idx_meal_plan_user_date" json:"user_id"`
+	Date      time.Time `gorm:"type:date;not null;index:idx_meal_plan_user_date" json:"date"`
+	Slot      MealSlot  `gorm:"size:20;not null;" json:"slot"`
+	FoodID    uint64    `gorm:"not null;index" json:"food_id"`
+	Food      *Food     `gorm:"foreignKey:FoodID" json:"food,omitempty"`
+	Servings  float64   `gorm:"not null;default:1" json:"servings"`
+	Note      string    `gorm:"size:255;" json:"note"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// MealPlanDay holds the entries of a day by slot, every slot is listed even when it is empty
+type MealPlanDay struct {
+	Date  string                       `json:"date"`
+	Meals map[MealSlot][]MealPlanEntry `json:"meals"`
+}
+
+// MealPlan is the plan of a user over a range of days
+type MealPlan struct {
+	From string        `json:"from"`
+	To   string        `json:"to"`
+	Days []MealPlanDay `json:"days"`
+}
+
+// CalendarFeed is the secret token of the iCalendar feed of a user's meal plan.
+// Calendar apps cannot send our access token, so the feed url carries its own.
+type CalendarFeed struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint64    `gorm:"not null;uniqueIndex" json:"user_id"`
+	Token     string    `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+func (e *MealPlanEntry) Prepare() {
+	e.Slot = MealSlot(strings.ToLower(strings.TrimSpace(string(e.Slot))))
+	e.Note = html.EscapeString(strings.TrimSpace(e.Note))
+	if e.Servings == 0 {
+		e.Servings = 1
+	}
+	e.CreatedAt = time.Now()
+	e.UpdatedAt = time.Now()
+}
+
+func (e *MealPlanEntry) Validate() map[string]string {
+	var errorMessages = make(map[string]string)
+
+	if !IsMealSlot(e.Slot) {
+		errorMessages["invalid_slot"] = "slot should be one of breakfast, lunch, dinner or snack"
+	}
+	if e.FoodID == 0 {
+		errorMessages["food_required"] = "food is required"
+	}
+	if e.Servings <= 0 || e.Servings > 100 {
+		errorMessages["invalid_servings"] = "servings should be more than 0 and at most 100"
+	}
+	if len(e.Note) > 255 {
+		errorMessages["note_too_long"] = "note should not be more than 255 characters"
+	}
+	return errorMessages
+}
+
+// Start is when the meal starts on its day. It has no time zone, a calendar shows it in the local time of the user.
+func (e *MealPlanEntry) Start() time.Time {
+	day := time.Date(e.Date.Year(), e.Date.Month(), e.Date.Day(), 0, 0, 0, 0, time.UTC)
+	return day.Add(mealSlotStart[e.Slot])
+}
+
+// HideFoodFrom leaves out the food of the entry when the user cannot see it anymore, e.g a food moved back to draft
+func (e *MealPlanEntry) HideFoodFrom(userId uint64) {
+	if e.Food != nil && !e.Food.IsVisibleTo(userId) {
+		e.Food = nil
+	}
+}
+
+// SignURLs returns a copy of the entry with the urls of its food signed, see Food.SignURLs
+func (e *MealPlanEntry) SignURLs(sign URLSigner) *MealPlanEntry {
+	signed := *e
+	signed.Food = e.Food.SignURLs(sign)
+	return &signed
+}
+
+func IsMealSlot(slot MealSlot) bool {
+	for _, s := range MealSlots {
+		if s == slot {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseDate reads a day of a meal plan, e.g 2021-07-26
+func ParseDate(value string) (time.Time, error) {
+	return time.Parse(DateLayout, strings.TrimSpace(value))
+}
+
+// WeekStart returns the monday of the week of the day
+func WeekStart(day time.Time) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// NewMealPlan groups the entries by day and slot, from and to are both included
+func NewMealPlan(from time.Time, to time.Time, entries []MealPlanEntry) *MealPlan {
+	plan := &MealPlan{
+		From: from.Format(DateLayout),
+		To:   to.Format(DateLayout),
+		Days: []MealPlanDay{},
+	}
+	days := map[string]int{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		meals := map[MealSlot][]MealPlanEntry{}
+		for _, slot := range MealSlots {
+			meals[slot] = []MealPlanEntry{}
+		}
+		days[day.Format(DateLayout)] = len(plan.Days)
+		plan.Days = append(plan.Days, MealPlanDay{Date: day.Format(DateLayout), Meals: meals})
+	}
+	for _, entry := range entries {
+		i, ok := days[entry.Date.Format(DateLayout)]
+		if !ok {
+			continue
+		}
+		plan.Days[i].Meals[entry.Slot] = append(plan.Days[i].Meals[entry.Slot], entry)
+	}
+	return plan
+}
+
+// HideFoodsFrom leaves out the foods of the entries the user cannot see anymore, see MealPlanEntry.HideFoodFrom
+func (p *MealPlan) HideFoodsFrom(userId uint64) {
+	for _, day := range p.Days {
+		for _, entries := range day.Meals {
+			for i := range entries {
+				entries[i].HideFoodFrom(userId)
+			}
+		}
+	}
+}
+
+// SignURLs returns a copy of the plan with the urls of its foods signed, see Food.SignURLs
+func (p *MealPlan) SignURLs(sign URLSigner) *MealPlan {
+	signed := *p
+	signed.Days = make([]MealPlanDay, len(p.Days))
+	for i, day := range p.Days {
+		meals := make(map[MealSlot][]MealPlanEntry, len(day.Meals))
+		for slot, entries := range day.Meals {
+			meals[slot] = make([]MealPlanEntry, len(entries))
+			for j := range entries {
+				meals[slot][j] = *entries[j].SignURLs(sign)
+			}
+		}
+		signed.Days[i] = MealPlanDay{Date: day.Date, Meals: meals}
+	}
+	return &signed
+}
+
+func NewCalendarFeed(userId uint64) (*CalendarFeed, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	return &CalendarFeed{
+		UserID:    userId,
+		Token:     hex.EncodeToString(token),
+		CreatedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	NotificationContentHidden = "content_hidden"
+	NotificationUserBanned    = "user_banned"
+)
+
+// Notification tells a user about something that happened to them or their content
+type Notification struct {
+	ID         uint64       `gorm:"primary_key;auto_increment" json:"id"`
+	UserID     uint64       `gorm:"not null;index" json:"user_id"`
+	Kind       string       `gorm:"size:50;not null;" json:"kind"`
+	Message    string       `gorm:"size:500;not null;" json:"message"`
+	TargetType ReportTarget `gorm:"size:20;" json:"target_type,omitempty"`
+	TargetID   uint64       `json:"target_id,omitempty"`
+	ReadAt     *time.Time   `json:"read_at"`
+	CreatedAt  time.Time    `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// NewModerationNotification tells the author that their reported content was hidden, and banned them when it did
+func NewModerationNotification(report *Report) *Notification {
+	kind := NotificationContentHidden
+	message := fmt.Sprintf("your %s was hidden by a moderator after being reported for %s", report.TargetType, report.Reason)
+	if report.Action == ModerationBan {
+		kind = NotificationUserBanned
+		message += ", and you can no longer post"
+	}
+	return &Notification{
+		UserID:     report.AuthorID,
+		Kind:       kind,
+		Message:    message,
+		TargetType: report.TargetType,
+		TargetID:   report.TargetID,
+		CreatedAt:  time.Now(),
+	}
+}
+
+func (n *Notification) MarkRead() {
+	if n.ReadAt == nil {
+		now := time.Now()
+		n.ReadAt = &now
+	}
+}
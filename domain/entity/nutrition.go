@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"sort"
+	"strings"
+)
+
+// NutritionFact is a single entry of the nutrition dataset. All values are per 100g.
+type NutritionFact struct {
+	Name         string   `json:"name"`
+	Calories     float64  `json:"calories"`
+	Protein      float64  `json:"protein"`
+	Carbohydrate float64  `json:"carbohydrate"`
+	Fat          float64  `json:"fat"`
+	ServingGrams float64  `json:"serving_grams"`
+	Allergens    []string `json:"allergens"`
+	// the aisle of the store the ingredient is found in, e.g "produce"
+	Aisle string `json:"aisle"`
+}
+
+// Nutrition is embedded into the food table, so the values can be filtered on
+type Nutrition struct {
+	Calories     float64 `gorm:"default:0" json:"calories"`
+	Protein      float64 `gorm:"default:0" json:"protein"`
+	Carbohydrate float64 `gorm:"default:0" json:"carbohydrate"`
+	Fat          float64 `gorm:"default:0" json:"fat"`
+	// comma separated and sorted, e.g "egg,gluten,milk"
+	Allergens string `gorm:"size:255;" json:"allergens"`
+}
+
+// Add the nutrition of the given amount of grams of fact
+func (n *Nutrition) Add(fact *NutritionFact, grams float64) {
+	factor := grams / 100
+	n.Calories += fact.Calories * factor
+	n.Protein += fact.Protein * factor
+	n.Carbohydrate += fact.Carbohydrate * factor
+	n.Fat += fact.Fat * factor
+	n.AddAllergens(fact.Allergens...)
+}
+
+func (n *Nutrition) AddAllergens(allergens ...string) {
+	set := map[string]bool{}
+	for _, a := range n.AllergenList() {
+		set[a] = true
+	}
+	for _, a := range allergens {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a != "" {
+			set[a] = true
+		}
+	}
+	list := make([]string, 0, len(set))
+	for a := range set {
+		list = append(list, a)
+	}
+	sort.Strings(list)
+	n.Allergens = strings.Join(list, ",")
+}
+
+func (n *Nutrition) AllergenList() []string {
+	if n.Allergens == "" {
+		return []string{}
+	}
+	return strings.Split(n.Allergens, ",")
+}
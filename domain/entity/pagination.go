@@ -0,0 +1,29 @@
+package entity
+
+const (
+	DefaultPerPage = 20
+	MaxPerPage     = 100
+)
+
+type Pagination struct {
+	Page    int   `json:"page"`
+	PerPage int   `json:"per_page"`
+	Total   int64 `json:"total"`
+}
+
+// Prepare falls back to the defaults when the page or the page size are out of range
+func (p *Pagination) Prepare() {
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	if p.PerPage < 1 {
+		p.PerPage = DefaultPerPage
+	}
+	if p.PerPage > MaxPerPage {
+		p.PerPage = MaxPerPage
+	}
+}
+
+func (p *Pagination) Offset() int {
+	return (p.Page - 1) * p.PerPage
+}
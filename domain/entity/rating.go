@@ -0,0 +1,35 @@
+package entity
+
+import "time"
+
+// a user can rate a food only once, rating it again replaces the previous stars
+type Rating struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	FoodID    uint64    `gorm:"not null;uniqueIndex:idx_rating_food_user" json:"food_id"`
+	UserID    uint64    `gorm:"not null;uniqueIndex:idx_rating_food_user" json:"user_id"`
+	Stars     int       `gorm:"not null;" json:"stars"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// RatingSummary is embedded into the food table. It is updated every time
+// a rating is given, changed or removed, so reading it is cheap.
+type RatingSummary struct {
+	Average float64 `gorm:"default:0" json:"average"`
+	Count   int64   `gorm:"default:0" json:"count"`
+	Total   int64   `gorm:"default:0" json:"-"`
+}
+
+func (r *Rating) Prepare() {
+	r.CreatedAt = time.Now()
+	r.UpdatedAt = time.Now()
+}
+
+func (r *Rating) Validate() map[string]string {
+	var errorMessages = make(map[string]string)
+
+	if r.Stars < 1 || r.Stars > 5 {
+		errorMessages["invalid_stars"] = "stars should be between 1 and 5"
+	}
+	return errorMessages
+}
@@ -0,0 +1,166 @@
+package entity
+
+import (
+	"html"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type ReportTarget string
+
+const (
+	ReportTargetFood    ReportTarget = "food"
+	ReportTargetComment ReportTarget = "comment"
+)
+
+type ReportReason string
+
+const (
+	ReportReasonSpam          ReportReason = "spam"
+	ReportReasonOffensive     ReportReason = "offensive"
+	ReportReasonInappropriate ReportReason = "inappropriate"
+	ReportReasonCopyright     ReportReason = "copyright"
+	ReportReasonOther         ReportReason = "other"
+	// ReportReasonKeyword is used by the auto-flagging, users cannot pick it
+	ReportReasonKeyword ReportReason = "keyword"
+)
+
+// the reasons a user can report content for
+var reportReasons = []ReportReason{ReportReasonSpam, ReportReasonOffensive, ReportReasonInappropriate, ReportReasonCopyright, ReportReasonOther}
+
+type ReportStatus string
+
+const (
+	ReportStatusOpen     ReportStatus = "open"
+	ReportStatusResolved ReportStatus = "resolved"
+)
+
+// ModerationAction is what a moderator decides about reported content
+type ModerationAction string
+
+const (
+	// ModerationApprove keeps the content, or brings it back when it was hidden
+	ModerationApprove ModerationAction = "approve"
+	ModerationHide    ModerationAction = "hide"
+	// ModerationBan hides the content and bans its author from posting
+	ModerationBan ModerationAction = "ban"
+)
+
+// Report is a user's complaint about a food or a comment. The open reports make the moderation queue,
+// a decision on one of them resolves all the open reports of the same content.
+type Report struct {
+	ID uint64 `gorm:"primary_key;auto_increment" json:"id"`
+	// ReporterID is nil for the reports made by the keyword auto-flagging
+	ReporterID *uint64          `gorm:"uniqueIndex:idx_report_reporter_target" json:"reporter_id"`
+	TargetType ReportTarget     `gorm:"size:20;not null;uniqueIndex:idx_report_reporter_target;index:idx_report_target" json:"target_type"`
+	TargetID   uint64           `gorm:"not null;uniqueIndex:idx_report_reporter_target;index:idx_report_target" json:"target_id"`
+	AuthorID   uint64           `gorm:"not null;index" json:"author_id"`
+	Reason     ReportReason     `gorm:"size:20;not null;" json:"reason"`
+	Details    string           `gorm:"size:500;" json:"details"`
+	Status     ReportStatus     `gorm:"size:20;not null;default:open;index" json:"status"`
+	Action     ModerationAction `gorm:"size:20;" json:"action,omitempty"`
+	ResolvedBy *uint64          `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time       `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+func (r *Report) Prepare() {
+	r.Reason = ReportReason(strings.ToLower(strings.TrimSpace(string(r.Reason))))
+	r.Details = html.EscapeString(strings.TrimSpace(r.Details))
+	r.Status = ReportStatusOpen
+	r.CreatedAt = time.Now()
+}
+
+func (r *Report) Validate() map[string]string {
+	var errorMessages = make(map[string]string)
+
+	if !IsReportReason(r.Reason) {
+		errorMessages["invalid_reason"] = "reason should be one of spam, offensive, inappropriate, copyright or other"
+	}
+	if r.Reason == ReportReasonOther && r.Details == "" {
+		errorMessages["details_required"] = "details are required when the reason is other"
+	}
+	if len(r.Details) > 500 {
+		errorMessages["details_too_long"] = "details should not be more than 500 characters"
+	}
+	return errorMessages
+}
+
+func (r *Report) IsOpen() bool {
+	return r.Status == ReportStatusOpen
+}
+
+// Resolve records the moderator's decision
+func (r *Report) Resolve(action ModerationAction, moderatorId uint64) {
+	now := time.Now()
+	r.Status = ReportStatusResolved
+	r.Action = action
+	r.ResolvedBy = &moderatorId
+	r.ResolvedAt = &now
+}
+
+func IsReportReason(reason ReportReason) bool {
+	for _, r := range reportReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+func IsModerationAction(action ModerationAction) bool {
+	return action == ModerationApprove || action == ModerationHide || action == ModerationBan
+}
+
+// NewKeywordReport flags content that contains some of the moderation keywords
+func NewKeywordReport(target ReportTarget, targetId uint64, authorId uint64, matches []string) *Report {
+	return &Report{
+		TargetType: target,
+		TargetID:   targetId,
+		AuthorID:   authorId,
+		Reason:     ReportReasonKeyword,
+		Details:    "matched: " + strings.Join(matches, ", "),
+		Status:     ReportStatusOpen,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// KeywordFilter finds the moderation keywords in user content. The keywords match whole words,
+// whatever their case, and a keyword can be made of several words.
+type KeywordFilter struct {
+	keywords []string
+}
+
+func NewKeywordFilter(keywords []string) *KeywordFilter {
+	filter := &KeywordFilter{}
+	for _, keyword := range keywords {
+		if normalized := normalizeWords(keyword); normalized != "" {
+			filter.keywords = append(filter.keywords, normalized)
+		}
+	}
+	return filter
+}
+
+// Match returns the keywords found in the texts
+func (f *KeywordFilter) Match(texts ...string) []string {
+	var matches []string
+	if len(f.keywords) == 0 {
+		return matches
+	}
+	content := " " + normalizeWords(html.UnescapeString(strings.Join(texts, " "))) + " "
+	for _, keyword := range f.keywords {
+		if strings.Contains(content, " "+keyword+" ") {
+			matches = append(matches, keyword)
+		}
+	}
+	return matches
+}
+
+// normalizeWords lowercases the text and keeps its words only, separated by single spaces
+func normalizeWords(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"html"
+	"strings"
+	"time"
+)
+
+type Review struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	FoodID    uint64    `gorm:"not null;index" json:"food_id"`
+	UserID    uint64    `gorm:"not null;" json:"user_id"`
+	Body      string    `gorm:"type:text;not null;" json:"body"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+func (r *Review) Prepare() {
+	r.Body = html.EscapeString(strings.TrimSpace(r.Body))
+	r.CreatedAt = time.Now()
+	r.UpdatedAt = time.Now()
+}
+
+func (r *Review) Validate() map[string]string {
+	var errorMessages = make(map[string]string)
+
+	if r.Body == "" || r.Body == "null" {
+		errorMessages["body_required"] = "review body is required"
+	}
+	if len(r.Body) > 5000 {
+		errorMessages["body_too_long"] = "review should not be more than 5000 characters"
+	}
+	return errorMessages
+}
@@ -0,0 +1,199 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// the aisles in the order a store is usually walked, the others come after them by name
+var aisleOrder = []string{"produce", "bakery", "meat", "seafood", "dairy", "refrigerated", "baking", "pantry", "spices", "beverages"}
+
+// AisleOther is the aisle of the items we know nothing about
+const AisleOther = "other"
+
+// ShoppingList is generated from foods or a meal plan. The ingredients of the foods are merged into items,
+// more items can be added by hand.
+type ShoppingList struct {
+	ID     uint64 `gorm:"primary_key;auto_increment" json:"id"`
+	UserID uint64 `gorm:"not null;index" json:"user_id"`
+	Name   string `gorm:"size:100;not null;" json:"name"`
+	// the list can be read with a signed link while it is set, clearing it revokes the links
+	ShareNonce string             `gorm:"size:32;" json:"-"`
+	Items      []ShoppingListItem `gorm:"foreignKey:ListID" json:"items"`
+	CreatedAt  time.Time          `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt  time.Time          `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+type ShoppingListItem struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	ListID    uint64    `gorm:"not null;index" json:"list_id"`
+	Name      string    `gorm:"size:100;not null;" json:"name"`
+	Quantity  float64   `gorm:"not null;default:0" json:"quantity"`
+	Unit      string    `gorm:"size:20;" json:"unit"`
+	Aisle     string    `gorm:"size:50;not null;default:other" json:"aisle"`
+	Checked   bool      `gorm:"not null;default:false" json:"checked"`
+	Manual    bool      `gorm:"not null;default:false" json:"manual"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// ShoppingListAisle is an aisle of a list with its items, the unchecked ones first
+type ShoppingListAisle struct {
+	Aisle string             `json:"aisle"`
+	Items []ShoppingListItem `json:"items"`
+}
+
+func (l *ShoppingList) Prepare() {
+	l.Name = html.EscapeString(strings.TrimSpace(l.Name))
+	l.CreatedAt = time.Now()
+	l.UpdatedAt = time.Now()
+}
+
+func (l *ShoppingList) Validate() map[string]string {
+	var errorMessages = make(map[string]string)
+
+	if l.Name == "" || l.Name == "null" {
+		errorMessages["name_required"] = "shopping list name is required"
+	}
+	if len(l.Name) > 100 {
+		errorMessages["name_too_long"] = "shopping list name should not be more than 100 characters"
+	}
+	return errorMessages
+}
+
+// AddIngredient adds the ingredient to the list, multiplied by servings. Ingredients with the same name
+// and kind of unit are merged into one item, in g, ml or pcs.
+func (l *ShoppingList) AddIngredient(ingredient Ingredient, servings float64, aisle string) {
+	quantity, unit := NormalizeQuantity(ingredient.Quantity*servings, ingredient.Unit)
+	name := strings.ToLower(strings.TrimSpace(ingredient.Name))
+	if aisle == "" {
+		aisle = AisleOther
+	}
+	for i := range l.Items {
+		item := &l.Items[i]
+		if !item.Manual && item.Name == name && item.Unit == unit {
+			item.Quantity += quantity
+			return
+		}
+	}
+	l.Items = append(l.Items, ShoppingListItem{
+		Name:      name,
+		Quantity:  quantity,
+		Unit:      unit,
+		Aisle:     aisle,
+		CreatedAt: time.Now(),
+	})
+}
+
+// Tidy rounds the merged quantities and switches to kg and l for the large ones
+func (l *ShoppingList) Tidy() {
+	for i := range l.Items {
+		item := &l.Items[i]
+		switch {
+		case item.Unit == "g" && item.Quantity >= 1000:
+			item.Quantity, item.Unit = item.Quantity/1000, "kg"
+		case item.Unit == "ml" && item.Quantity >= 1000:
+			item.Quantity, item.Unit = item.Quantity/1000, "l"
+		}
+		item.Quantity = math.Round(item.Quantity*100) / 100
+	}
+}
+
+// Aisles groups the items by aisle
+func (l *ShoppingList) Aisles() []ShoppingListAisle {
+	byAisle := map[string][]ShoppingListItem{}
+	for _, item := range l.Items {
+		byAisle[item.Aisle] = append(byAisle[item.Aisle], item)
+	}
+	names := make([]string, 0, len(byAisle))
+	for name := range byAisle {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := aisleRank(names[i]), aisleRank(names[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+
+	aisles := make([]ShoppingListAisle, 0, len(names))
+	for _, name := range names {
+		items := byAisle[name]
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].Checked != items[j].Checked {
+				return !items[i].Checked
+			}
+			return items[i].Name < items[j].Name
+		})
+		aisles = append(aisles, ShoppingListAisle{Aisle: name, Items: items})
+	}
+	return aisles
+}
+
+// aisleRank places the known aisles first, then the others, then "other"
+func aisleRank(aisle string) int {
+	for i, known := range aisleOrder {
+		if known == aisle {
+			return i
+		}
+	}
+	if aisle == AisleOther {
+		return len(aisleOrder) + 1
+	}
+	return len(aisleOrder)
+}
+
+// Share starts sharing the list, the signed links made before a Unshare stay revoked
+func (l *ShoppingList) Share() error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	l.ShareNonce = hex.EncodeToString(nonce)
+	return nil
+}
+
+func (l *ShoppingList) Unshare() {
+	l.ShareNonce = ""
+}
+
+func (l *ShoppingList) IsShared() bool {
+	return l.ShareNonce != ""
+}
+
+func (i *ShoppingListItem) Prepare() {
+	i.Name = html.EscapeString(strings.ToLower(strings.TrimSpace(i.Name)))
+	i.Unit = strings.ToLower(strings.TrimSpace(i.Unit))
+	i.Aisle = strings.ToLower(strings.TrimSpace(i.Aisle))
+	if i.Aisle == "" {
+		i.Aisle = AisleOther
+	}
+	i.CreatedAt = time.Now()
+}
+
+// Validate checks an item added by hand. The quantity and unit are optional, e.g "kitchen paper".
+func (i *ShoppingListItem) Validate() map[string]string {
+	var errorMessages = make(map[string]string)
+
+	if i.Name == "" || i.Name == "null" {
+		errorMessages["item_name_required"] = "item name is required"
+	}
+	if len(i.Name) > 100 {
+		errorMessages["item_name_too_long"] = "item name should not be more than 100 characters"
+	}
+	if i.Quantity < 0 {
+		errorMessages["invalid_item_quantity"] = "item quantity should not be negative"
+	}
+	if i.Unit != "" && !IsSupportedUnit(i.Unit) {
+		errorMessages["invalid_item_unit"] = "item unit is not supported"
+	}
+	if len(i.Aisle) > 50 {
+		errorMessages["aisle_too_long"] = "aisle should not be more than 50 characters"
+	}
+	return errorMessages
+}
@@ -0,0 +1,254 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type UploadStatus string
+
+const (
+	// the upload url was handed out, the file may or may not be there yet
+	UploadStatusPending UploadStatus = "pending"
+	// the file was checked and turned into an image, it can be attached to a food
+	UploadStatusConfirmed UploadStatus = "confirmed"
+	// the image is used by a food, it cannot be attached a second time
+	UploadStatusAttached UploadStatus = "attached"
+)
+
+// MaxUploadSize is the largest image that can be uploaded straight to the storage, see UPLOAD_MAX_SIZE
+var MaxUploadSize int64 = 10 << 20
+
+// MaxVideoSize and MaxVideoDuration bound the clips, see VIDEO_MAX_SIZE and VIDEO_MAX_DURATION
+var (
+	MaxVideoSize     int64 = 50 << 20
+	MaxVideoDuration       = time.Minute
+)
+
+const (
+	// UploadURLTTL is how long an upload url can be used
+	UploadURLTTL = 15 * time.Minute
+	// ResumableUploadTTL is how long a resumable upload can take, from its creation to its last chunk
+	ResumableUploadTTL = 24 * time.Hour
+)
+
+// ErrUploadOffset is returned when the offset of a resumable upload moved, e.g two chunks were sent at once
+var ErrUploadOffset = errors.New("the upload offset changed")
+
+// UploadContentTypes are the types of the images that can be uploaded
+var UploadContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// VideoContentTypes are the types of the clips that can be uploaded
+var VideoContentTypes = []string{"video/mp4", "video/webm"}
+
+// MediaKind tells how an uploaded file is processed
+type MediaKind string
+
+const (
+	MediaImage MediaKind = "image"
+	MediaVideo MediaKind = "video"
+)
+
+// UploadPolicy is what can be uploaded with a content type
+type UploadPolicy struct {
+	Kind    MediaKind
+	MaxSize int64
+	// MaxDuration bounds the videos
+	MaxDuration time.Duration
+}
+
+// UploadPolicyFor returns the policy of the content type, false when it cannot be uploaded
+func UploadPolicyFor(contentType string) (UploadPolicy, bool) {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	for _, imageType := range UploadContentTypes {
+		if contentType == imageType {
+			return UploadPolicy{Kind: MediaImage, MaxSize: MaxUploadSize}, true
+		}
+	}
+	for _, videoType := range VideoContentTypes {
+		if contentType == videoType {
+			return UploadPolicy{Kind: MediaVideo, MaxSize: MaxVideoSize, MaxDuration: MaxVideoDuration}, true
+		}
+	}
+	return UploadPolicy{}, false
+}
+
+// MaxUploadSizeOfAll is the largest file of any kind that can be uploaded
+func MaxUploadSizeOfAll() int64 {
+	if MaxVideoSize > MaxUploadSize {
+		return MaxVideoSize
+	}
+	return MaxUploadSize
+}
+
+// UploadedMedia is what a file was turned into once confirmed: an image, or a video with the image of its poster
+type UploadedMedia struct {
+	ImagePath string
+	VideoPath string
+	Duration  time.Duration
+	Width     int
+	Height    int
+}
+
+// Upload is a file uploaded by the client straight to the storage, with a presigned url.
+// It is bound to its user, its content type and its size, which are checked when it is confirmed.
+type Upload struct {
+	ID          uint64       `gorm:"primary_key;auto_increment" json:"id"`
+	UserID      uint64       `gorm:"not null;index" json:"user_id"`
+	Key         string       `gorm:"size:255;not null;uniqueIndex" json:"-"`
+	ContentType string       `gorm:"size:100;not null" json:"content_type"`
+	MaxSize     int64        `gorm:"not null" json:"max_size"`
+	Status      UploadStatus `gorm:"size:20;not null;default:pending" json:"status"`
+	// a resumable upload comes in chunks, the exact size of the file is then MaxSize.
+	// Offset is how much of it was received, and Metadata the Upload-Metadata header it was created with.
+	Resumable bool   `gorm:"not null;default:false" json:"resumable"`
+	Offset    int64  `gorm:"not null;default:0" json:"offset"`
+	Chunks    int    `gorm:"not null;default:0" json:"-"`
+	Metadata  string `gorm:"size:1024;" json:"-"`
+	// the url of the default rendition of the image, once confirmed. For a video, the image is its poster.
+	ImagePath string `gorm:"size:255;" json:"-"`
+	VideoPath string `gorm:"size:255;" json:"-"`
+	// the duration of a video, in milliseconds, and its size
+	DurationMs int64     `gorm:"not null;default:0" json:"-"`
+	Width      int       `gorm:"not null;default:0" json:"-"`
+	Height     int       `gorm:"not null;default:0" json:"-"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// MarshalJSON adds the image, or the video, once the upload is confirmed
+func (u Upload) MarshalJSON() ([]byte, error) {
+	type upload Upload
+	var video *Video
+	if u.VideoPath != "" {
+		video = NewVideo(u.VideoPath, u.ContentType, u.DurationMs, u.Width, u.Height, u.ImagePath)
+	}
+	return json.Marshal(struct {
+		upload
+		Image *ImageSet `json:"image"`
+		Video *Video    `json:"video,omitempty"`
+	}{upload(u), NewImageSet(u.ImagePath), video})
+}
+
+// SignURLs returns a copy of the upload with the urls of its image and video signed,
+// they are private until a published food uses them
+func (u *Upload) SignURLs(sign URLSigner) *Upload {
+	signed := *u
+	signed.ImagePath = sign.Sign(u.ImagePath)
+	signed.VideoPath = sign.Sign(u.VideoPath)
+	return &signed
+}
+
+// NewUpload prepares the upload of a file of at most maxSize bytes, under a key nobody can guess
+func NewUpload(userId uint64, contentType string, maxSize int64) (*Upload, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	if policy, ok := UploadPolicyFor(contentType); ok && maxSize == 0 {
+		maxSize = policy.MaxSize
+	}
+	now := time.Now()
+	return &Upload{
+		UserID:      userId,
+		Key:         fmt.Sprintf("incoming/%d/%s", userId, hex.EncodeToString(random)),
+		ContentType: strings.ToLower(strings.TrimSpace(contentType)),
+		MaxSize:     maxSize,
+		Status:      UploadStatusPending,
+		ExpiresAt:   now.Add(UploadURLTTL),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+
+// NewResumableUpload prepares the upload of a file of exactly length bytes, sent in chunks
+func NewResumableUpload(userId uint64, contentType string, length int64, metadata string) (*Upload, error) {
+	upload, err := NewUpload(userId, contentType, length)
+	if err != nil {
+		return nil, err
+	}
+	upload.Resumable = true
+	upload.Metadata = metadata
+	upload.ExpiresAt = upload.CreatedAt.Add(ResumableUploadTTL)
+	return upload, nil
+}
+
+func (u *Upload) Validate() map[string]string {
+	var errorMessages = make(map[string]string)
+
+	policy, ok := UploadPolicyFor(u.ContentType)
+	if !ok {
+		allowed := append(append([]string{}, UploadContentTypes...), VideoContentTypes...)
+		errorMessages["invalid_content_type"] = "content_type should be one of " + strings.Join(allowed, ", ")
+		return errorMessages
+	}
+	if u.MaxSize < 0 || u.MaxSize > policy.MaxSize {
+		errorMessages["invalid_size"] = fmt.Sprintf("size should be at most %d bytes", policy.MaxSize)
+	}
+	return errorMessages
+}
+
+// ChunkKey is where the n-th chunk of a resumable upload is stored until the file is complete
+func (u *Upload) ChunkKey(n int) string {
+	return fmt.Sprintf("%s.part%d", u.Key, n)
+}
+
+// ChunkKeys returns the keys of the chunks received so far, in order
+func (u *Upload) ChunkKeys() []string {
+	keys := make([]string, u.Chunks)
+	for i := range keys {
+		keys[i] = u.ChunkKey(i)
+	}
+	return keys
+}
+
+// AddChunk counts the bytes of a new chunk
+func (u *Upload) AddChunk(size int64) {
+	u.Offset += size
+	u.Chunks++
+	u.UpdatedAt = time.Now()
+}
+
+// IsComplete reports whether all the bytes of a resumable upload were received
+func (u *Upload) IsComplete() bool {
+	return u.Offset == u.MaxSize
+}
+
+// IsExpired reports whether the upload can no longer be sent, once confirmed it does not expire
+func (u *Upload) IsExpired(now time.Time) bool {
+	return u.Status == UploadStatusPending && now.After(u.ExpiresAt)
+}
+
+// Confirm records the image, or the video, the uploaded file was turned into
+func (u *Upload) Confirm(media *UploadedMedia) {
+	u.Status = UploadStatusConfirmed
+	u.ImagePath = media.ImagePath
+	u.VideoPath = media.VideoPath
+	u.DurationMs = media.Duration.Milliseconds()
+	u.Width = media.Width
+	u.Height = media.Height
+	u.UpdatedAt = time.Now()
+}
+
+// IsVideo reports whether the upload is a clip
+func (u *Upload) IsVideo() bool {
+	policy, _ := UploadPolicyFor(u.ContentType)
+	return policy.Kind == MediaVideo
+}
+
+// Files returns the stored files of a confirmed upload
+func (u *Upload) Files() []string {
+	files := []string{}
+	for _, file := range []string{u.ImagePath, u.VideoPath} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
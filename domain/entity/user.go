@@ -7,17 +7,21 @@ import (
 	"time"
 
 	"github.com/badoux/checkmail"
+	"gorm.io/gorm"
 )
 
 type User struct {
-	ID        uint16     `gorm:"primary_key;auto_increment" json:"id"`
-	FirstName string     `gorm:"size:100;not null;" json:"first_name"`
-	LastName  string     `gorm:"size:100;not null;" json:"last_name"`
-	Email     string     `gorm:"size:100;not null" json:"email"`
-	Password  string     `gorm:"size:100;not null" json:"password"`
-	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	ID        uint16 `gorm:"primary_key;auto_increment" json:"id"`
+	FirstName string `gorm:"size:100;not null;" json:"first_name"`
+	LastName  string `gorm:"size:100;not null;" json:"last_name"`
+	Email     string `gorm:"size:100;not null" json:"email"`
+	Password  string `gorm:"size:100;not null" json:"password"`
+	IsAdmin   bool   `gorm:"not null;default:false" json:"-"`
+	// a banned user can still sign in and read, but can no longer post
+	BannedAt  *time.Time     `json:"-"`
+	CreatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 }
 
 type PublicUser struct {
@@ -56,6 +60,22 @@ func (users Users) PublicUsers() []interface{} {
 	return result
 }
 
+// ExportProfile is the user as written to a data export, everything but the password
+func (u *User) ExportProfile() *ExportProfile {
+	return &ExportProfile{
+		ID:        uint64(u.ID),
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Email:     u.Email,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
+}
+
+func (u *User) IsBanned() bool {
+	return u.BannedAt != nil
+}
+
 func (u *User) Prepare() {
 	u.FirstName = html.EscapeString(strings.TrimSpace(u.FirstName))
 	u.LastName = html.EscapeString(strings.TrimSpace(u.LastName))
@@ -0,0 +1,14 @@
+package repository
+
+import "learning-golang-ddd/domain/entity"
+
+type CollectionRepository interface {
+	SaveCollection(*entity.Collection) (*entity.Collection, map[string]string)
+	GetCollection(uint64) (*entity.Collection, error)
+	GetCollectionsByUser(userId uint64, publicOnly bool) ([]entity.Collection, error)
+	UpdateCollection(*entity.Collection) (*entity.Collection, map[string]string)
+	DeleteCollection(uint64) error
+	AddCollectionItem(*entity.CollectionItem) (*entity.CollectionItem, map[string]string)
+	RemoveCollectionItem(collectionId uint64, foodId uint64) error
+	ReorderCollectionItems(collectionId uint64, foodIds []uint64) map[string]string
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "learning-golang-ddd/domain/entity"
+
+type CommentRepository interface {
+	SaveComment(*entity.Comment) (*entity.Comment, map[string]string)
+	GetComment(uint64) (*entity.Comment, error)
+	GetCommentThreads(uint64, *entity.Pagination) ([]*entity.Comment, error)
+	UpdateComment(*entity.Comment) (*entity.Comment, map[string]string)
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"time"
+)
+
+type DataExportRepository interface {
+	SaveDataExport(*entity.DataExport) (*entity.DataExport, map[string]string)
+	GetDataExport(uint64) (*entity.DataExport, error)
+	GetDataExportByPath(string) (*entity.DataExport, error)
+	GetActiveDataExport(userId uint64) (*entity.DataExport, error)
+	ClaimDataExport() (*entity.DataExport, error)
+	UpdateDataExport(*entity.DataExport) error
+	DeleteExpiredDataExports(time.Time) ([]entity.DataExport, error)
+}
@@ -0,0 +1,9 @@
+package repository
+
+import "learning-golang-ddd/domain/entity"
+
+type FavoriteRepository interface {
+	SaveFavorite(*entity.Favorite) (*entity.Favorite, map[string]string)
+	GetFavoritesByUser(uint64) ([]entity.Favorite, error)
+	DeleteFavorite(userId uint64, foodId uint64) error
+}
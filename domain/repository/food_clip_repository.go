@@ -0,0 +1,12 @@
+package repository
+
+import "learning-golang-ddd/domain/entity"
+
+type FoodClipRepository interface {
+	AddClip(*entity.FoodClip) (*entity.FoodClip, map[string]string)
+	GetClip(uint64) (*entity.FoodClip, error)
+	GetClipsByFood(uint64) ([]entity.FoodClip, error)
+	UpdateClip(*entity.FoodClip) (*entity.FoodClip, map[string]string)
+	DeleteClip(uint64) error
+	ReorderClips(foodId uint64, clipIds []uint64) map[string]string
+}
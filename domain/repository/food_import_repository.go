@@ -0,0 +1,10 @@
+package repository
+
+import "learning-golang-ddd/domain/entity"
+
+type FoodImportRepository interface {
+	SaveFoodImport(*entity.FoodImport) (*entity.FoodImport, map[string]string)
+	GetFoodImport(uint64) (*entity.FoodImport, error)
+	ClaimFoodImport() (*entity.FoodImport, error)
+	UpdateFoodImport(*entity.FoodImport) error
+}
@@ -1,11 +1,24 @@
 package repository
 
-import "learning-golang-ddd/domain/entity"
+import (
+	"learning-golang-ddd/domain/entity"
+	"time"
+)
 
 type FoodRepository interface {
 	SaveFood(*entity.Food) (*entity.Food, map[string]string)
 	GetFood(uint64) (*entity.Food, error)
+	FoodTitleExists(string) (bool, error)
 	GetAllFood() ([]entity.Food, error)
+	GetAllFoodByFilter(*entity.FoodFilter) ([]entity.Food, error)
+	GetFoodsByUser(uint64) ([]entity.Food, error)
+	GetFoodsChangedSince(time.Time) ([]entity.Food, error)
 	UpdateFood(*entity.Food) (*entity.Food, map[string]string)
+	UpdateFoodStatus(*entity.Food) (*entity.Food, map[string]string)
+	PublishScheduledFoods(time.Time) ([]entity.Food, error)
 	DeleteFood(uint64) error
+	GetDeletedFood(uint64) (*entity.Food, error)
+	GetDeletedFoodsByUser(uint64) ([]entity.Food, error)
+	RestoreFood(uint64) error
+	PurgeDeletedFoods(time.Time) ([]entity.Food, error)
 }
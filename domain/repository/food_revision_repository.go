@@ -0,0 +1,9 @@
+package repository
+
+import "learning-golang-ddd/domain/entity"
+
+// the revisions are written by the FoodRepository every time a food is saved, so they can only be read
+type FoodRevisionRepository interface {
+	GetRevisions(uint64) ([]entity.FoodRevision, error)
+	GetRevision(foodId uint64, revision int) (*entity.FoodRevision, error)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"time"
+)
+
+type GalleryRepository interface {
+	AddImage(*entity.GalleryImage) (*entity.GalleryImage, map[string]string)
+	GetImage(uint64) (*entity.GalleryImage, error)
+	GetImagesByFood(uint64) ([]entity.GalleryImage, error)
+	UserHasImage(userId uint64, path string) (bool, error)
+	UpdateImage(*entity.GalleryImage) (*entity.GalleryImage, map[string]string)
+	SetCoverImage(foodId uint64, imageId uint64) map[string]string
+	DeleteImage(uint64) error
+	ReorderImages(foodId uint64, imageIds []uint64) map[string]string
+	CountImageRefs(path string) (int64, error)
+	CountImageRefsByKey(key string) (int64, error)
+	IsImageVisibleTo(path string, userId uint64) (bool, error)
+	LockImage(key string, fn func() error) error
+	HoldImage(path string, until time.Time) error
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"time"
+)
+
+type MealPlanRepository interface {
+	SaveMealPlanEntry(*entity.MealPlanEntry) (*entity.MealPlanEntry, map[string]string)
+	GetMealPlanEntry(uint64) (*entity.MealPlanEntry, error)
+	GetMealPlanEntries(userId uint64, from time.Time, to time.Time) ([]entity.MealPlanEntry, error)
+	UpdateMealPlanEntry(*entity.MealPlanEntry) (*entity.MealPlanEntry, map[string]string)
+	DeleteMealPlanEntry(uint64) error
+	CopyMealPlanWeek(userId uint64, from time.Time, to time.Time, replace bool) ([]entity.MealPlanEntry, error)
+	SaveCalendarFeed(*entity.CalendarFeed) (*entity.CalendarFeed, map[string]string)
+	GetCalendarFeed(token string) (*entity.CalendarFeed, error)
+}
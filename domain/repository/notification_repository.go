@@ -0,0 +1,9 @@
+package repository
+
+import "learning-golang-ddd/domain/entity"
+
+type NotificationRepository interface {
+	GetNotification(uint64) (*entity.Notification, error)
+	GetNotifications(uint64, *entity.Pagination) ([]entity.Notification, error)
+	MarkNotificationRead(*entity.Notification) map[string]string
+}
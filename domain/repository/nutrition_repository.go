@@ -0,0 +1,7 @@
+package repository
+
+import "learning-golang-ddd/domain/entity"
+
+type NutritionRepository interface {
+	GetNutritionFact(string) (*entity.NutritionFact, error)
+}
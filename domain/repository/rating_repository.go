@@ -0,0 +1,9 @@
+package repository
+
+import "learning-golang-ddd/domain/entity"
+
+type RatingRepository interface {
+	SaveRating(*entity.Rating) (*entity.Rating, map[string]string)
+	GetRating(foodId uint64, userId uint64) (*entity.Rating, error)
+	DeleteRating(foodId uint64, userId uint64) error
+}
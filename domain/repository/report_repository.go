@@ -0,0 +1,11 @@
+package repository
+
+import "learning-golang-ddd/domain/entity"
+
+type ReportRepository interface {
+	SaveReport(*entity.Report) (*entity.Report, map[string]string)
+	GetReport(uint64) (*entity.Report, error)
+	GetReports(entity.ReportStatus, *entity.Pagination) ([]entity.Report, error)
+	HasOpenReport(entity.ReportTarget, uint64, entity.ReportReason) (bool, error)
+	ModerateReport(*entity.Report, entity.ModerationAction, uint64) ([]entity.Report, map[string]string)
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "learning-golang-ddd/domain/entity"
+
+type ReviewRepository interface {
+	SaveReview(*entity.Review) (*entity.Review, map[string]string)
+	GetReview(uint64) (*entity.Review, error)
+	GetReviewsByFood(uint64) ([]entity.Review, error)
+	UpdateReview(*entity.Review) (*entity.Review, map[string]string)
+	DeleteReview(uint64) error
+}
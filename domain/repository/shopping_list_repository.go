@@ -0,0 +1,15 @@
+package repository
+
+import "learning-golang-ddd/domain/entity"
+
+type ShoppingListRepository interface {
+	SaveShoppingList(*entity.ShoppingList) (*entity.ShoppingList, map[string]string)
+	GetShoppingList(uint64) (*entity.ShoppingList, error)
+	GetShoppingListsByUser(uint64) ([]entity.ShoppingList, error)
+	UpdateShoppingListShare(*entity.ShoppingList) map[string]string
+	DeleteShoppingList(uint64) error
+	SaveShoppingListItem(*entity.ShoppingListItem) (*entity.ShoppingListItem, map[string]string)
+	GetShoppingListItem(uint64) (*entity.ShoppingListItem, error)
+	UpdateShoppingListItem(*entity.ShoppingListItem) (*entity.ShoppingListItem, map[string]string)
+	DeleteShoppingListItem(uint64) error
+}
@@ -0,0 +1,14 @@
+package repository
+
+import "learning-golang-ddd/domain/entity"
+
+type UploadRepository interface {
+	SaveUpload(*entity.Upload) (*entity.Upload, map[string]string)
+	GetUpload(uint64) (*entity.Upload, error)
+	GetUploadByKey(string) (*entity.Upload, error)
+	UpdateUpload(*entity.Upload) error
+	UpdateUploadOffset(upload *entity.Upload, from int64) error
+	DeleteUpload(uint64) error
+	AttachUpload(uploadId uint64, userId uint64, kind entity.MediaKind) (*entity.Upload, map[string]string)
+	DetachUpload(uint64) error
+}
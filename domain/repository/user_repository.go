@@ -1,10 +1,16 @@
 package repository
 
-import "learning-golang-ddd/domain/entity"
+import (
+	"learning-golang-ddd/domain/entity"
+	"time"
+)
 
 type UserRepository interface {
 	SaveUser(*entity.User) (*entity.User, map[string]string)
 	GetUser(uint64) (*entity.User, error)
 	GetUsers() ([]entity.User, error)
 	GetUserByFilter(*entity.User) (*entity.User, map[string]string)
+	DeleteUser(uint64) error
+	RestoreUser(uint64) error
+	PurgeDeletedUsers(time.Time) ([]entity.User, error)
 }
@@ -0,0 +1,34 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"learning-golang-ddd/domain/entity"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// the redis channel the events are published on
+const Channel = "events"
+
+type PublisherInterface interface {
+	Publish(*entity.Event) error
+}
+
+type RedisPublisher struct {
+	client *redis.Client
+}
+
+var _ PublisherInterface = &RedisPublisher{}
+
+func NewRedisPublisher(client *redis.Client) *RedisPublisher {
+	return &RedisPublisher{client: client}
+}
+
+func (p *RedisPublisher) Publish(event *entity.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.client.Publish(context.Background(), Channel, payload).Err()
+}
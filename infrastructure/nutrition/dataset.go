@@ -0,0 +1,96 @@
+package nutrition
+
+import (
+	"embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// the dataset that ships with the binary, used when no other dataset is configured
+//
+//go:embed data/nutrition.csv
+var bundled embed.FS
+
+type Dataset struct {
+	facts map[string]*entity.NutritionFact
+}
+
+// Dataset implements the repository.NutritionRepository interface
+var _ repository.NutritionRepository = &Dataset{}
+
+// NewDataset loads the nutrition dataset. When path is empty the bundled dataset is used.
+func NewDataset(path string) (*Dataset, error) {
+	var r io.ReadCloser
+	var err error
+	if path == "" {
+		r, err = bundled.Open("data/nutrition.csv")
+	} else {
+		r, err = os.Open(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 1 {
+		return nil, errors.New("nutrition dataset is empty")
+	}
+
+	d := &Dataset{facts: make(map[string]*entity.NutritionFact, len(records)-1)}
+	// the first row is the header:
+	// name,calories,protein,carbohydrate,fat,serving_grams,allergens[,aisle]
+	for line, record := range records[1:] {
+		if len(record) != 7 && len(record) != 8 {
+			return nil, fmt.Errorf("nutrition dataset line %d: expected 7 or 8 columns, got %d", line+2, len(record))
+		}
+		fact := &entity.NutritionFact{Name: normalize(record[0])}
+		values := []*float64{&fact.Calories, &fact.Protein, &fact.Carbohydrate, &fact.Fat, &fact.ServingGrams}
+		for i, v := range values {
+			*v, err = strconv.ParseFloat(strings.TrimSpace(record[i+1]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("nutrition dataset line %d: %v", line+2, err)
+			}
+		}
+		fact.Allergens = []string{}
+		for _, a := range strings.Split(record[6], ";") {
+			if a = strings.TrimSpace(a); a != "" {
+				fact.Allergens = append(fact.Allergens, a)
+			}
+		}
+		// the aisle column is optional, for the datasets made before it was added
+		if len(record) == 8 {
+			fact.Aisle = strings.ToLower(strings.TrimSpace(record[7]))
+		}
+		d.facts[fact.Name] = fact
+	}
+	return d, nil
+}
+
+func (d *Dataset) GetNutritionFact(name string) (*entity.NutritionFact, error) {
+	name = normalize(name)
+	if fact, ok := d.facts[name]; ok {
+		return fact, nil
+	}
+	// "eggs" and "tomatoes" should still match "egg" and "tomato"
+	for _, singular := range []string{strings.TrimSuffix(name, "es"), strings.TrimSuffix(name, "s")} {
+		if fact, ok := d.facts[singular]; ok {
+			return fact, nil
+		}
+	}
+	return nil, errors.New("nutrition fact not found")
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,164 @@
+package persistence
+
+import (
+	"errors"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type CollectionRepo struct {
+	db *gorm.DB
+}
+
+func NewCollectionRepository(db *gorm.DB) *CollectionRepo {
+	return &CollectionRepo{db}
+}
+
+// CollectionRepo implements the repository.CollectionRepository interface
+var _ repository.CollectionRepository = &CollectionRepo{}
+
+func (r *CollectionRepo) SaveCollection(collection *entity.Collection) (*entity.Collection, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Omit("Items").Create(&collection).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	collection.Items = []entity.CollectionItem{}
+	return collection, nil
+}
+
+func (r *CollectionRepo) GetCollection(id uint64) (*entity.Collection, error) {
+	var collection entity.Collection
+	err := r.db.Debug().
+		Preload("Items", orderByPosition).
+		Preload("Items.Food").
+		Where("id = ?", id).Take(&collection).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("collection not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &collection, nil
+}
+
+func (r *CollectionRepo) GetCollectionsByUser(userId uint64, publicOnly bool) ([]entity.Collection, error) {
+	var collections []entity.Collection
+	query := r.db.Debug().
+		Preload("Items", orderByPosition).
+		Where("user_id = ?", userId)
+	if publicOnly {
+		query = query.Where("is_public = ?", true)
+	}
+	err := query.Order("created_at desc").Find(&collections).Error
+	if err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+func (r *CollectionRepo) UpdateCollection(collection *entity.Collection) (*entity.Collection, map[string]string) {
+	dbErr := map[string]string{}
+	// the items are managed on their own, they are not touched when renaming a collection
+	err := r.db.Debug().Omit("Items").Save(&collection).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return collection, nil
+}
+
+func (r *CollectionRepo) DeleteCollection(id uint64) error {
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ?", id).Delete(&entity.CollectionItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&entity.Collection{}).Error
+	})
+	if err != nil {
+		return errors.New("database error, please try again")
+	}
+	return nil
+}
+
+// AddCollectionItem appends the food at the end of the collection
+func (r *CollectionRepo) AddCollectionItem(item *entity.CollectionItem) (*entity.CollectionItem, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		var last struct{ Position *int }
+		err := tx.Model(&entity.CollectionItem{}).Select("MAX(position) AS position").
+			Where("collection_id = ?", item.CollectionID).Scan(&last).Error
+		if err != nil {
+			return err
+		}
+		item.Position = 0
+		if last.Position != nil {
+			item.Position = *last.Position + 1
+		}
+		return tx.Omit("Food").Create(&item).Error
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "Duplicate") {
+			dbErr["already_in_collection"] = "food is already in this collection"
+			return nil, dbErr
+		}
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return item, nil
+}
+
+func (r *CollectionRepo) RemoveCollectionItem(collectionId uint64, foodId uint64) error {
+	result := r.db.Debug().Where("collection_id = ? AND food_id = ?", collectionId, foodId).Delete(&entity.CollectionItem{})
+	if result.Error != nil {
+		return errors.New("database error, please try again")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("food is not in this collection")
+	}
+	return nil
+}
+
+// ReorderCollectionItems sets the position of every item from the given order.
+// All the foods of the collection should be given, each once.
+func (r *CollectionRepo) ReorderCollectionItems(collectionId uint64, foodIds []uint64) map[string]string {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		var items []entity.CollectionItem
+		if err := tx.Where("collection_id = ?", collectionId).Find(&items).Error; err != nil {
+			return err
+		}
+		positions := make(map[uint64]int, len(foodIds))
+		for i, foodId := range foodIds {
+			positions[foodId] = i
+		}
+		if len(positions) != len(items) || len(foodIds) != len(items) {
+			dbErr["invalid_order"] = "the order should contain every food of the collection exactly once"
+			return errors.New("invalid order")
+		}
+		for _, item := range items {
+			position, ok := positions[item.FoodID]
+			if !ok {
+				dbErr["invalid_order"] = "the order should contain every food of the collection exactly once"
+				return errors.New("invalid order")
+			}
+			err := tx.Model(&entity.CollectionItem{}).Where("id = ?", item.ID).UpdateColumn("position", position).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if len(dbErr) > 0 {
+		return dbErr
+	}
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return dbErr
+	}
+	return nil
+}
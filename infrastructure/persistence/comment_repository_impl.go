@@ -0,0 +1,89 @@
+package persistence
+
+import (
+	"errors"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type CommentRepo struct {
+	db *gorm.DB
+}
+
+func NewCommentRepository(db *gorm.DB) *CommentRepo {
+	return &CommentRepo{db}
+}
+
+// CommentRepo implements the repository.CommentRepository interface
+var _ repository.CommentRepository = &CommentRepo{}
+
+func (r *CommentRepo) SaveComment(comment *entity.Comment) (*entity.Comment, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		// a top level comment is the root of its own thread
+		if comment.ParentID == nil {
+			comment.RootID = comment.ID
+			return tx.Model(comment).UpdateColumn("root_id", comment.ID).Error
+		}
+		return nil
+	})
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return comment, nil
+}
+
+func (r *CommentRepo) GetComment(id uint64) (*entity.Comment, error) {
+	var comment entity.Comment
+	err := r.db.Debug().Where("id = ?", id).Take(&comment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("comment not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &comment, nil
+}
+
+// GetCommentThreads paginates the top level comments of the food, and loads all the replies of the page's threads
+func (r *CommentRepo) GetCommentThreads(foodId uint64, page *entity.Pagination) ([]*entity.Comment, error) {
+	var roots []entity.Comment
+	err := r.db.Debug().Model(&entity.Comment{}).Where("food_id = ? AND parent_id IS NULL", foodId).Count(&page.Total).Error
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.Debug().Where("food_id = ? AND parent_id IS NULL", foodId).Order("created_at desc").Limit(page.PerPage).Offset(page.Offset()).Find(&roots).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(roots) == 0 {
+		return []*entity.Comment{}, nil
+	}
+
+	rootIds := make([]uint64, len(roots))
+	for i, root := range roots {
+		rootIds[i] = root.ID
+	}
+	var replies []entity.Comment
+	err = r.db.Debug().Where("root_id IN ? AND parent_id IS NOT NULL", rootIds).Order("created_at asc").Find(&replies).Error
+	if err != nil {
+		return nil, err
+	}
+	return entity.BuildCommentThreads(roots, replies), nil
+}
+
+func (r *CommentRepo) UpdateComment(comment *entity.Comment) (*entity.Comment, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Save(&comment).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return comment, nil
+}
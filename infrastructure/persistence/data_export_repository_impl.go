@@ -0,0 +1,133 @@
+package persistence
+
+import (
+	"errors"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DataExportRepo struct {
+	db *gorm.DB
+}
+
+func NewDataExportRepository(db *gorm.DB) *DataExportRepo {
+	return &DataExportRepo{db}
+}
+
+// DataExportRepo implements the repository.DataExportRepository interface
+var _ repository.DataExportRepository = &DataExportRepo{}
+
+func (r *DataExportRepo) SaveDataExport(export *entity.DataExport) (*entity.DataExport, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Create(export).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return export, nil
+}
+
+func (r *DataExportRepo) GetDataExport(id uint64) (*entity.DataExport, error) {
+	var export entity.DataExport
+	err := r.db.Debug().Where("id = ?", id).Take(&export).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("export not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &export, nil
+}
+
+// GetDataExportByPath returns the export of an archive, nil is returned when there is none
+func (r *DataExportRepo) GetDataExportByPath(path string) (*entity.DataExport, error) {
+	var export entity.DataExport
+	err := r.db.Debug().Where("path = ?", path).Take(&export).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &export, nil
+}
+
+// GetActiveDataExport returns the export of the user that is still being built
+func (r *DataExportRepo) GetActiveDataExport(userId uint64) (*entity.DataExport, error) {
+	var export entity.DataExport
+	err := r.db.Debug().
+		Where("user_id = ? AND status IN ?", userId, []entity.DataExportStatus{entity.DataExportStatusPending, entity.DataExportStatusRunning}).
+		Take(&export).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("export not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &export, nil
+}
+
+// ClaimDataExport marks the oldest pending export as running and returns it, nil is returned when there is none.
+// The running exports that saved no progress for too long are pending again first, their job is gone.
+func (r *DataExportRepo) ClaimDataExport() (*entity.DataExport, error) {
+	var export entity.DataExport
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Model(&entity.DataExport{}).
+			Where("status = ? AND updated_at < ?", entity.DataExportStatusRunning, now.Add(-entity.RunningExportTimeout)).
+			UpdateColumns(map[string]interface{}{"status": entity.DataExportStatusPending, "updated_at": now}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", entity.DataExportStatusPending).
+			Order("id asc").Take(&export).Error
+		if err != nil {
+			return err
+		}
+		export.Status = entity.DataExportStatusRunning
+		export.UpdatedAt = now
+		return tx.Model(&export).UpdateColumns(map[string]interface{}{"status": export.Status, "updated_at": now}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *DataExportRepo) UpdateDataExport(export *entity.DataExport) error {
+	export.UpdatedAt = time.Now()
+	return r.db.Debug().Model(export).
+		Select("status", "path", "error", "expires_at", "finished_at", "updated_at").
+		Updates(export).Error
+}
+
+// DeleteExpiredDataExports deletes the exports whose link expired before the given time, and returns them,
+// so their archives can be deleted too.
+func (r *DataExportRepo) DeleteExpiredDataExports(before time.Time) ([]entity.DataExport, error) {
+	var exports []entity.DataExport
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("expires_at < ?", before).
+			Limit(100).Find(&exports).Error
+		if err != nil || len(exports) == 0 {
+			return err
+		}
+		ids := make([]uint64, len(exports))
+		for i, export := range exports {
+			ids[i] = export.ID
+		}
+		return tx.Where("id IN ?", ids).Delete(&entity.DataExport{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
@@ -11,9 +11,24 @@ import (
 )
 
 type Repositories struct {
-	User repository.UserRepository
-	Food repository.FoodRepository
-	db   *gorm.DB
+	User         repository.UserRepository
+	Food         repository.FoodRepository
+	Rating       repository.RatingRepository
+	Review       repository.ReviewRepository
+	Comment      repository.CommentRepository
+	Favorite     repository.FavoriteRepository
+	Collection   repository.CollectionRepository
+	FoodRevision repository.FoodRevisionRepository
+	Gallery      repository.GalleryRepository
+	FoodClip     repository.FoodClipRepository
+	FoodImport   repository.FoodImportRepository
+	DataExport   repository.DataExportRepository
+	MealPlan     repository.MealPlanRepository
+	ShoppingList repository.ShoppingListRepository
+	Report       repository.ReportRepository
+	Notification repository.NotificationRepository
+	Upload       repository.UploadRepository
+	db           *gorm.DB
 }
 
 func NewRepositories(dbUser, dbPassword, dbPort, dbHost, dbName string) (*Repositories, error) {
@@ -25,9 +40,24 @@ func NewRepositories(dbUser, dbPassword, dbPort, dbHost, dbName string) (*Reposi
 	db.Logger.LogMode(logger.LogLevel(4))
 
 	return &Repositories{
-		User: NewUserRepository(db),
-		Food: NewFoodRepository(db),
-		db:   db,
+		User:         NewUserRepository(db),
+		Food:         NewFoodRepository(db),
+		Rating:       NewRatingRepository(db),
+		Review:       NewReviewRepository(db),
+		Comment:      NewCommentRepository(db),
+		Favorite:     NewFavoriteRepository(db),
+		Collection:   NewCollectionRepository(db),
+		FoodRevision: NewFoodRevisionRepository(db),
+		Gallery:      NewGalleryRepository(db),
+		FoodClip:     NewFoodClipRepository(db),
+		FoodImport:   NewFoodImportRepository(db),
+		DataExport:   NewDataExportRepository(db),
+		MealPlan:     NewMealPlanRepository(db),
+		ShoppingList: NewShoppingListRepository(db),
+		Report:       NewReportRepository(db),
+		Notification: NewNotificationRepository(db),
+		Upload:       NewUploadRepository(db),
+		db:           db,
 	}, nil
 }
 
@@ -43,5 +73,43 @@ func (r *Repositories) Close() error {
 
 // this migrate all tables
 func (r *Repositories) Automigrate() error {
-	return r.db.AutoMigrate(&entity.User{}, &entity.Food{})
+	err := r.db.AutoMigrate(
+		&entity.User{},
+		&entity.Food{},
+		&entity.Ingredient{},
+		&entity.Rating{},
+		&entity.Review{},
+		&entity.Comment{},
+		&entity.Favorite{},
+		&entity.Collection{},
+		&entity.CollectionItem{},
+		&entity.FoodRevision{},
+		&entity.GalleryImage{},
+		&entity.FoodClip{},
+		&entity.FoodImport{},
+		&entity.DataExport{},
+		&entity.MealPlanEntry{},
+		&entity.CalendarFeed{},
+		&entity.ShoppingList{},
+		&entity.ShoppingListItem{},
+		&entity.Report{},
+		&entity.Notification{},
+		&entity.Upload{},
+		&entity.ImageHold{},
+	)
+	if err != nil {
+		return err
+	}
+	// the titles were unique among all the foods, the trashed ones too, before they became the partial idx_food_title
+	return r.db.Exec("ALTER TABLE foods DROP CONSTRAINT IF EXISTS foods_title_key").Error
+}
+
+// orderByPosition sorts the preloaded items of an ordered list, e.g a collection or a gallery
+func orderByPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("position asc")
+}
+
+// orderById keeps the preloaded items in the order they were added
+func orderById(db *gorm.DB) *gorm.DB {
+	return db.Order("id asc")
 }
@@ -0,0 +1,56 @@
+package persistence
+
+import (
+	"errors"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type FavoriteRepo struct {
+	db *gorm.DB
+}
+
+func NewFavoriteRepository(db *gorm.DB) *FavoriteRepo {
+	return &FavoriteRepo{db}
+}
+
+// FavoriteRepo implements the repository.FavoriteRepository interface
+var _ repository.FavoriteRepository = &FavoriteRepo{}
+
+func (r *FavoriteRepo) SaveFavorite(favorite *entity.Favorite) (*entity.Favorite, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Create(&favorite).Error
+	if err != nil {
+		// a food can only be bookmarked once by the same user
+		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "Duplicate") {
+			dbErr["already_favorite"] = "food is already in your favorites"
+			return nil, dbErr
+		}
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return favorite, nil
+}
+
+func (r *FavoriteRepo) GetFavoritesByUser(userId uint64) ([]entity.Favorite, error) {
+	var favorites []entity.Favorite
+	err := r.db.Debug().Preload("Food").Where("user_id = ?", userId).Order("created_at desc").Find(&favorites).Error
+	if err != nil {
+		return nil, err
+	}
+	return favorites, nil
+}
+
+func (r *FavoriteRepo) DeleteFavorite(userId uint64, foodId uint64) error {
+	result := r.db.Debug().Where("user_id = ? AND food_id = ?", userId, foodId).Delete(&entity.Favorite{})
+	if result.Error != nil {
+		return errors.New("database error, please try again")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("favorite not found")
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package persistence
+
+import (
+	"errors"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type FoodClipRepo struct {
+	db *gorm.DB
+}
+
+func NewFoodClipRepository(db *gorm.DB) *FoodClipRepo {
+	return &FoodClipRepo{db}
+}
+
+// FoodClipRepo implements the repository.FoodClipRepository interface
+var _ repository.FoodClipRepository = &FoodClipRepo{}
+
+// AddClip appends the clip after the other clips of the food
+func (r *FoodClipRepo) AddClip(clip *entity.FoodClip) (*entity.FoodClip, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		var last struct{ Position *int }
+		err := tx.Model(&entity.FoodClip{}).Select("MAX(position) AS position").
+			Where("food_id = ?", clip.FoodID).Scan(&last).Error
+		if err != nil {
+			return err
+		}
+		clip.Position = 0
+		if last.Position != nil {
+			clip.Position = *last.Position + 1
+		}
+		if err := tx.Create(clip).Error; err != nil {
+			return err
+		}
+		return bumpFoodVersion(tx, clip.FoodID)
+	})
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return clip, nil
+}
+
+func (r *FoodClipRepo) GetClip(id uint64) (*entity.FoodClip, error) {
+	var clip entity.FoodClip
+	err := r.db.Debug().Where("id = ?", id).Take(&clip).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("clip not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &clip, nil
+}
+
+func (r *FoodClipRepo) GetClipsByFood(foodId uint64) ([]entity.FoodClip, error) {
+	var clips []entity.FoodClip
+	err := r.db.Debug().Where("food_id = ?", foodId).Order("position asc").Find(&clips).Error
+	if err != nil {
+		return nil, err
+	}
+	return clips, nil
+}
+
+func (r *FoodClipRepo) UpdateClip(clip *entity.FoodClip) (*entity.FoodClip, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(clip).UpdateColumn("caption", clip.Caption).Error; err != nil {
+			return err
+		}
+		return bumpFoodVersion(tx, clip.FoodID)
+	})
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return clip, nil
+}
+
+func (r *FoodClipRepo) DeleteClip(id uint64) error {
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		var clip entity.FoodClip
+		if err := tx.Where("id = ?", id).Take(&clip).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&clip).Error; err != nil {
+			return err
+		}
+		return bumpFoodVersion(tx, clip.FoodID)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("clip not found")
+	}
+	if err != nil {
+		return errors.New("database error, please try again")
+	}
+	return nil
+}
+
+// ReorderClips sets the position of every clip from the given order.
+// All the clips of the food should be given, each once.
+func (r *FoodClipRepo) ReorderClips(foodId uint64, clipIds []uint64) map[string]string {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		var clips []entity.FoodClip
+		if err := tx.Where("food_id = ?", foodId).Find(&clips).Error; err != nil {
+			return err
+		}
+		positions := make(map[uint64]int, len(clipIds))
+		for i, clipId := range clipIds {
+			positions[clipId] = i
+		}
+		if len(positions) != len(clips) || len(clipIds) != len(clips) {
+			dbErr["invalid_order"] = "the order should contain every clip of the food exactly once"
+			return errors.New("invalid order")
+		}
+		for _, clip := range clips {
+			position, ok := positions[clip.ID]
+			if !ok {
+				dbErr["invalid_order"] = "the order should contain every clip of the food exactly once"
+				return errors.New("invalid order")
+			}
+			err := tx.Model(&entity.FoodClip{}).Where("id = ?", clip.ID).UpdateColumn("position", position).Error
+			if err != nil {
+				return err
+			}
+		}
+		return bumpFoodVersion(tx, foodId)
+	})
+	if len(dbErr) > 0 {
+		return dbErr
+	}
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return dbErr
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package persistence
+
+import (
+	"errors"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FoodImportRepo struct {
+	db *gorm.DB
+}
+
+func NewFoodImportRepository(db *gorm.DB) *FoodImportRepo {
+	return &FoodImportRepo{db}
+}
+
+// FoodImportRepo implements the repository.FoodImportRepository interface
+var _ repository.FoodImportRepository = &FoodImportRepo{}
+
+func (r *FoodImportRepo) SaveFoodImport(foodImport *entity.FoodImport) (*entity.FoodImport, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Create(foodImport).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return foodImport, nil
+}
+
+func (r *FoodImportRepo) GetFoodImport(id uint64) (*entity.FoodImport, error) {
+	var foodImport entity.FoodImport
+	err := r.db.Debug().Omit("payload").Where("id = ?", id).Take(&foodImport).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("import not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	if err := foodImport.DecodeErrors(); err != nil {
+		return nil, errors.New("the import errors are corrupted")
+	}
+	return &foodImport, nil
+}
+
+// ClaimFoodImport marks the oldest pending import as running and returns it, nil is returned when there is none.
+// The row is locked with SKIP LOCKED, so several instances of the app never run the same import.
+// The running imports that saved no progress for too long are failed first, their job is gone.
+func (r *FoodImportRepo) ClaimFoodImport() (*entity.FoodImport, error) {
+	var foodImport entity.FoodImport
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		if err := failStaleImports(tx); err != nil {
+			return err
+		}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", entity.ImportStatusPending).
+			Order("id asc").Take(&foodImport).Error
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		foodImport.Status = entity.ImportStatusRunning
+		foodImport.StartedAt = &now
+		foodImport.UpdatedAt = now
+		return tx.Model(&foodImport).Select("status", "started_at", "updated_at").Updates(&foodImport).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &foodImport, nil
+}
+
+// UpdateFoodImport saves the progress of an import
+func (r *FoodImportRepo) UpdateFoodImport(foodImport *entity.FoodImport) error {
+	foodImport.EncodeErrors()
+	foodImport.UpdatedAt = time.Now()
+	return r.db.Debug().Model(foodImport).
+		Select("status", "payload", "imported", "failed", "errors", "finished_at", "updated_at").
+		Updates(foodImport).Error
+}
+
+// failStaleImports fails the imports still running after RunningImportTimeout without any progress
+func failStaleImports(tx *gorm.DB) error {
+	var stale []entity.FoodImport
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).Omit("payload").
+		Where("status = ? AND updated_at < ?", entity.ImportStatusRunning, time.Now().Add(-entity.RunningImportTimeout)).
+		Find(&stale).Error
+	if err != nil {
+		return err
+	}
+	for i := range stale {
+		foodImport := &stale[i]
+		if err := foodImport.DecodeErrors(); err != nil {
+			foodImport.RowErrors = nil
+		}
+		foodImport.Interrupt()
+		foodImport.UpdatedAt = time.Now()
+		err := tx.Model(foodImport).Select("status", "payload", "errors", "finished_at", "updated_at").Updates(foodImport).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,10 +4,11 @@ import (
 	"errors"
 	"learning-golang-ddd/domain/entity"
 	"learning-golang-ddd/domain/repository"
-	"os"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type FoodRepo struct {
@@ -21,12 +22,23 @@ func NewFoodRepository(db *gorm.DB) *FoodRepo {
 //FoodRepo implements the repository.FoodRepository interface
 var _ repository.FoodRepository = &FoodRepo{}
 
+var errVersionMismatch = errors.New("version mismatch")
+
 func (r *FoodRepo) SaveFood(food *entity.Food) (*entity.Food, map[string]string) {
 	dbErr := map[string]string{}
-	//The images are uploaded to digital ocean spaces. So we need to prepend the url. This might not be your use case, if you are not uploading image to Digital Ocean.
-	food.FoodImage = os.Getenv("DO_SPACES_URL") + food.FoodImage
-
-	err := r.db.Debug().Create(&food).Error
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&food).Error; err != nil {
+			return err
+		}
+		if err := syncCoverImage(tx, food); err != nil {
+			return err
+		}
+		return saveRevision(tx, food)
+	})
+	if errors.Is(err, errRevisionConflict) {
+		dbErr["revision_conflict"] = "the food was saved at the same time, please try again"
+		return nil, dbErr
+	}
 	if err != nil {
 		//since our title is unique
 		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "Duplicate") {
@@ -42,7 +54,7 @@ func (r *FoodRepo) SaveFood(food *entity.Food) (*entity.Food, map[string]string)
 
 func (r *FoodRepo) GetFood(id uint64) (*entity.Food, error) {
 	var food entity.Food
-	err := r.db.Debug().Where("id = ?", id).Take(&food).Error
+	err := r.db.Debug().Preload("Ingredients").Preload("Images", orderByPosition).Preload("Clips", orderByPosition).Where("id = ?", id).Take(&food).Error
 	if err != nil {
 		return nil, errors.New("database error, please try again")
 	}
@@ -52,6 +64,17 @@ func (r *FoodRepo) GetFood(id uint64) (*entity.Food, error) {
 	return &food, nil
 }
 
+// FoodTitleExists reports whether the title is taken. The foods in the trash keep their title,
+// so they are counted as well.
+func (r *FoodRepo) FoodTitleExists(title string) (bool, error) {
+	var count int64
+	err := r.db.Debug().Unscoped().Model(&entity.Food{}).Where("title = ?", title).Count(&count).Error
+	if err != nil {
+		return false, errors.New("database error, please try again")
+	}
+	return count > 0, nil
+}
+
 func (r *FoodRepo) GetAllFood() ([]entity.Food, error) {
 	var foods []entity.Food
 	err := r.db.Debug().Limit(100).Order("created_at desc").Find(&foods).Error
@@ -64,9 +87,116 @@ func (r *FoodRepo) GetAllFood() ([]entity.Food, error) {
 	return foods, nil
 }
 
+func (r *FoodRepo) GetAllFoodByFilter(filter *entity.FoodFilter) ([]entity.Food, error) {
+	var foods []entity.Food
+	query := r.db.Debug().Preload("Ingredients").Preload("Images", orderByPosition).Preload("Clips", orderByPosition)
+	if filter.ViewerID != 0 {
+		query = query.Where("((status = ? AND hidden = ?) OR user_id = ?)", entity.FoodStatusPublished, false, filter.ViewerID)
+	} else {
+		query = query.Where("status = ? AND hidden = ?", entity.FoodStatusPublished, false)
+	}
+	if filter.MinCalories != nil {
+		query = query.Where("calories >= ?", *filter.MinCalories)
+	}
+	if filter.MaxCalories != nil {
+		query = query.Where("calories <= ?", *filter.MaxCalories)
+	}
+	for _, allergen := range filter.ExcludeAllergens {
+		// allergens are stored comma separated, so we wrap both sides in commas to match whole names only
+		query = query.Where(`',' || COALESCE(allergens, '') || ',' NOT LIKE ? ESCAPE '\'`, "%,"+escapeLike(allergen)+",%")
+	}
+	switch filter.SortBy {
+	case "rating":
+		query = query.Order("rating_average desc").Order("rating_count desc")
+	}
+	err := query.Limit(100).Order("created_at desc").Find(&foods).Error
+	if err != nil {
+		return nil, err
+	}
+	return foods, nil
+}
+
+// bumpFoodVersion gives the food a new version, and so a new ETag, when what it shows changed
+// without the food itself being updated, e.g its rating or its gallery
+func bumpFoodVersion(tx *gorm.DB, foodId uint64) error {
+	return tx.Model(&entity.Food{}).Where("id = ?", foodId).UpdateColumns(map[string]interface{}{
+		"version":    gorm.Expr("version + 1"),
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, so the value only matches itself
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// GetFoodsByUser returns all the foods of the user whatever their status, the ones in the trash excepted
+func (r *FoodRepo) GetFoodsByUser(userId uint64) ([]entity.Food, error) {
+	var foods []entity.Food
+	err := r.db.Debug().Preload("Ingredients").Preload("Images", orderByPosition).Preload("Clips", orderByPosition).
+		Where("user_id = ?", userId).Order("created_at asc").Find(&foods).Error
+	if err != nil {
+		return nil, err
+	}
+	return foods, nil
+}
+
+// GetFoodsChangedSince returns the foods updated, moved to the trash or restored since the given time,
+// the ones in the trash included, with their images and clips
+func (r *FoodRepo) GetFoodsChangedSince(since time.Time) ([]entity.Food, error) {
+	var foods []entity.Food
+	err := r.db.Debug().Unscoped().Preload("Images").Preload("Clips").
+		Where("updated_at >= ?", since).Find(&foods).Error
+	if err != nil {
+		return nil, err
+	}
+	return foods, nil
+}
+
 func (r *FoodRepo) UpdateFood(food *entity.Food) (*entity.Food, map[string]string) {
 	dbErr := map[string]string{}
-	err := r.db.Debug().Save(&food).Error
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		// the rating summary is maintained by the rating repository, saving a stale copy of it would undo other users' ratings.
+		// The status has its own lifecycle, see UpdateFoodStatus, and only the moderators hide foods.
+		omit := []string{clause.Associations, "rating_average", "rating_count", "rating_total", "status", "publish_at", "published_at", "hidden"}
+		// the update only goes through when nobody else updated the food since it was read
+		expectedVersion := food.Version
+		food.Version = expectedVersion + 1
+		result := tx.Model(food).Where("version = ?", expectedVersion).Select("*").Omit(omit...).Updates(food)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			food.Version = expectedVersion
+			return errVersionMismatch
+		}
+		// the ingredients are replaced as a whole, so the removed ones dont linger around
+		if err := tx.Where("food_id = ?", food.ID).Delete(&entity.Ingredient{}).Error; err != nil {
+			return err
+		}
+		if len(food.Ingredients) > 0 {
+			for i := range food.Ingredients {
+				food.Ingredients[i].ID = 0
+				food.Ingredients[i].FoodID = food.ID
+			}
+			if err := tx.Create(&food.Ingredients).Error; err != nil {
+				return err
+			}
+		}
+		if err := syncCoverImage(tx, food); err != nil {
+			return err
+		}
+		// the previous versions are kept as revisions, so the update is never lost
+		return saveRevision(tx, food)
+	})
+	if errors.Is(err, errVersionMismatch) {
+		dbErr["version_mismatch"] = "the food was changed by someone else"
+		return nil, dbErr
+	}
+	if errors.Is(err, errRevisionConflict) {
+		dbErr["revision_conflict"] = "the food was changed at the same time, please try again"
+		return nil, dbErr
+	}
 	if err != nil {
 		//since our title is unique
 		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "Duplicate") {
@@ -80,11 +210,167 @@ func (r *FoodRepo) UpdateFood(food *entity.Food) (*entity.Food, map[string]strin
 	return food, nil
 }
 
+func (r *FoodRepo) UpdateFoodStatus(food *entity.Food) (*entity.Food, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Model(food).Select("status", "publish_at", "published_at", "version").Updates(map[string]interface{}{
+		"status":       food.Status,
+		"publish_at":   food.PublishAt,
+		"published_at": food.PublishedAt,
+		"version":      gorm.Expr("version + 1"),
+	}).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	food.Version++
+	return food, nil
+}
+
+// PublishScheduledFoods publishes the scheduled foods whose publish_at has passed, and returns them.
+// The rows are locked with SKIP LOCKED, so several instances of the app never publish the same food twice.
+func (r *FoodRepo) PublishScheduledFoods(now time.Time) ([]entity.Food, error) {
+	var foods []entity.Food
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND publish_at <= ?", entity.FoodStatusScheduled, now).
+			Limit(100).Find(&foods).Error
+		if err != nil || len(foods) == 0 {
+			return err
+		}
+		for i := range foods {
+			foods[i].Status = entity.FoodStatusPublished
+			foods[i].PublishedAt = foods[i].PublishAt
+			foods[i].PublishAt = nil
+			err := tx.Model(&foods[i]).Select("status", "publish_at", "published_at", "version").Updates(map[string]interface{}{
+				"status":       foods[i].Status,
+				"publish_at":   foods[i].PublishAt,
+				"published_at": foods[i].PublishedAt,
+				"version":      gorm.Expr("version + 1"),
+			}).Error
+			if err != nil {
+				return err
+			}
+			foods[i].Version++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return foods, nil
+}
+
+// DeleteFood moves the food to the trash, it can be restored until it is purged.
+// It is removed from every collection and favorites list it was added to right away.
 func (r *FoodRepo) DeleteFood(id uint64) error {
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		return softDeleteFoods(tx, time.Now(), "id = ?", id)
+	})
+	if err != nil {
+		return errors.New("database error, please try again")
+	}
+	return nil
+}
+
+func (r *FoodRepo) GetDeletedFood(id uint64) (*entity.Food, error) {
 	var food entity.Food
-	err := r.db.Debug().Where("id = ?", id).Delete(&food).Error
+	err := r.db.Debug().Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).Take(&food).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("food not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &food, nil
+}
+
+func (r *FoodRepo) GetDeletedFoodsByUser(userId uint64) ([]entity.Food, error) {
+	var foods []entity.Food
+	err := r.db.Debug().Unscoped().Preload("Images", orderByPosition).Preload("Clips", orderByPosition).
+		Where("user_id = ? AND deleted_at IS NOT NULL", userId).
+		Order("deleted_at desc").Find(&foods).Error
+	if err != nil {
+		return nil, err
+	}
+	return foods, nil
+}
+
+func (r *FoodRepo) RestoreFood(id uint64) error {
+	err := r.db.Debug().Unscoped().Model(&entity.Food{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"deleted_at": nil, "updated_at": time.Now()}).Error
+	if err != nil && (strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "Duplicate")) {
+		// the title is only unique among the foods that are not in the trash
+		return entity.ErrTitleTaken
+	}
 	if err != nil {
 		return errors.New("database error, please try again")
 	}
 	return nil
 }
+
+// PurgeDeletedFoods permanently deletes the foods that were moved to the trash before the given time,
+// with everything that belongs to them. The purged foods are returned with their images and clips,
+// so the stored files can be deleted too.
+func (r *FoodRepo) PurgeDeletedFoods(before time.Time) ([]entity.Food, error) {
+	var foods []entity.Food
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Preload("Images").Preload("Clips").
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Limit(100).Find(&foods).Error
+		if err != nil || len(foods) == 0 {
+			return err
+		}
+		ids := make([]uint64, len(foods))
+		for i, food := range foods {
+			ids[i] = food.ID
+		}
+		// the reports of the foods and of their comments go first, the comments are deleted below
+		commentIds := tx.Model(&entity.Comment{}).Select("id").Where("food_id IN ?", ids)
+		err = tx.Where("(target_type = ? AND target_id IN ?) OR (target_type = ? AND target_id IN (?))",
+			entity.ReportTargetFood, ids, entity.ReportTargetComment, commentIds).Delete(&entity.Report{}).Error
+		if err != nil {
+			return err
+		}
+		dependents := []interface{}{
+			&entity.Ingredient{},
+			&entity.CollectionItem{},
+			&entity.Favorite{},
+			&entity.Rating{},
+			&entity.Review{},
+			&entity.Comment{},
+			&entity.FoodRevision{},
+			&entity.GalleryImage{},
+			&entity.FoodClip{},
+			&entity.MealPlanEntry{},
+		}
+		for _, dependent := range dependents {
+			if err := tx.Where("food_id IN ?", ids).Delete(dependent).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&entity.Food{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return foods, nil
+}
+
+// softDeleteFoods moves the matching foods to the trash at the given time
+func softDeleteFoods(tx *gorm.DB, deletedAt time.Time, query interface{}, args ...interface{}) error {
+	var ids []uint64
+	if err := tx.Model(&entity.Food{}).Where(query, args...).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	// a food in the trash should not show up in anyone's lists
+	for _, dependent := range []interface{}{&entity.CollectionItem{}, &entity.Favorite{}, &entity.MealPlanEntry{}} {
+		if err := tx.Where("food_id IN ?", ids).Delete(dependent).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&entity.Food{}).Where("id IN ?", ids).
+		UpdateColumns(map[string]interface{}{"deleted_at": deletedAt, "updated_at": deletedAt}).Error
+}
@@ -0,0 +1,73 @@
+package persistence
+
+import (
+	"errors"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FoodRevisionRepo struct {
+	db *gorm.DB
+}
+
+func NewFoodRevisionRepository(db *gorm.DB) *FoodRevisionRepo {
+	return &FoodRevisionRepo{db}
+}
+
+// FoodRevisionRepo implements the repository.FoodRevisionRepository interface
+var _ repository.FoodRevisionRepository = &FoodRevisionRepo{}
+
+func (r *FoodRevisionRepo) GetRevisions(foodId uint64) ([]entity.FoodRevision, error) {
+	var revisions []entity.FoodRevision
+	err := r.db.Debug().Where("food_id = ?", foodId).Order("revision desc").Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (r *FoodRevisionRepo) GetRevision(foodId uint64, revision int) (*entity.FoodRevision, error) {
+	var foodRevision entity.FoodRevision
+	err := r.db.Debug().Where("food_id = ? AND revision = ?", foodId, revision).Take(&foodRevision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("revision not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &foodRevision, nil
+}
+
+// errRevisionConflict is returned when another revision of the food was saved at the same time
+var errRevisionConflict = errors.New("revision conflict")
+
+// saveRevision snapshots the food as its next revision. It should run in the transaction that saved the food.
+// The food row is locked first, so two saves cannot both read the same last revision.
+func saveRevision(tx *gorm.DB, food *entity.Food) error {
+	err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").Where("id = ?", food.ID).Take(&entity.Food{}).Error
+	if err != nil {
+		return err
+	}
+	revision := entity.NewFoodRevision(food)
+	var last struct{ Revision *int }
+	err = tx.Model(&entity.FoodRevision{}).Select("MAX(revision) AS revision").
+		Where("food_id = ?", food.ID).Scan(&last).Error
+	if err != nil {
+		return err
+	}
+	revision.Revision = 1
+	if last.Revision != nil {
+		revision.Revision = *last.Revision + 1
+	}
+	err = tx.Create(revision).Error
+	if err != nil && strings.Contains(err.Error(), "idx_food_revision") {
+		return errRevisionConflict
+	}
+	return err
+}
@@ -0,0 +1,337 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GalleryRepo struct {
+	db *gorm.DB
+}
+
+func NewGalleryRepository(db *gorm.DB) *GalleryRepo {
+	return &GalleryRepo{db}
+}
+
+// GalleryRepo implements the repository.GalleryRepository interface
+var _ repository.GalleryRepository = &GalleryRepo{}
+
+// AddImage appends the image at the end of the gallery. The first image of a food becomes its cover.
+func (r *GalleryRepo) AddImage(image *entity.GalleryImage) (*entity.GalleryImage, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		if err := appendImage(tx, image); err != nil {
+			return err
+		}
+		if image.IsCover {
+			if err := setCover(tx, image); err != nil {
+				return err
+			}
+		}
+		return bumpFoodVersion(tx, image.FoodID)
+	})
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return image, nil
+}
+
+func (r *GalleryRepo) GetImage(id uint64) (*entity.GalleryImage, error) {
+	var image entity.GalleryImage
+	err := r.db.Debug().Where("id = ?", id).Take(&image).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("image not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &image, nil
+}
+
+func (r *GalleryRepo) GetImagesByFood(foodId uint64) ([]entity.GalleryImage, error) {
+	var images []entity.GalleryImage
+	err := r.db.Debug().Where("food_id = ?", foodId).Order("position asc").Find(&images).Error
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// UserHasImage reports whether the image was uploaded to one of the user's foods
+func (r *GalleryRepo) UserHasImage(userId uint64, path string) (bool, error) {
+	var count int64
+	err := r.db.Debug().Model(&entity.GalleryImage{}).
+		Joins("JOIN foods ON foods.id = gallery_images.food_id").
+		Where("foods.user_id = ? AND foods.deleted_at IS NULL AND gallery_images.path = ?", userId, path).
+		Count(&count).Error
+	if err != nil {
+		return false, errors.New("database error, please try again")
+	}
+	return count > 0, nil
+}
+
+func (r *GalleryRepo) UpdateImage(image *entity.GalleryImage) (*entity.GalleryImage, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(image).UpdateColumn("alt_text", image.AltText).Error; err != nil {
+			return err
+		}
+		return bumpFoodVersion(tx, image.FoodID)
+	})
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return image, nil
+}
+
+func (r *GalleryRepo) SetCoverImage(foodId uint64, imageId uint64) map[string]string {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		var image entity.GalleryImage
+		if err := tx.Where("id = ? AND food_id = ?", imageId, foodId).Take(&image).Error; err != nil {
+			return err
+		}
+		if err := setCover(tx, &image); err != nil {
+			return err
+		}
+		return bumpFoodVersion(tx, foodId)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		dbErr["image_not_found"] = "image not found"
+		return dbErr
+	}
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return dbErr
+	}
+	return nil
+}
+
+// DeleteImage removes the image from the gallery. When it was the cover, the next image takes its place.
+func (r *GalleryRepo) DeleteImage(id uint64) error {
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		var image entity.GalleryImage
+		if err := tx.Where("id = ?", id).Take(&image).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&image).Error; err != nil {
+			return err
+		}
+		if err := bumpFoodVersion(tx, image.FoodID); err != nil {
+			return err
+		}
+		if !image.IsCover {
+			return nil
+		}
+		var next entity.GalleryImage
+		err := tx.Where("food_id = ?", image.FoodID).Order("position asc").Take(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// that was the last image
+			return setFoodImage(tx, image.FoodID, "")
+		}
+		if err != nil {
+			return err
+		}
+		return setCover(tx, &next)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("image not found")
+	}
+	if err != nil {
+		return errors.New("database error, please try again")
+	}
+	return nil
+}
+
+// ReorderImages sets the position of every image from the given order.
+// All the images of the food should be given, each once.
+func (r *GalleryRepo) ReorderImages(foodId uint64, imageIds []uint64) map[string]string {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		var images []entity.GalleryImage
+		if err := tx.Where("food_id = ?", foodId).Find(&images).Error; err != nil {
+			return err
+		}
+		positions := make(map[uint64]int, len(imageIds))
+		for i, imageId := range imageIds {
+			positions[imageId] = i
+		}
+		if len(positions) != len(images) || len(imageIds) != len(images) {
+			dbErr["invalid_order"] = "the order should contain every image of the food exactly once"
+			return errors.New("invalid order")
+		}
+		for _, image := range images {
+			position, ok := positions[image.ID]
+			if !ok {
+				dbErr["invalid_order"] = "the order should contain every image of the food exactly once"
+				return errors.New("invalid order")
+			}
+			err := tx.Model(&entity.GalleryImage{}).Where("id = ?", image.ID).UpdateColumn("position", position).Error
+			if err != nil {
+				return err
+			}
+		}
+		return bumpFoodVersion(tx, foodId)
+	})
+	if len(dbErr) > 0 {
+		return dbErr
+	}
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return dbErr
+	}
+	return nil
+}
+
+// CountImageRefs counts what still uses the image, or the video: the foods, trashed ones included, the galleries,
+// the clips and their posters, the revisions a food can be restored to, the confirmed uploads not attached yet,
+// and the holds not expired yet, see HoldImage. The images are stored by their content, so several foods can use the same one.
+func (r *GalleryRepo) CountImageRefs(path string) (int64, error) {
+	return r.countImageRefs("%s = ?", path)
+}
+
+// CountImageRefsByKey counts the uses of the stored file like CountImageRefs, whatever public url it was saved with,
+// e.g before the storage moved. It is slower, the whole tables are read.
+func (r *GalleryRepo) CountImageRefsByKey(key string) (int64, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(key)
+	return r.countImageRefs("(%[1]s = ? OR %[1]s LIKE ?)", key, "%/"+escaped)
+}
+
+// countImageRefs counts the rows of each table using an image, the condition is formatted with the column of the image
+func (r *GalleryRepo) countImageRefs(condition string, args ...interface{}) (int64, error) {
+	var total int64
+	refs := []*gorm.DB{
+		r.db.Debug().Unscoped().Model(&entity.Food{}).Where(fmt.Sprintf(condition, "food_image"), args...),
+		r.db.Debug().Model(&entity.GalleryImage{}).Where(fmt.Sprintf(condition, "path"), args...),
+		r.db.Debug().Model(&entity.FoodRevision{}).Where(fmt.Sprintf(condition, "food_image"), args...),
+		r.db.Debug().Model(&entity.FoodClip{}).Where(fmt.Sprintf(condition, "path"), args...),
+		r.db.Debug().Model(&entity.FoodClip{}).Where(fmt.Sprintf(condition, "poster_path"), args...),
+		r.db.Debug().Model(&entity.Upload{}).Where(fmt.Sprintf(condition, "image_path"), args...).
+			Where("status = ?", entity.UploadStatusConfirmed),
+		r.db.Debug().Model(&entity.Upload{}).Where(fmt.Sprintf(condition, "video_path"), args...).
+			Where("status = ?", entity.UploadStatusConfirmed),
+		r.db.Debug().Model(&entity.ImageHold{}).Where(fmt.Sprintf(condition, "path"), args...).
+			Where("expires_at > ?", time.Now()),
+	}
+	for _, ref := range refs {
+		var count int64
+		if err := ref.Count(&count).Error; err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// LockImage runs fn while the stored image, or video, is locked for every instance of the app.
+// The lock is a postgres advisory lock, released with the transaction it is taken in.
+func (r *GalleryRepo) LockImage(key string, fn func() error) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error; err != nil {
+			return err
+		}
+		return fn()
+	})
+}
+
+// HoldImage counts the image as used until the given time, see CountImageRefs. The expired holds are removed.
+func (r *GalleryRepo) HoldImage(path string, until time.Time) error {
+	if err := r.db.Debug().Where("expires_at <= ?", time.Now()).Delete(&entity.ImageHold{}).Error; err != nil {
+		return err
+	}
+	return r.db.Debug().Create(&entity.ImageHold{Path: path, ExpiresAt: until}).Error
+}
+
+// IsImageVisibleTo tells whether the user can see the image, or the video: a published food uses it, or with a user,
+// one of their foods, trashed ones included, revisions or uploads does. With no user, whether the image can be public.
+func (r *GalleryRepo) IsImageVisibleTo(path string, userId uint64) (bool, error) {
+	usedBy := func(db *gorm.DB) *gorm.DB {
+		galleries := r.db.Model(&entity.GalleryImage{}).Select("food_id").Where("path = ?", path)
+		clips := r.db.Model(&entity.FoodClip{}).Select("food_id").Where("path = ? OR poster_path = ?", path, path)
+		return db.Model(&entity.Food{}).Where("(food_image = ? OR id IN (?) OR id IN (?))", path, galleries, clips)
+	}
+	checks := []*gorm.DB{
+		usedBy(r.db.Debug()).Where("status = ? AND hidden = ?", entity.FoodStatusPublished, false),
+	}
+	if userId != 0 {
+		ownFoods := r.db.Unscoped().Model(&entity.Food{}).Select("id").Where("user_id = ?", userId)
+		checks = append(checks,
+			usedBy(r.db.Debug().Unscoped()).Where("user_id = ?", userId),
+			r.db.Debug().Model(&entity.FoodRevision{}).Where("food_image = ? AND food_id IN (?)", path, ownFoods),
+			r.db.Debug().Model(&entity.Upload{}).Where("(image_path = ? OR video_path = ?) AND user_id = ?", path, path, userId),
+		)
+	}
+	for _, check := range checks {
+		var count int64
+		if err := check.Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func appendImage(tx *gorm.DB, image *entity.GalleryImage) error {
+	var last struct{ Position *int }
+	err := tx.Model(&entity.GalleryImage{}).Select("MAX(position) AS position").
+		Where("food_id = ?", image.FoodID).Scan(&last).Error
+	if err != nil {
+		return err
+	}
+	image.Position = 0
+	if last.Position != nil {
+		image.Position = *last.Position + 1
+	} else {
+		image.IsCover = true
+	}
+	return tx.Create(image).Error
+}
+
+// setCover makes the image the only cover of its food, and copies its path to the food
+func setCover(tx *gorm.DB, image *entity.GalleryImage) error {
+	err := tx.Model(&entity.GalleryImage{}).Where("food_id = ? AND id <> ?", image.FoodID, image.ID).UpdateColumn("is_cover", false).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(image).UpdateColumn("is_cover", true).Error; err != nil {
+		return err
+	}
+	return setFoodImage(tx, image.FoodID, image.Path)
+}
+
+// setFoodImage changes the image of the food. The food gets a new version only when the image actually changed.
+func setFoodImage(tx *gorm.DB, foodId uint64, path string) error {
+	return tx.Model(&entity.Food{}).Where("id = ? AND food_image IS DISTINCT FROM ?", foodId, path).UpdateColumns(map[string]interface{}{
+		"food_image": path,
+		"version":    gorm.Expr("version + 1"),
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// syncCoverImage makes sure the food's image is the cover of its gallery. When the image is new,
+// e.g it was uploaded through the food_image field of SaveFood or UpdateFood, it is added to the gallery.
+func syncCoverImage(tx *gorm.DB, food *entity.Food) error {
+	if food.FoodImage == "" {
+		return nil
+	}
+	var image entity.GalleryImage
+	err := tx.Where("food_id = ? AND path = ?", food.ID, food.FoodImage).Take(&image).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		image = entity.GalleryImage{FoodID: food.ID, Path: food.FoodImage, CreatedAt: time.Now()}
+		err = appendImage(tx, &image)
+	}
+	if err != nil {
+		return err
+	}
+	return setCover(tx, &image)
+}
@@ -0,0 +1,145 @@
+package persistence
+
+import (
+	"errors"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MealPlanRepo struct {
+	db *gorm.DB
+}
+
+func NewMealPlanRepository(db *gorm.DB) *MealPlanRepo {
+	return &MealPlanRepo{db}
+}
+
+// MealPlanRepo implements the repository.MealPlanRepository interface
+var _ repository.MealPlanRepository = &MealPlanRepo{}
+
+func (r *MealPlanRepo) SaveMealPlanEntry(entry *entity.MealPlanEntry) (*entity.MealPlanEntry, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Omit(clause.Associations).Create(entry).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return entry, nil
+}
+
+func (r *MealPlanRepo) GetMealPlanEntry(id uint64) (*entity.MealPlanEntry, error) {
+	var entry entity.MealPlanEntry
+	err := r.db.Debug().Preload("Food").Where("id = ?", id).Take(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("meal plan entry not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &entry, nil
+}
+
+// GetMealPlanEntries returns the entries of the user between the two days, both included
+func (r *MealPlanRepo) GetMealPlanEntries(userId uint64, from time.Time, to time.Time) ([]entity.MealPlanEntry, error) {
+	var entries []entity.MealPlanEntry
+	err := r.db.Debug().Preload("Food").
+		Where("user_id = ? AND date >= ? AND date <= ?", userId, from, to).
+		Order("date asc").Order("id asc").Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *MealPlanRepo) UpdateMealPlanEntry(entry *entity.MealPlanEntry) (*entity.MealPlanEntry, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Model(entry).
+		Select("date", "slot", "food_id", "servings", "note", "updated_at").
+		Updates(entry).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return entry, nil
+}
+
+func (r *MealPlanRepo) DeleteMealPlanEntry(id uint64) error {
+	err := r.db.Debug().Where("id = ?", id).Delete(&entity.MealPlanEntry{}).Error
+	if err != nil {
+		return errors.New("database error, please try again")
+	}
+	return nil
+}
+
+// CopyMealPlanWeek copies the entries of the week starting on from to the week starting on to,
+// each entry keeps its weekday, slot and food. When replace is set, the entries of the target week are removed first.
+func (r *MealPlanRepo) CopyMealPlanWeek(userId uint64, from time.Time, to time.Time, replace bool) ([]entity.MealPlanEntry, error) {
+	var copies []entity.MealPlanEntry
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		if replace {
+			err := tx.Where("user_id = ? AND date >= ? AND date < ?", userId, to, to.AddDate(0, 0, 7)).
+				Delete(&entity.MealPlanEntry{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		var entries []entity.MealPlanEntry
+		err := tx.Preload("Food").Where("user_id = ? AND date >= ? AND date < ?", userId, from, from.AddDate(0, 0, 7)).
+			Order("date asc").Order("id asc").Find(&entries).Error
+		if err != nil || len(entries) == 0 {
+			return err
+		}
+		days := int(to.Sub(from).Hours() / 24)
+		now := time.Now()
+		for _, entry := range entries {
+			copies = append(copies, entity.MealPlanEntry{
+				UserID:    userId,
+				Date:      entry.Date.AddDate(0, 0, days),
+				Slot:      entry.Slot,
+				FoodID:    entry.FoodID,
+				Food:      entry.Food,
+				Servings:  entry.Servings,
+				Note:      entry.Note,
+				CreatedAt: now,
+				UpdatedAt: now,
+			})
+		}
+		// the copies are returned with their food, like GetMealPlanEntries, the foods are not saved again
+		return tx.Omit("Food").Create(&copies).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copies, nil
+}
+
+// SaveCalendarFeed creates the feed of the user, or gives it a new token when it exists,
+// so an old feed url that leaked stops working.
+func (r *MealPlanRepo) SaveCalendarFeed(feed *entity.CalendarFeed) (*entity.CalendarFeed, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token", "created_at"}),
+	}).Create(feed).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return feed, nil
+}
+
+func (r *MealPlanRepo) GetCalendarFeed(token string) (*entity.CalendarFeed, error) {
+	var feed entity.CalendarFeed
+	err := r.db.Debug().Where("token = ?", token).Take(&feed).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("calendar feed not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &feed, nil
+}
@@ -0,0 +1,56 @@
+package persistence
+
+import (
+	"errors"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepo struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepo {
+	return &NotificationRepo{db}
+}
+
+// NotificationRepo implements the repository.NotificationRepository interface
+var _ repository.NotificationRepository = &NotificationRepo{}
+
+func (r *NotificationRepo) GetNotification(id uint64) (*entity.Notification, error) {
+	var notification entity.Notification
+	err := r.db.Debug().Where("id = ?", id).Take(&notification).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("notification not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &notification, nil
+}
+
+// GetNotifications returns a page of the user's notifications, the newest first
+func (r *NotificationRepo) GetNotifications(userId uint64, page *entity.Pagination) ([]entity.Notification, error) {
+	var notifications []entity.Notification
+	err := r.db.Debug().Model(&entity.Notification{}).Where("user_id = ?", userId).Count(&page.Total).Error
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.Debug().Where("user_id = ?", userId).Order("created_at desc").Limit(page.PerPage).Offset(page.Offset()).Find(&notifications).Error
+	if err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (r *NotificationRepo) MarkNotificationRead(notification *entity.Notification) map[string]string {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Model(notification).UpdateColumn("read_at", notification.ReadAt).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return dbErr
+	}
+	return nil
+}
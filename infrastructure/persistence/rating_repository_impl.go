@@ -0,0 +1,116 @@
+package persistence
+
+import (
+	"errors"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RatingRepo struct {
+	db *gorm.DB
+}
+
+func NewRatingRepository(db *gorm.DB) *RatingRepo {
+	return &RatingRepo{db}
+}
+
+// RatingRepo implements the repository.RatingRepository interface
+var _ repository.RatingRepository = &RatingRepo{}
+
+// SaveRating creates the user's rating of the food, or replaces it when the user already rated it.
+// The food's rating summary is adjusted in the same transaction.
+func (r *RatingRepo) SaveRating(rating *entity.Rating) (*entity.Rating, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		var existing entity.Rating
+		findExisting := func() error {
+			return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("food_id = ? AND user_id = ?", rating.FoodID, rating.UserID).
+				Take(&existing).Error
+		}
+		err := findExisting()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(rating)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 1 {
+				return adjustRatingSummary(tx, rating.FoodID, 1, int64(rating.Stars))
+			}
+			// another request of the user created the rating in the meantime, it is replaced below
+			err = findExisting()
+		}
+		if err != nil {
+			return err
+		}
+
+		oldStars := existing.Stars
+		existing.Stars = rating.Stars
+		existing.UpdatedAt = time.Now()
+		if err := tx.Save(&existing).Error; err != nil {
+			return err
+		}
+		*rating = existing
+		return adjustRatingSummary(tx, rating.FoodID, 0, int64(rating.Stars-oldStars))
+	})
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return rating, nil
+}
+
+func (r *RatingRepo) GetRating(foodId uint64, userId uint64) (*entity.Rating, error) {
+	var rating entity.Rating
+	err := r.db.Debug().Where("food_id = ? AND user_id = ?", foodId, userId).Take(&rating).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("rating not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &rating, nil
+}
+
+func (r *RatingRepo) DeleteRating(foodId uint64, userId uint64) error {
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		var rating entity.Rating
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("food_id = ? AND user_id = ?", foodId, userId).
+			Take(&rating).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&rating).Error; err != nil {
+			return err
+		}
+		return adjustRatingSummary(tx, foodId, -1, -int64(rating.Stars))
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("rating not found")
+	}
+	if err != nil {
+		return errors.New("database error, please try again")
+	}
+	return nil
+}
+
+// adjustRatingSummary applies the change in the number of ratings and in the total stars
+// to the food, instead of recomputing the average from every rating.
+func adjustRatingSummary(tx *gorm.DB, foodId uint64, countDelta int64, totalDelta int64) error {
+	// UpdateColumns, so the food's updated_at is not touched by someone else's rating.
+	// It still gets a new version, its ETag covers the rating it shows.
+	return tx.Model(&entity.Food{}).Where("id = ?", foodId).UpdateColumns(map[string]interface{}{
+		"version":      gorm.Expr("version + 1"),
+		"rating_count": gorm.Expr("rating_count + ?", countDelta),
+		"rating_total": gorm.Expr("rating_total + ?", totalDelta),
+		"rating_average": gorm.Expr(
+			"CASE WHEN rating_count + ? <= 0 THEN 0 ELSE (rating_total + ?)::float / (rating_count + ?) END",
+			countDelta, totalDelta, countDelta,
+		),
+	}).Error
+}
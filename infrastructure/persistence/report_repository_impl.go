@@ -0,0 +1,146 @@
+package persistence
+
+import (
+	"errors"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReportRepo struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) *ReportRepo {
+	return &ReportRepo{db}
+}
+
+// ReportRepo implements the repository.ReportRepository interface
+var _ repository.ReportRepository = &ReportRepo{}
+
+var errReportResolved = errors.New("report already resolved")
+
+func (r *ReportRepo) SaveReport(report *entity.Report) (*entity.Report, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Create(report).Error
+	if err != nil {
+		// a user reports the same content once
+		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "Duplicate") {
+			dbErr["already_reported"] = "you already reported this " + string(report.TargetType)
+			return nil, dbErr
+		}
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return report, nil
+}
+
+func (r *ReportRepo) GetReport(id uint64) (*entity.Report, error) {
+	var report entity.Report
+	err := r.db.Debug().Where("id = ?", id).Take(&report).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("report not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &report, nil
+}
+
+// GetReports returns a page of the reports with the given status, the oldest first so the queue is worked in order
+func (r *ReportRepo) GetReports(status entity.ReportStatus, page *entity.Pagination) ([]entity.Report, error) {
+	var reports []entity.Report
+	err := r.db.Debug().Model(&entity.Report{}).Where("status = ?", status).Count(&page.Total).Error
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.Debug().Where("status = ?", status).Order("created_at asc").Limit(page.PerPage).Offset(page.Offset()).Find(&reports).Error
+	if err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (r *ReportRepo) HasOpenReport(target entity.ReportTarget, targetId uint64, reason entity.ReportReason) (bool, error) {
+	var count int64
+	err := r.db.Debug().Model(&entity.Report{}).
+		Where("target_type = ? AND target_id = ? AND reason = ? AND status = ?", target, targetId, reason, entity.ReportStatusOpen).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ModerateReport applies the moderator's decision to the reported content and resolves all the open reports of it.
+// When the content gets hidden, the author is notified in the same transaction.
+func (r *ReportRepo) ModerateReport(report *entity.Report, action entity.ModerationAction, moderatorId uint64) ([]entity.Report, map[string]string) {
+	dbErr := map[string]string{}
+	var resolved []entity.Report
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("target_type = ? AND target_id = ? AND status = ?", report.TargetType, report.TargetID, entity.ReportStatusOpen).
+			Order("id asc").Find(&resolved).Error
+		if err != nil {
+			return err
+		}
+		if len(resolved) == 0 {
+			return errReportResolved
+		}
+
+		hidden := action != entity.ModerationApprove
+		switch report.TargetType {
+		case entity.ReportTargetFood:
+			err = tx.Model(&entity.Food{}).Where("id = ?", report.TargetID).
+				UpdateColumns(map[string]interface{}{"hidden": hidden, "version": gorm.Expr("version + 1"), "updated_at": time.Now()}).Error
+		case entity.ReportTargetComment:
+			var hiddenBy *uint64
+			if hidden {
+				hiddenBy = &moderatorId
+			}
+			err = tx.Model(&entity.Comment{}).Where("id = ?", report.TargetID).
+				UpdateColumns(map[string]interface{}{"hidden": hidden, "hidden_by": hiddenBy}).Error
+		}
+		if err != nil {
+			return err
+		}
+		if action == entity.ModerationBan {
+			err := tx.Model(&entity.User{}).Where("id = ? AND banned_at IS NULL", report.AuthorID).UpdateColumn("banned_at", time.Now()).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		ids := make([]uint64, len(resolved))
+		for i := range resolved {
+			resolved[i].Resolve(action, moderatorId)
+			ids[i] = resolved[i].ID
+		}
+		err = tx.Model(&entity.Report{}).Where("id IN ?", ids).UpdateColumns(map[string]interface{}{
+			"status":      entity.ReportStatusResolved,
+			"action":      action,
+			"resolved_by": moderatorId,
+			"resolved_at": resolved[0].ResolvedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if hidden {
+			return tx.Create(entity.NewModerationNotification(&resolved[0])).Error
+		}
+		return nil
+	})
+	if errors.Is(err, errReportResolved) {
+		dbErr["report_resolved"] = "the report was already resolved"
+		return nil, dbErr
+	}
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return resolved, nil
+}
@@ -0,0 +1,70 @@
+package persistence
+
+import (
+	"errors"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type ReviewRepo struct {
+	db *gorm.DB
+}
+
+func NewReviewRepository(db *gorm.DB) *ReviewRepo {
+	return &ReviewRepo{db}
+}
+
+// ReviewRepo implements the repository.ReviewRepository interface
+var _ repository.ReviewRepository = &ReviewRepo{}
+
+func (r *ReviewRepo) SaveReview(review *entity.Review) (*entity.Review, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Create(&review).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return review, nil
+}
+
+func (r *ReviewRepo) GetReview(id uint64) (*entity.Review, error) {
+	var review entity.Review
+	err := r.db.Debug().Where("id = ?", id).Take(&review).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("review not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &review, nil
+}
+
+func (r *ReviewRepo) GetReviewsByFood(foodId uint64) ([]entity.Review, error) {
+	var reviews []entity.Review
+	err := r.db.Debug().Where("food_id = ?", foodId).Limit(100).Order("created_at desc").Find(&reviews).Error
+	if err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+func (r *ReviewRepo) UpdateReview(review *entity.Review) (*entity.Review, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Save(&review).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return review, nil
+}
+
+func (r *ReviewRepo) DeleteReview(id uint64) error {
+	var review entity.Review
+	err := r.db.Debug().Where("id = ?", id).Delete(&review).Error
+	if err != nil {
+		return errors.New("database error, please try again")
+	}
+	return nil
+}
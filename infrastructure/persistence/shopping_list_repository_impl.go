@@ -0,0 +1,115 @@
+package persistence
+
+import (
+	"errors"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type ShoppingListRepo struct {
+	db *gorm.DB
+}
+
+func NewShoppingListRepository(db *gorm.DB) *ShoppingListRepo {
+	return &ShoppingListRepo{db}
+}
+
+// ShoppingListRepo implements the repository.ShoppingListRepository interface
+var _ repository.ShoppingListRepository = &ShoppingListRepo{}
+
+// SaveShoppingList saves the list with its items
+func (r *ShoppingListRepo) SaveShoppingList(list *entity.ShoppingList) (*entity.ShoppingList, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Create(list).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return list, nil
+}
+
+func (r *ShoppingListRepo) GetShoppingList(id uint64) (*entity.ShoppingList, error) {
+	var list entity.ShoppingList
+	err := r.db.Debug().Preload("Items", orderById).Where("id = ?", id).Take(&list).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("shopping list not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &list, nil
+}
+
+func (r *ShoppingListRepo) GetShoppingListsByUser(userId uint64) ([]entity.ShoppingList, error) {
+	var lists []entity.ShoppingList
+	err := r.db.Debug().Preload("Items", orderById).Where("user_id = ?", userId).Order("created_at desc").Find(&lists).Error
+	if err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+func (r *ShoppingListRepo) UpdateShoppingListShare(list *entity.ShoppingList) map[string]string {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Model(list).UpdateColumn("share_nonce", list.ShareNonce).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return dbErr
+	}
+	return nil
+}
+
+func (r *ShoppingListRepo) DeleteShoppingList(id uint64) error {
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("list_id = ?", id).Delete(&entity.ShoppingListItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&entity.ShoppingList{}).Error
+	})
+	if err != nil {
+		return errors.New("database error, please try again")
+	}
+	return nil
+}
+
+func (r *ShoppingListRepo) SaveShoppingListItem(item *entity.ShoppingListItem) (*entity.ShoppingListItem, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Create(item).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return item, nil
+}
+
+func (r *ShoppingListRepo) GetShoppingListItem(id uint64) (*entity.ShoppingListItem, error) {
+	var item entity.ShoppingListItem
+	err := r.db.Debug().Where("id = ?", id).Take(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("item not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &item, nil
+}
+
+func (r *ShoppingListRepo) UpdateShoppingListItem(item *entity.ShoppingListItem) (*entity.ShoppingListItem, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Model(item).Select("name", "quantity", "unit", "aisle", "checked").Updates(item).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return item, nil
+}
+
+func (r *ShoppingListRepo) DeleteShoppingListItem(id uint64) error {
+	err := r.db.Debug().Where("id = ?", id).Delete(&entity.ShoppingListItem{}).Error
+	if err != nil {
+		return errors.New("database error, please try again")
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/domain/repository"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UploadRepo struct {
+	db *gorm.DB
+}
+
+func NewUploadRepository(db *gorm.DB) *UploadRepo {
+	return &UploadRepo{db}
+}
+
+// UploadRepo implements the repository.UploadRepository interface
+var _ repository.UploadRepository = &UploadRepo{}
+
+func (r *UploadRepo) SaveUpload(upload *entity.Upload) (*entity.Upload, map[string]string) {
+	dbErr := map[string]string{}
+	err := r.db.Debug().Create(upload).Error
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	return upload, nil
+}
+
+func (r *UploadRepo) GetUpload(id uint64) (*entity.Upload, error) {
+	var upload entity.Upload
+	err := r.db.Debug().Where("id = ?", id).Take(&upload).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("upload not found")
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &upload, nil
+}
+
+// GetUploadByKey returns the upload of a stored file, nil is returned when there is none
+func (r *UploadRepo) GetUploadByKey(key string) (*entity.Upload, error) {
+	var upload entity.Upload
+	err := r.db.Debug().Where("key = ?", key).Take(&upload).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("database error, please try again")
+	}
+	return &upload, nil
+}
+
+func (r *UploadRepo) UpdateUpload(upload *entity.Upload) error {
+	return r.db.Debug().Model(upload).
+		Select("status", "image_path", "video_path", "duration_ms", "width", "height", "updated_at").
+		Updates(upload).Error
+}
+
+// UpdateUploadOffset saves the offset and the chunks of a resumable upload, only if its offset in the database is still from.
+// The bytes of a chunk are claimed this way before they are stored, so two chunks never get the same place.
+func (r *UploadRepo) UpdateUploadOffset(upload *entity.Upload, from int64) error {
+	result := r.db.Debug().Model(&entity.Upload{}).
+		Where("id = ? AND \"offset\" = ? AND status = ?", upload.ID, from, entity.UploadStatusPending).
+		UpdateColumns(map[string]interface{}{
+			"offset":     upload.Offset,
+			"chunks":     upload.Chunks,
+			"updated_at": upload.UpdatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entity.ErrUploadOffset
+	}
+	return nil
+}
+
+func (r *UploadRepo) DeleteUpload(id uint64) error {
+	return r.db.Debug().Where("id = ?", id).Delete(&entity.Upload{}).Error
+}
+
+// AttachUpload marks the confirmed upload of the user as used, an image or a video is attached to a single food.
+// An upload of another kind is left as it is.
+func (r *UploadRepo) AttachUpload(uploadId uint64, userId uint64, kind entity.MediaKind) (*entity.Upload, map[string]string) {
+	dbErr := map[string]string{}
+	var upload entity.Upload
+	var status entity.UploadStatus
+	var uploadKind entity.MediaKind
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", uploadId, userId).
+			Take(&upload).Error
+		if err != nil {
+			return err
+		}
+		status = upload.Status
+		policy, _ := entity.UploadPolicyFor(upload.ContentType)
+		uploadKind = policy.Kind
+		if upload.Status != entity.UploadStatusConfirmed || uploadKind != kind {
+			return nil
+		}
+		upload.Status = entity.UploadStatusAttached
+		upload.UpdatedAt = time.Now()
+		return tx.Model(&upload).UpdateColumns(map[string]interface{}{
+			"status":     upload.Status,
+			"updated_at": upload.UpdatedAt,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		dbErr["upload_not_found"] = "upload not found"
+		return nil, dbErr
+	}
+	if err != nil {
+		dbErr["db_error"] = "database error"
+		return nil, dbErr
+	}
+	switch {
+	case uploadKind != kind:
+		dbErr["invalid_upload_kind"] = fmt.Sprintf("the upload should be of kind %s", kind)
+		return nil, dbErr
+	case status == entity.UploadStatusPending:
+		dbErr["upload_not_confirmed"] = "the upload should be confirmed first"
+		return nil, dbErr
+	case status == entity.UploadStatusAttached:
+		dbErr["upload_used"] = "the upload is already used"
+		return nil, dbErr
+	}
+	return &upload, nil
+}
+
+// DetachUpload gives back an attached upload when what it was attached to could not be saved, so it can be used again
+func (r *UploadRepo) DetachUpload(uploadId uint64) error {
+	err := r.db.Debug().Model(&entity.Upload{}).
+		Where("id = ? AND status = ?", uploadId, entity.UploadStatusAttached).
+		UpdateColumns(map[string]interface{}{
+			"status":     entity.UploadStatusConfirmed,
+			"updated_at": time.Now(),
+		}).Error
+	if err != nil {
+		return errors.New("database error, please try again")
+	}
+	return nil
+}
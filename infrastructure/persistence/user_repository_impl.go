@@ -6,6 +6,7 @@ import (
 	"learning-golang-ddd/domain/repository"
 	"learning-golang-ddd/infrastructure/security"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -84,3 +85,85 @@ func (r *UserRepo) GetUserByFilter(u *entity.User) (*entity.User, map[string]str
 
 	return &user, nil
 }
+
+// DeleteUser moves the user to the trash, together with their foods.
+// The foods share the user's deleted_at, so restoring the user brings back exactly those foods.
+func (r *UserRepo) DeleteUser(id uint64) error {
+	now := time.Now()
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		if err := softDeleteFoods(tx, now, "user_id = ?", id); err != nil {
+			return err
+		}
+		return tx.Model(&entity.User{}).Where("id = ?", id).UpdateColumn("deleted_at", now).Error
+	})
+	if err != nil {
+		return errors.New("database error, please try again")
+	}
+	return nil
+}
+
+func (r *UserRepo) RestoreUser(id uint64) error {
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		var user entity.User
+		if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).Take(&user).Error; err != nil {
+			return err
+		}
+		err := tx.Unscoped().Model(&entity.Food{}).
+			Where("user_id = ? AND deleted_at = ?", id, user.DeletedAt).
+			UpdateColumns(map[string]interface{}{"deleted_at": nil, "updated_at": time.Now()}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&user).UpdateColumn("deleted_at", nil).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("user not found")
+	}
+	if err != nil {
+		return errors.New("database error, please try again")
+	}
+	return nil
+}
+
+// PurgeDeletedUsers permanently deletes the users that were moved to the trash before the given time.
+// Their foods were trashed at the same time, so they are purged by PurgeDeletedFoods.
+func (r *UserRepo) PurgeDeletedUsers(before time.Time) ([]entity.User, error) {
+	var users []entity.User
+	err := r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Limit(100).Find(&users).Error
+		if err != nil || len(users) == 0 {
+			return err
+		}
+		ids := make([]uint64, len(users))
+		for i, user := range users {
+			ids[i] = uint64(user.ID)
+		}
+		var collectionIds []uint64
+		if err := tx.Model(&entity.Collection{}).Where("user_id IN ?", ids).Pluck("id", &collectionIds).Error; err != nil {
+			return err
+		}
+		if len(collectionIds) > 0 {
+			if err := tx.Where("collection_id IN ?", collectionIds).Delete(&entity.CollectionItem{}).Error; err != nil {
+				return err
+			}
+		}
+		// the reports they made stay in the queue, without their name
+		if err := tx.Model(&entity.Report{}).Where("reporter_id IN ?", ids).UpdateColumn("reporter_id", nil).Error; err != nil {
+			return err
+		}
+		listIds := tx.Model(&entity.ShoppingList{}).Select("id").Where("user_id IN ?", ids)
+		if err := tx.Where("list_id IN (?)", listIds).Delete(&entity.ShoppingListItem{}).Error; err != nil {
+			return err
+		}
+		for _, owned := range []interface{}{&entity.Collection{}, &entity.Favorite{}, &entity.MealPlanEntry{}, &entity.CalendarFeed{}, &entity.ShoppingList{}, &entity.Notification{}, &entity.Upload{}} {
+			if err := tx.Where("user_id IN ?", ids).Delete(owned).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&entity.User{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
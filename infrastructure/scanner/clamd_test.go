@@ -0,0 +1,159 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd answers a single INSTREAM command with reply, and records the chunks it received
+type fakeClamd struct {
+	listener net.Listener
+	reply    string
+	done     chan struct{}
+
+	command    string
+	chunks     [][]byte
+	terminated bool
+	err        error
+}
+
+func newFakeClamd(t *testing.T, network string, reply string) *fakeClamd {
+	t.Helper()
+	address := "127.0.0.1:0"
+	if network == "unix" {
+		address = filepath.Join(t.TempDir(), "clamd.sock")
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatalf("cannot listen on %s %s: %v", network, address, err)
+	}
+	clamd := &fakeClamd{listener: listener, reply: reply, done: make(chan struct{})}
+	go clamd.serve()
+	t.Cleanup(func() { listener.Close() })
+	return clamd
+}
+
+func (f *fakeClamd) serve() {
+	defer close(f.done)
+	conn, err := f.listener.Accept()
+	if err != nil {
+		f.err = err
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	f.command, f.err = r.ReadString(0)
+	if f.err != nil {
+		return
+	}
+	size := make([]byte, 4)
+	for {
+		if _, f.err = io.ReadFull(r, size); f.err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint32(size)
+		if n == 0 {
+			f.terminated = true
+			break
+		}
+		chunk := make([]byte, n)
+		if _, f.err = io.ReadFull(r, chunk); f.err != nil {
+			return
+		}
+		f.chunks = append(f.chunks, chunk)
+	}
+	_, f.err = conn.Write([]byte(f.reply + "\x00"))
+}
+
+// wait returns once the fake clamd replied or gave up
+func (f *fakeClamd) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-f.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the fake clamd did not finish")
+	}
+}
+
+func TestClamdScanner_Scan(t *testing.T) {
+	// a bit more than two chunks
+	file := bytes.Repeat([]byte("0123456789abcdef"), (2*clamdChunkSize+100)/16)
+
+	tests := []struct {
+		name      string
+		reply     string
+		wantFound string
+		wantErr   string
+	}{
+		{name: "clean", reply: "stream: OK"},
+		{name: "infected", reply: "stream: Eicar-Signature FOUND", wantFound: "Eicar-Signature"},
+		{name: "error reply", reply: "INSTREAM size limit exceeded. ERROR", wantErr: "clamd: INSTREAM size limit exceeded. ERROR"},
+	}
+	for _, network := range []string{"tcp", "unix"} {
+		for _, tt := range tests {
+			t.Run(network+"/"+tt.name, func(t *testing.T) {
+				clamd := newFakeClamd(t, network, tt.reply)
+				scanner := NewClamdScanner(network, clamd.listener.Addr().String(), 5*time.Second)
+
+				found, err := scanner.Scan(bytes.NewReader(file))
+				clamd.wait(t)
+				if clamd.err != nil {
+					t.Fatalf("fake clamd: %v", clamd.err)
+				}
+				if tt.wantErr != "" {
+					if err == nil || err.Error() != tt.wantErr {
+						t.Fatalf("Scan() error = %v, want %q", err, tt.wantErr)
+					}
+				} else if err != nil {
+					t.Fatalf("Scan() error = %v", err)
+				}
+				if found != tt.wantFound {
+					t.Errorf("Scan() = %q, want %q", found, tt.wantFound)
+				}
+
+				if clamd.command != "zINSTREAM\x00" {
+					t.Errorf("command = %q, want %q", clamd.command, "zINSTREAM\x00")
+				}
+				if !clamd.terminated {
+					t.Error("the stream did not end with a zero length chunk")
+				}
+				if len(clamd.chunks) < 3 {
+					t.Errorf("got %d chunks, want the file split in at least 3", len(clamd.chunks))
+				}
+				for i, chunk := range clamd.chunks {
+					if len(chunk) > clamdChunkSize {
+						t.Errorf("chunk %d is %d bytes, more than %d", i, len(chunk), clamdChunkSize)
+					}
+				}
+				if received := bytes.Join(clamd.chunks, nil); !bytes.Equal(received, file) {
+					t.Errorf("clamd received %d bytes, want the %d bytes of the file", len(received), len(file))
+				}
+			})
+		}
+	}
+}
+
+func TestClamdScanner_ScanUnreachable(t *testing.T) {
+	for _, network := range []string{"tcp", "unix"} {
+		t.Run(network, func(t *testing.T) {
+			clamd := newFakeClamd(t, network, "stream: OK")
+			address := clamd.listener.Addr().String()
+			// nothing listens on the address anymore
+			clamd.listener.Close()
+			clamd.wait(t)
+
+			scanner := NewClamdScanner(network, address, time.Second)
+			found, err := scanner.Scan(strings.NewReader("file"))
+			if err == nil {
+				t.Fatalf("Scan() = %q, want an error", found)
+			}
+		})
+	}
+}
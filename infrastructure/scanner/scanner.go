@@ -0,0 +1,46 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Scanner looks for malware in a file. Scan returns the name of what was found, empty when the file is clean.
+type Scanner interface {
+	Scan(r io.Reader) (string, error)
+}
+
+// NoopScanner finds nothing, it is used when no scanner is configured
+type NoopScanner struct{}
+
+var _ Scanner = NoopScanner{}
+
+func NewNoopScanner() NoopScanner {
+	return NoopScanner{}
+}
+
+func (NoopScanner) Scan(r io.Reader) (string, error) {
+	return "", nil
+}
+
+// FromEnv creates the scanner of CLAMD_ADDRESS, e.g tcp://localhost:3310 or unix:///var/run/clamav/clamd.ctl.
+// The files are not scanned when it is not set.
+func FromEnv() (Scanner, error) {
+	address := os.Getenv("CLAMD_ADDRESS")
+	if address == "" {
+		return NewNoopScanner(), nil
+	}
+	timeout, err := time.ParseDuration(os.Getenv("CLAMD_TIMEOUT"))
+	if err != nil {
+		timeout = 30 * time.Second
+	}
+	for _, network := range []string{"tcp", "unix"} {
+		if strings.HasPrefix(address, network+"://") {
+			return NewClamdScanner(network, strings.TrimPrefix(address, network+"://"), timeout), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid CLAMD_ADDRESS %q, should start with tcp:// or unix://", address)
+}
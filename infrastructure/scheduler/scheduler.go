@@ -0,0 +1,26 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Every runs the job on each tick of the interval, until the context is done.
+// The job is not run concurrently with itself: a slow run delays the next one.
+func Every(ctx context.Context, interval time.Duration, name string, job func(time.Time) error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := job(now); err != nil {
+					log.Printf("scheduler: %s failed: %v", name, err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,34 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+)
+
+// signingKey is SIGNING_SECRET, or ACCESS_SECRET when it is not set
+func signingKey() []byte {
+	if secret := os.Getenv("SIGNING_SECRET"); secret != "" {
+		return toByte(secret)
+	}
+	return toByte(os.Getenv("ACCESS_SECRET"))
+}
+
+// Sign returns the HMAC-SHA256 of the message as hex, used to sign the links we hand out
+func Sign(message string) string {
+	mac := hmac.New(sha256.New, signingKey())
+	mac.Write(toByte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a signature made by Sign, in constant time
+func VerifySignature(message string, signature string) bool {
+	given, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, signingKey())
+	mac.Write(toByte(message))
+	return hmac.Equal(mac.Sum(nil), given)
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// the put options of each file are kept in this directory of the root, as <key>.json
+const localMetaDir = ".meta"
+
+// LocalStorage keeps the files in a directory of the local disk
+type LocalStorage struct {
+	root      string
+	publicURL string
+}
+
+var _ Storage = &LocalStorage{}
+
+func NewLocalStorage(root string, publicURL string) (*LocalStorage, error) {
+	if err := os.MkdirAll(filepath.Join(root, localMetaDir), 0755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{root: root, publicURL: publicURL}, nil
+}
+
+// Put writes the file next to its final place first, so a reader never sees half a file
+func (s *LocalStorage) Put(key string, r io.Reader, size int64, opts PutOptions) error {
+	filePath, metaPath, err := s.paths(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if opts.ContentType == "" {
+		opts.ContentType = mime.TypeByExtension(path.Ext(key))
+	}
+	meta, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(metaPath, meta, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+func (s *LocalStorage) Get(key string) (io.ReadCloser, error) {
+	filePath, _, err := s.paths(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	filePath, metaPath, err := s.paths(key)
+	if err != nil {
+		return err
+	}
+	for _, p := range []string{filePath, metaPath} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LocalStorage) Stat(key string) (*ObjectInfo, error) {
+	filePath, metaPath, err := s.paths(key)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// a file copied into the directory by hand has no options, it is private
+	var opts PutOptions
+	if meta, err := ioutil.ReadFile(metaPath); err == nil {
+		json.Unmarshal(meta, &opts)
+	}
+	if opts.ContentType == "" {
+		opts.ContentType = mime.TypeByExtension(path.Ext(key))
+	}
+	cleaned, _ := CleanKey(key)
+	return &ObjectInfo{
+		Key:          cleaned,
+		Size:         stat.Size(),
+		ContentType:  opts.ContentType,
+		CacheControl: opts.CacheControl,
+		Public:       opts.Public,
+		ModTime:      stat.ModTime(),
+	}, nil
+}
+
+func (s *LocalStorage) SetPublic(key string, public bool) error {
+	info, err := s.Stat(key)
+	if err != nil {
+		return err
+	}
+	_, metaPath, err := s.paths(key)
+	if err != nil {
+		return err
+	}
+	meta, err := json.Marshal(PutOptions{ContentType: info.ContentType, CacheControl: info.CacheControl, Public: public})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(metaPath, meta, 0644)
+}
+
+// List walks the directory, leaving out the options and the files still being written
+func (s *LocalStorage) List(prefix string, fn func(ObjectInfo) error) error {
+	return filepath.Walk(s.root, func(filePath string, stat os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.root, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if stat.IsDir() {
+			if key == localMetaDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(stat.Name(), ".upload-") || !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		return fn(ObjectInfo{Key: key, Size: stat.Size(), ModTime: stat.ModTime()})
+	})
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.publicURL + key
+}
+
+func (s *LocalStorage) PresignPut(key string, contentType string, expires time.Duration) (string, error) {
+	return presignLocalPut(s.publicURL, key, contentType, expires)
+}
+
+// paths returns where the file and its options are stored
+func (s *LocalStorage) paths(key string) (string, string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	if key == localMetaDir || strings.HasPrefix(key, localMetaDir+"/") {
+		return "", "", errors.New("invalid key " + key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), filepath.Join(s.root, localMetaDir, filepath.FromSlash(key)+".json"), nil
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStorage keeps the files in memory, they are lost on restart. Meant for development and tests.
+type MemoryStorage struct {
+	mu        sync.RWMutex
+	objects   map[string]*memoryObject
+	publicURL string
+}
+
+type memoryObject struct {
+	data []byte
+	info ObjectInfo
+}
+
+var _ Storage = &MemoryStorage{}
+
+func NewMemoryStorage(publicURL string) *MemoryStorage {
+	return &MemoryStorage{
+		objects:   map[string]*memoryObject{},
+		publicURL: publicURL,
+	}
+}
+
+func (s *MemoryStorage) Put(key string, r io.Reader, size int64, opts PutOptions) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = &memoryObject{
+		data: data,
+		info: ObjectInfo{
+			Key:          key,
+			Size:         int64(len(data)),
+			ContentType:  opts.ContentType,
+			CacheControl: opts.CacheControl,
+			Public:       opts.Public,
+			ModTime:      time.Now(),
+		},
+	}
+	return nil
+}
+
+func (s *MemoryStorage) Get(key string) (io.ReadCloser, error) {
+	object, err := s.object(key)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(object.data)), nil
+}
+
+func (s *MemoryStorage) Delete(key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *MemoryStorage) Stat(key string) (*ObjectInfo, error) {
+	object, err := s.object(key)
+	if err != nil {
+		return nil, err
+	}
+	info := object.info
+	return &info, nil
+}
+
+func (s *MemoryStorage) List(prefix string, fn func(ObjectInfo) error) error {
+	// fn may change the storage, e.g delete the file it is given
+	s.mu.RLock()
+	infos := make([]ObjectInfo, 0, len(s.objects))
+	for key, object := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			infos = append(infos, object.info)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	for _, info := range infos {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStorage) SetPublic(key string, public bool) error {
+	object, err := s.object(key)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	object.info.Public = public
+	return nil
+}
+
+func (s *MemoryStorage) URL(key string) string {
+	return s.publicURL + key
+}
+
+func (s *MemoryStorage) PresignPut(key string, contentType string, expires time.Duration) (string, error) {
+	return presignLocalPut(s.publicURL, key, contentType, expires)
+}
+
+func (s *MemoryStorage) object(key string) (*memoryObject, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	object, ok := s.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return object, nil
+}
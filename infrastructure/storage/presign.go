@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"fmt"
+	"learning-golang-ddd/infrastructure/security"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// The local disk and the memory have no presigned urls of their own. Their urls point to the api,
+// which accepts the PUT on the public url of the key when it carries a signature made with SIGNING_SECRET.
+func presignLocalPut(publicURL string, key string, contentType string, expires time.Duration) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{}
+	query.Set("content_type", contentType)
+	query.Set("expires", expiresAt)
+	query.Set("signature", security.Sign(presignMessage(key, contentType, expiresAt)))
+	return publicURL + key + "?" + query.Encode(), nil
+}
+
+// VerifyPresignedPut checks the query of an url made by the local or memory storage, it fails once the url expired
+func VerifyPresignedPut(key string, query url.Values, now time.Time) bool {
+	key, err := CleanKey(key)
+	if err != nil {
+		return false
+	}
+	expiresAt, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || now.Unix() > expiresAt {
+		return false
+	}
+	return security.VerifySignature(presignMessage(key, query.Get("content_type"), query.Get("expires")), query.Get("signature"))
+}
+
+func presignMessage(key string, contentType string, expiresAt string) string {
+	return fmt.Sprintf("put:%s:%s:%s", key, contentType, expiresAt)
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// the user metadata telling a public file from a private one, S3 does not return the acl with the object
+const s3VisibilityMeta = "Visibility"
+
+// S3Storage keeps the files in a bucket of an S3 compatible service, e.g Digital Ocean spaces or minio
+type S3Storage struct {
+	client    *minio.Client
+	bucket    string
+	publicURL string
+}
+
+var _ Storage = &S3Storage{}
+
+func NewS3Storage(cfg S3Config, publicURL string) (*S3Storage, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.SSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3Storage{client: client, bucket: cfg.Bucket, publicURL: publicURL}, nil
+}
+
+func (s *S3Storage) Put(key string, r io.Reader, size int64, opts PutOptions) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(context.Background(), s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType:  opts.ContentType,
+		CacheControl: opts.CacheControl,
+		UserMetadata: s3Visibility(opts.Public),
+	})
+	return err
+}
+
+// SetPublic copies the object onto itself with the new acl, S3 has no other way to change the metadata.
+// The content type and the cache control are copied along.
+func (s *S3Storage) SetPublic(key string, public bool) error {
+	info, err := s.Stat(key)
+	if err != nil {
+		return err
+	}
+	userMetadata := s3Visibility(public)
+	userMetadata["Content-Type"] = info.ContentType
+	if info.CacheControl != "" {
+		userMetadata["Cache-Control"] = info.CacheControl
+	}
+	_, err = s.client.CopyObject(context.Background(),
+		minio.CopyDestOptions{Bucket: s.bucket, Object: info.Key, UserMetadata: userMetadata, ReplaceMetadata: true},
+		minio.CopySrcOptions{Bucket: s.bucket, Object: info.Key},
+	)
+	return s3Error(err)
+}
+
+func (s *S3Storage) Get(key string) (io.ReadCloser, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	object, err := s.client.GetObject(context.Background(), s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	// the object is fetched lazily, stat it so a missing file is reported here
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, s3Error(err)
+	}
+	return object, nil
+}
+
+func (s *S3Storage) Delete(key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	return s.client.RemoveObject(context.Background(), s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *S3Storage) Stat(key string) (*ObjectInfo, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.client.StatObject(context.Background(), s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	return &ObjectInfo{
+		Key:          key,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		CacheControl: info.Metadata.Get("Cache-Control"),
+		Public:       info.UserMetadata[s3VisibilityMeta] == "public",
+		ModTime:      info.LastModified,
+	}, nil
+}
+
+func (s *S3Storage) List(prefix string, fn func(ObjectInfo) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	// stops the listing when fn returns early
+	defer cancel()
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return object.Err
+		}
+		err := fn(ObjectInfo{Key: object.Key, Size: object.Size, ContentType: object.ContentType, ModTime: object.LastModified})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3Storage) URL(key string) string {
+	return s.publicURL + key
+}
+
+// PresignPut signs a PUT of the object. The content type is not part of an S3 signature,
+// so what was uploaded has to be checked once it is there.
+func (s *S3Storage) PresignPut(key string, contentType string, expires time.Duration) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	u, err := s.client.PresignedPutObject(context.Background(), s.bucket, key, expires)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// s3Visibility is the metadata of a public or a private object
+func s3Visibility(public bool) map[string]string {
+	if public {
+		return map[string]string{"x-amz-acl": "public-read", s3VisibilityMeta: "public"}
+	}
+	return map[string]string{"x-amz-acl": "private", s3VisibilityMeta: "private"}
+}
+
+func s3Error(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when there is no file under the key
+var ErrNotFound = errors.New("file not found")
+
+// Storage keeps the uploaded files under keys like "a1b2.png" or "exports/1/2.zip"
+type Storage interface {
+	Put(key string, r io.Reader, size int64, opts PutOptions) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	Stat(key string) (*ObjectInfo, error)
+	// URL is where the public files can be downloaded from
+	URL(key string) string
+	// List calls fn with each file whose key starts with prefix, until fn returns an error
+	List(prefix string, fn func(ObjectInfo) error) error
+	// PresignPut returns an url the file can be uploaded to with a PUT, without going through the api
+	PresignPut(key string, contentType string, expires time.Duration) (string, error)
+	// SetPublic makes a stored file public or private, its content and other options are kept
+	SetPublic(key string, public bool) error
+}
+
+type PutOptions struct {
+	ContentType  string
+	CacheControl string
+	// only the public files can be downloaded with their url
+	Public bool
+}
+
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type"`
+	CacheControl string    `json:"cache_control"`
+	Public       bool      `json:"public"`
+	ModTime      time.Time `json:"mod_time"`
+}
+
+const (
+	DriverLocal  = "local"
+	DriverMemory = "memory"
+	DriverS3     = "s3"
+)
+
+type Config struct {
+	Driver string
+	// prepended to the keys by URL, /files/ when the files are served by the api itself
+	PublicURL string
+	LocalDir  string
+	S3        S3Config
+}
+
+type S3Config struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	SSL       bool
+}
+
+// ConfigFromEnv reads the STORAGE_ variables. The DO_SPACES_ variables are still read when they are not set,
+// so a deployment on Digital Ocean spaces keeps working as it was.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Driver:    strings.ToLower(os.Getenv("STORAGE_DRIVER")),
+		PublicURL: envOr("STORAGE_PUBLIC_URL", "DO_SPACES_URL"),
+		LocalDir:  os.Getenv("STORAGE_LOCAL_DIR"),
+		S3: S3Config{
+			Endpoint:  envOr("STORAGE_S3_ENDPOINT", "DO_SPACES_ENDPOINT"),
+			AccessKey: envOr("STORAGE_S3_KEY", "DO_SPACES_KEY"),
+			SecretKey: envOr("STORAGE_S3_SECRET", "DO_SPACES_SECRET"),
+			Bucket:    os.Getenv("STORAGE_S3_BUCKET"),
+			Region:    os.Getenv("STORAGE_S3_REGION"),
+			SSL:       true,
+		},
+	}
+	if ssl, err := strconv.ParseBool(os.Getenv("STORAGE_S3_SSL")); err == nil {
+		cfg.S3.SSL = ssl
+	}
+	if cfg.Driver == "" {
+		cfg.Driver = DriverLocal
+		if cfg.S3.Endpoint != "" {
+			cfg.Driver = DriverS3
+		}
+	}
+	return cfg
+}
+
+// New creates the storage of the configured driver
+func New(cfg Config) (Storage, error) {
+	switch cfg.Driver {
+	case DriverLocal:
+		if cfg.LocalDir == "" {
+			cfg.LocalDir = "uploads"
+		}
+		return NewLocalStorage(cfg.LocalDir, publicURL(cfg.PublicURL, "/files/"))
+	case DriverMemory:
+		return NewMemoryStorage(publicURL(cfg.PublicURL, "/files/")), nil
+	case DriverS3:
+		if cfg.S3.Bucket == "" {
+			cfg.S3.Bucket = "chodapi"
+		}
+		return NewS3Storage(cfg.S3, cfg.PublicURL)
+	}
+	return nil, fmt.Errorf("unknown storage driver %q, should be local, memory or s3", cfg.Driver)
+}
+
+// CleanKey rejects the keys that could reach outside of the storage, e.g "../.env"
+func CleanKey(key string) (string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(key, "/"))
+	if key == "" || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return cleaned, nil
+}
+
+func envOr(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return os.Getenv(fallback)
+}
+
+func publicURL(configured string, fallback string) string {
+	if configured == "" {
+		return fallback
+	}
+	return configured
+}
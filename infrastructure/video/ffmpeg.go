@@ -0,0 +1,52 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// FFmpegFrameExtractor runs ffmpeg to decode a frame. A video it cannot decode is reported as an error.
+type FFmpegFrameExtractor struct {
+	path    string
+	timeout time.Duration
+}
+
+var _ FrameExtractor = &FFmpegFrameExtractor{}
+
+func NewFFmpegFrameExtractor(path string, timeout time.Duration) *FFmpegFrameExtractor {
+	return &FFmpegFrameExtractor{path: path, timeout: timeout}
+}
+
+// Frame seeks to the time before opening the input, so only the frames from the key frame before it are decoded.
+// The image is written to the standard output, nothing is written to the disk.
+func (e *FFmpegFrameExtractor) Frame(videoPath string, at time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.path,
+		"-nostdin", "-hide_banner", "-loglevel", "error",
+		"-ss", fmt.Sprintf("%.3f", at.Seconds()),
+		"-i", videoPath,
+		"-frames:v", "1", "-an", "-sn",
+		"-f", "image2pipe", "-c:v", "mjpeg", "-q:v", "3",
+		"pipe:1",
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, errors.New("ffmpeg timed out")
+		}
+		return nil, fmt.Errorf("ffmpeg: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, errors.New("ffmpeg found no frame")
+	}
+	return stdout.Bytes(), nil
+}
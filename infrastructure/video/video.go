@@ -0,0 +1,48 @@
+package video
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// FrameExtractor takes a frame of a video file, at the given time, as a JPEG image.
+// It returns no image and no error when it cannot extract frames at all.
+type FrameExtractor interface {
+	Frame(videoPath string, at time.Duration) ([]byte, error)
+}
+
+// NoopFrameExtractor extracts nothing, it is used when ffmpeg is not installed. The clips have no poster then.
+type NoopFrameExtractor struct{}
+
+var _ FrameExtractor = NoopFrameExtractor{}
+
+func NewNoopFrameExtractor() NoopFrameExtractor {
+	return NoopFrameExtractor{}
+}
+
+func (NoopFrameExtractor) Frame(videoPath string, at time.Duration) ([]byte, error) {
+	return nil, nil
+}
+
+// FromEnv creates the extractor of FFMPEG_PATH, or of the ffmpeg found in the PATH when it is not set.
+// No frame is extracted when there is no ffmpeg.
+func FromEnv() (FrameExtractor, error) {
+	path := os.Getenv("FFMPEG_PATH")
+	if path == "" {
+		found, err := exec.LookPath("ffmpeg")
+		if err != nil {
+			return NewNoopFrameExtractor(), nil
+		}
+		path = found
+	}
+	if _, err := exec.LookPath(path); err != nil {
+		return nil, fmt.Errorf("invalid FFMPEG_PATH %q: %v", path, err)
+	}
+	timeout, err := time.ParseDuration(os.Getenv("FFMPEG_TIMEOUT"))
+	if err != nil {
+		timeout = 30 * time.Second
+	}
+	return NewFFmpegFrameExtractor(path, timeout), nil
+}
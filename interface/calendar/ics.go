@@ -0,0 +1,83 @@
+package calendar
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// Event is a single VEVENT of an iCalendar (RFC 5545) file
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	// Start and End are written as floating times, without a time zone
+	Start time.Time
+	End   time.Time
+}
+
+const (
+	floatingLayout = "20060102T150405"
+	utcLayout      = "20060102T150405Z"
+)
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// Write writes the events as an iCalendar file named name
+func Write(w io.Writer, name string, events []Event) error {
+	out := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format(utcLayout)
+
+	writeLine(out, "BEGIN:VCALENDAR")
+	writeLine(out, "VERSION:2.0")
+	writeLine(out, "PRODID:-//learning-golang-ddd//meal plan//EN")
+	writeLine(out, "CALSCALE:GREGORIAN")
+	writeLine(out, "METHOD:PUBLISH")
+	writeLine(out, "X-WR-CALNAME:"+escapeText(name))
+	for _, event := range events {
+		writeLine(out, "BEGIN:VEVENT")
+		writeLine(out, "UID:"+event.UID)
+		writeLine(out, "DTSTAMP:"+stamp)
+		writeLine(out, "DTSTART:"+event.Start.Format(floatingLayout))
+		writeLine(out, "DTEND:"+event.End.Format(floatingLayout))
+		writeLine(out, "SUMMARY:"+escapeText(event.Summary))
+		if event.Description != "" {
+			writeLine(out, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		if event.URL != "" {
+			writeLine(out, "URL:"+event.URL)
+		}
+		writeLine(out, "END:VEVENT")
+	}
+	writeLine(out, "END:VCALENDAR")
+	return out.Flush()
+}
+
+func escapeText(text string) string {
+	return textEscaper.Replace(text)
+}
+
+// writeLine ends the line with CRLF and folds it when it is longer than 75 octets,
+// without splitting a multi-byte character
+func writeLine(out *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		out.WriteString(line[:cut])
+		out.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space of a continuation line counts in its 75 octets
+		limit = 74
+	}
+	out.WriteString(line)
+	out.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
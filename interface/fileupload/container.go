@@ -0,0 +1,672 @@
+package fileupload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	errInvalidVideo   = errors.New("the file is not a valid MP4 or WebM video")
+	errTruncatedVideo = errors.New("the video is truncated")
+	errNoVideoTrack   = errors.New("the video has no video track")
+	errVideoDuration  = errors.New("the duration of the video cannot be read")
+)
+
+const (
+	// a container of more boxes or elements than this is refused, a short clip has far fewer
+	maxContainerItems = 1 << 20
+	// the moov box of a MP4 is read at once, it is only the index of the samples
+	maxMoovSize = 16 << 20
+)
+
+// mp4Codecs are the sample entries of the video tracks a MP4 can have, the browsers play them
+var mp4Codecs = map[string]bool{"avc1": true, "avc3": true, "hvc1": true, "hev1": true, "av01": true, "vp09": true}
+
+// webmCodecs are the codecs of the video tracks a WebM can have
+var webmCodecs = map[string]bool{"V_VP8": true, "V_VP9": true, "V_AV1": true}
+
+// videoInfo is what a video is, from its container
+type videoInfo struct {
+	contentType string
+	duration    time.Duration
+	width       int
+	height      int
+}
+
+// probeVideo reads the container of the video, it has to be a well formed MP4 or WebM with a video track
+// and a known duration. The frames themselves are not decoded.
+func probeVideo(r io.ReaderAt, size int64) (*videoInfo, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, errInvalidVideo
+	}
+	switch {
+	case string(header[4:8]) == "ftyp":
+		return probeMP4(r, size)
+	case binary.BigEndian.Uint32(header) == ebmlHeaderID:
+		return probeWebM(r, size)
+	}
+	return nil, errInvalidVideo
+}
+
+// mp4Box is a box of a MP4, offset and size are those of its payload
+type mp4Box struct {
+	typ    string
+	offset int64
+	size   int64
+}
+
+// mp4Boxes lists the boxes between start and end, they have to fill it exactly
+func mp4Boxes(r io.ReaderAt, start int64, end int64) ([]mp4Box, error) {
+	boxes := []mp4Box{}
+	header := make([]byte, 16)
+	for offset := start; offset < end; {
+		if len(boxes) == maxContainerItems {
+			return nil, errInvalidVideo
+		}
+		if end-offset < 8 {
+			return nil, errTruncatedVideo
+		}
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return nil, errTruncatedVideo
+		}
+		size := int64(binary.BigEndian.Uint32(header))
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			// the last box goes to the end of the file
+			size = end - offset
+		case 1:
+			if end-offset < 16 {
+				return nil, errTruncatedVideo
+			}
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return nil, errTruncatedVideo
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize {
+			return nil, errInvalidVideo
+		}
+		if size > end-offset {
+			return nil, errTruncatedVideo
+		}
+		boxes = append(boxes, mp4Box{typ: string(header[4:8]), offset: offset + headerSize, size: size - headerSize})
+		offset += size
+	}
+	return boxes, nil
+}
+
+// mp4Child returns the payload of the first box of the path, nil when there is none
+func mp4Child(data []byte, path ...string) []byte {
+	for _, typ := range path {
+		boxes, err := mp4Boxes(bytes.NewReader(data), 0, int64(len(data)))
+		if err != nil {
+			return nil
+		}
+		var found []byte
+		for _, box := range boxes {
+			if box.typ == typ {
+				found = data[box.offset : box.offset+box.size]
+				break
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		data = found
+	}
+	return data
+}
+
+// probeMP4 checks the ISO base media file: a ftyp first, one moov with a video track, and the media data.
+// A QuickTime file is refused, its boxes are not all those of a MP4.
+func probeMP4(r io.ReaderAt, size int64) (*videoInfo, error) {
+	boxes, err := mp4Boxes(r, 0, size)
+	if err != nil {
+		return nil, err
+	}
+	if boxes[0].typ != "ftyp" || boxes[0].size < 8 {
+		return nil, errInvalidVideo
+	}
+	brand := make([]byte, 4)
+	if _, err := r.ReadAt(brand, boxes[0].offset); err != nil {
+		return nil, errInvalidVideo
+	}
+	if string(brand) == "qt  " {
+		return nil, errors.New("QuickTime videos are not supported, please upload a MP4 or WebM video")
+	}
+	var moov *mp4Box
+	hasData := false
+	for i, box := range boxes {
+		switch box.typ {
+		case "moov":
+			if moov != nil {
+				return nil, errInvalidVideo
+			}
+			moov = &boxes[i]
+		case "mdat":
+			hasData = hasData || box.size > 0
+		}
+	}
+	if moov == nil || !hasData {
+		return nil, errInvalidVideo
+	}
+	if moov.size > maxMoovSize {
+		return nil, errInvalidVideo
+	}
+	data := make([]byte, moov.size)
+	if _, err := r.ReadAt(data, moov.offset); err != nil {
+		return nil, errTruncatedVideo
+	}
+	return parseMoov(data)
+}
+
+func parseMoov(data []byte) (*videoInfo, error) {
+	boxes, err := mp4Boxes(bytes.NewReader(data), 0, int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	info := &videoInfo{contentType: "video/mp4"}
+	var timescale, duration, fragmentDuration uint64
+	for _, box := range boxes {
+		payload := data[box.offset : box.offset+box.size]
+		switch box.typ {
+		case "mvhd":
+			timescale, duration, err = parseMvhd(payload)
+			if err != nil {
+				return nil, err
+			}
+		case "mvex":
+			// a fragmented MP4 tells its duration here
+			if mehd := mp4Child(payload, "mehd"); mehd != nil {
+				fragmentDuration = mp4FullBoxValue(mehd, 4)
+			}
+		case "trak":
+			if info.width > 0 {
+				continue
+			}
+			width, height, err := parseTrak(payload)
+			if err != nil {
+				return nil, err
+			}
+			info.width, info.height = width, height
+		}
+	}
+	if info.width == 0 || info.height == 0 {
+		return nil, errNoVideoTrack
+	}
+	if duration == 0 {
+		duration = fragmentDuration
+	}
+	if timescale == 0 || duration == 0 {
+		return nil, errVideoDuration
+	}
+	info.duration = mediaDuration(float64(duration) / float64(timescale))
+	return info, nil
+}
+
+// parseMvhd returns the timescale of the movie and its duration in that scale, zero when unknown
+func parseMvhd(payload []byte) (uint64, uint64, error) {
+	if len(payload) < 20 {
+		return 0, 0, errInvalidVideo
+	}
+	if payload[0] == 1 {
+		if len(payload) < 32 {
+			return 0, 0, errInvalidVideo
+		}
+		return uint64(binary.BigEndian.Uint32(payload[20:24])), mp4FullBoxValue(payload, 24), nil
+	}
+	return uint64(binary.BigEndian.Uint32(payload[12:16])), mp4FullBoxValue(payload, 16), nil
+}
+
+// mp4FullBoxValue reads a value which is 64 bits in a version 1 box, 32 bits otherwise.
+// The value with all its bits set means unknown, it is read as zero.
+func mp4FullBoxValue(payload []byte, offset int) uint64 {
+	if len(payload) == 0 {
+		return 0
+	}
+	if payload[0] == 1 {
+		if len(payload) < offset+8 {
+			return 0
+		}
+		value := binary.BigEndian.Uint64(payload[offset : offset+8])
+		if value == math.MaxUint64 {
+			return 0
+		}
+		return value
+	}
+	if len(payload) < offset+4 {
+		return 0
+	}
+	value := binary.BigEndian.Uint32(payload[offset : offset+4])
+	if value == math.MaxUint32 {
+		return 0
+	}
+	return uint64(value)
+}
+
+// parseTrak returns the size of a video track, zero for the other tracks
+func parseTrak(payload []byte) (int, int, error) {
+	hdlr := mp4Child(payload, "mdia", "hdlr")
+	if len(hdlr) < 12 || string(hdlr[8:12]) != "vide" {
+		return 0, 0, nil
+	}
+	stsd := mp4Child(payload, "mdia", "minf", "stbl", "stsd")
+	if len(stsd) < 16 || !mp4Codecs[string(stsd[12:16])] {
+		return 0, 0, errors.New("the codec of the video is not supported, it should be H.264, H.265, VP9 or AV1")
+	}
+	tkhd := mp4Child(payload, "tkhd")
+	// the size is a 16.16 fixed point number, after the matrix
+	widthOffset := 76
+	if len(tkhd) > 0 && tkhd[0] == 1 {
+		widthOffset = 88
+	}
+	if len(tkhd) < widthOffset+8 {
+		return 0, 0, errInvalidVideo
+	}
+	width := int(binary.BigEndian.Uint32(tkhd[widthOffset:]) >> 16)
+	height := int(binary.BigEndian.Uint32(tkhd[widthOffset+4:]) >> 16)
+	return width, height, nil
+}
+
+// the EBML ids of the WebM elements that are read
+const (
+	ebmlHeaderID        = 0x1A45DFA3
+	ebmlDocTypeID       = 0x4282
+	webmSegmentID       = 0x18538067
+	webmInfoID          = 0x1549A966
+	webmTimecodeScaleID = 0x2AD7B1
+	webmDurationID      = 0x4489
+	webmTracksID        = 0x1654AE6B
+	webmTrackEntryID    = 0xAE
+	webmTrackTypeID     = 0x83
+	webmCodecID         = 0x86
+	webmVideoID         = 0xE0
+	webmPixelWidthID    = 0xB0
+	webmPixelHeightID   = 0xBA
+	webmClusterID       = 0x1F43B675
+	webmTimecodeID      = 0xE7
+	webmSimpleBlockID   = 0xA3
+	webmBlockGroupID    = 0xA0
+	webmBlockID         = 0xA1
+)
+
+// webmSegmentChildren are the elements of a segment, one of them ends a cluster of unknown size
+var webmSegmentChildren = map[uint64]bool{
+	0x114D9B74:    true, // SeekHead
+	webmInfoID:    true,
+	webmTracksID:  true,
+	webmClusterID: true,
+	0x1C53BB6B:    true, // Cues
+	0x1043A770:    true, // Chapters
+	0x1254C367:    true, // Tags
+	0x1941A469:    true, // Attachments
+}
+
+// ebmlElement is an element of a WebM, offset and size are those of its payload. The size is -1 when unknown.
+type ebmlElement struct {
+	id     uint64
+	offset int64
+	size   int64
+}
+
+func (el ebmlElement) end() int64 {
+	return el.offset + el.size
+}
+
+// ebmlReader reads the elements of a WebM, and counts them
+type ebmlReader struct {
+	r     io.ReaderAt
+	items int
+}
+
+// element reads the header of the element at offset, a known size has to fit before end
+func (e *ebmlReader) element(offset int64, end int64) (ebmlElement, error) {
+	e.items++
+	if e.items > maxContainerItems {
+		return ebmlElement{}, errInvalidVideo
+	}
+	header := make([]byte, 12)
+	n, err := e.r.ReadAt(header[:minInt64(12, end-offset)], offset)
+	if n == 0 && err != nil {
+		return ebmlElement{}, errTruncatedVideo
+	}
+	header = header[:n]
+	id, idLength, _, ok := ebmlVint(header, 4)
+	if !ok {
+		return ebmlElement{}, errTruncatedVideo
+	}
+	// the id keeps its length marker
+	id |= 1 << (7 * uint(idLength))
+	size, sizeLength, unknown, ok := ebmlVint(header[idLength:], 8)
+	if !ok {
+		return ebmlElement{}, errTruncatedVideo
+	}
+	el := ebmlElement{id: id, offset: offset + int64(idLength+sizeLength), size: int64(size)}
+	if el.offset > end {
+		return ebmlElement{}, errTruncatedVideo
+	}
+	if unknown {
+		el.size = -1
+		return el, nil
+	}
+	if size > uint64(end-el.offset) {
+		return ebmlElement{}, errTruncatedVideo
+	}
+	return el, nil
+}
+
+// ebmlVint reads a variable size integer of at most maxLength bytes, without its length marker.
+// A value with all its bits set means unknown.
+func ebmlVint(data []byte, maxLength int) (uint64, int, bool, bool) {
+	if len(data) == 0 || data[0] == 0 {
+		return 0, 0, false, false
+	}
+	length := 1
+	for data[0]&(0x80>>uint(length-1)) == 0 {
+		length++
+	}
+	if length > maxLength || len(data) < length {
+		return 0, 0, false, false
+	}
+	value := uint64(data[0] & (0xFF >> uint(length)))
+	for _, b := range data[1:length] {
+		value = value<<8 | uint64(b)
+	}
+	return value, length, value == 1<<(7*uint(length))-1, true
+}
+
+// children calls fn with each element of the parent, they all have a known size
+func (e *ebmlReader) children(parent ebmlElement, fn func(ebmlElement) error) error {
+	for offset := parent.offset; offset < parent.end(); {
+		el, err := e.element(offset, parent.end())
+		if err != nil {
+			return err
+		}
+		if el.size < 0 {
+			return errInvalidVideo
+		}
+		if err := fn(el); err != nil {
+			return err
+		}
+		offset = el.end()
+	}
+	return nil
+}
+
+func (e *ebmlReader) read(el ebmlElement, maxSize int64) ([]byte, error) {
+	if el.size > maxSize {
+		return nil, errInvalidVideo
+	}
+	data := make([]byte, el.size)
+	if _, err := e.r.ReadAt(data, el.offset); err != nil {
+		return nil, errTruncatedVideo
+	}
+	return data, nil
+}
+
+func (e *ebmlReader) uint(el ebmlElement) (uint64, error) {
+	data, err := e.read(el, 8)
+	if err != nil {
+		return 0, err
+	}
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value, nil
+}
+
+func (e *ebmlReader) float(el ebmlElement) (float64, error) {
+	data, err := e.read(el, 8)
+	if err != nil {
+		return 0, err
+	}
+	switch len(data) {
+	case 0:
+		return 0, nil
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	}
+	return 0, errInvalidVideo
+}
+
+func (e *ebmlReader) string(el ebmlElement) (string, error) {
+	data, err := e.read(el, 64)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\x00"), nil
+}
+
+// probeWebM checks the EBML header is the one of a WebM, and reads the info and the tracks of its segment.
+// A WebM recorded by a browser tells no duration, it is then the time of its last block.
+func probeWebM(r io.ReaderAt, size int64) (*videoInfo, error) {
+	e := &ebmlReader{r: r}
+	header, err := e.element(0, size)
+	if err != nil {
+		return nil, err
+	}
+	if header.id != ebmlHeaderID || header.size < 0 {
+		return nil, errInvalidVideo
+	}
+	docType := ""
+	err = e.children(header, func(el ebmlElement) error {
+		var err error
+		if el.id == ebmlDocTypeID {
+			docType, err = e.string(el)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if docType != "webm" {
+		return nil, errInvalidVideo
+	}
+	segment, err := e.element(header.end(), size)
+	if err != nil {
+		return nil, err
+	}
+	if segment.id != webmSegmentID {
+		return nil, errInvalidVideo
+	}
+	if segment.size < 0 {
+		segment.size = size - segment.offset
+	}
+
+	info := &videoInfo{contentType: "video/webm"}
+	timecodeScale := uint64(1000000)
+	var duration float64
+	var lastTimecode int64
+	clusters := 0
+	for offset := segment.offset; offset < segment.end(); {
+		el, err := e.element(offset, segment.end())
+		if err != nil {
+			return nil, err
+		}
+		if el.id == webmClusterID {
+			clusters++
+			end, timecode, err := e.cluster(el, segment.end())
+			if err != nil {
+				return nil, err
+			}
+			if timecode > lastTimecode {
+				lastTimecode = timecode
+			}
+			offset = end
+			continue
+		}
+		if el.size < 0 {
+			return nil, errInvalidVideo
+		}
+		switch el.id {
+		case webmInfoID:
+			err = e.children(el, func(child ebmlElement) error {
+				var err error
+				switch child.id {
+				case webmTimecodeScaleID:
+					timecodeScale, err = e.uint(child)
+				case webmDurationID:
+					duration, err = e.float(child)
+				}
+				return err
+			})
+		case webmTracksID:
+			err = e.children(el, func(child ebmlElement) error {
+				if child.id != webmTrackEntryID || info.width > 0 {
+					return nil
+				}
+				var err error
+				info.width, info.height, err = e.track(child)
+				return err
+			})
+		}
+		if err != nil {
+			return nil, err
+		}
+		offset = el.end()
+	}
+	if info.width == 0 || info.height == 0 {
+		return nil, errNoVideoTrack
+	}
+	if clusters == 0 || timecodeScale == 0 {
+		return nil, errInvalidVideo
+	}
+	if duration <= 0 || math.IsNaN(duration) || math.IsInf(duration, 0) {
+		duration = float64(lastTimecode)
+	}
+	if duration <= 0 {
+		return nil, errVideoDuration
+	}
+	info.duration = mediaDuration(duration * float64(timecodeScale) / float64(time.Second))
+	return info, nil
+}
+
+// track returns the size of a video track, zero for the other tracks
+func (e *ebmlReader) track(entry ebmlElement) (int, int, error) {
+	var trackType, width, height uint64
+	codec := ""
+	err := e.children(entry, func(el ebmlElement) error {
+		var err error
+		switch el.id {
+		case webmTrackTypeID:
+			trackType, err = e.uint(el)
+		case webmCodecID:
+			codec, err = e.string(el)
+		case webmVideoID:
+			err = e.children(el, func(child ebmlElement) error {
+				var err error
+				switch child.id {
+				case webmPixelWidthID:
+					width, err = e.uint(child)
+				case webmPixelHeightID:
+					height, err = e.uint(child)
+				}
+				return err
+			})
+		}
+		return err
+	})
+	if err != nil || trackType != 1 {
+		return 0, 0, err
+	}
+	if !webmCodecs[codec] {
+		return 0, 0, errors.New("the codec of the video is not supported, it should be VP8, VP9 or AV1")
+	}
+	if width > math.MaxInt32 || height > math.MaxInt32 {
+		return 0, 0, errInvalidVideo
+	}
+	return int(width), int(height), nil
+}
+
+// cluster reads the timecodes of the blocks of a cluster, and returns where it ends with the time of its last block.
+// A cluster of unknown size ends where the next element of the segment starts.
+func (e *ebmlReader) cluster(cluster ebmlElement, end int64) (int64, int64, error) {
+	if cluster.size >= 0 {
+		end = cluster.end()
+	}
+	var timecode, lastBlock int64
+	offset := cluster.offset
+	for offset < end {
+		el, err := e.element(offset, end)
+		if err != nil {
+			return 0, 0, err
+		}
+		if cluster.size < 0 && webmSegmentChildren[el.id] {
+			break
+		}
+		if el.size < 0 {
+			return 0, 0, errInvalidVideo
+		}
+		switch el.id {
+		case webmTimecodeID:
+			value, err := e.uint(el)
+			if err != nil {
+				return 0, 0, err
+			}
+			timecode = int64(value & math.MaxInt32)
+		case webmSimpleBlockID:
+			block, err := e.blockTimecode(el)
+			if err != nil {
+				return 0, 0, err
+			}
+			if block > lastBlock {
+				lastBlock = block
+			}
+		case webmBlockGroupID:
+			err := e.children(el, func(child ebmlElement) error {
+				if child.id != webmBlockID {
+					return nil
+				}
+				block, err := e.blockTimecode(child)
+				if block > lastBlock {
+					lastBlock = block
+				}
+				return err
+			})
+			if err != nil {
+				return 0, 0, err
+			}
+		}
+		offset = el.end()
+	}
+	return offset, timecode + lastBlock, nil
+}
+
+// blockTimecode reads the time of a block relative to its cluster, after its track number
+func (e *ebmlReader) blockTimecode(block ebmlElement) (int64, error) {
+	header := make([]byte, minInt64(10, block.size))
+	if _, err := e.r.ReadAt(header, block.offset); err != nil {
+		return 0, errTruncatedVideo
+	}
+	_, length, _, ok := ebmlVint(header, 8)
+	if !ok || len(header) < length+2 {
+		return 0, errInvalidVideo
+	}
+	return int64(int16(binary.BigEndian.Uint16(header[length:]))), nil
+}
+
+// mediaDuration converts seconds to a duration, a duration too long for it is clamped
+func mediaDuration(seconds float64) time.Duration {
+	if seconds*float64(time.Second) >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func minInt64(a int64, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
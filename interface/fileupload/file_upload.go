@@ -2,30 +2,65 @@ package fileupload
 
 import (
 	"bytes"
-	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/scanner"
+	"learning-golang-ddd/infrastructure/storage"
+	"learning-golang-ddd/infrastructure/video"
 	"log"
 	"mime/multipart"
-	"net/http"
-	"os"
 	"strings"
-
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
+	"time"
 )
 
 type UploadFileInterface interface {
 	UploadFile(*multipart.FileHeader) (string, error)
+	UploadFromURL(string) (string, error)
+	DeleteFile(string) error
+	SaveFile(path string, r io.Reader, size int64, contentType string) error
+	GetFile(string) (io.ReadCloser, error)
+	URL(string) string
+	PresignUpload(key string, contentType string, expires time.Duration) (string, error)
+	ProcessUpload(key string, contentType string, maxSize int64) (*entity.UploadedMedia, error)
+	AssembleUpload(key string, chunks []string, size int64, contentType string) error
+	ListFiles(prefix string, fn func(storage.ObjectInfo) error) error
+	SyncImageACL(paths ...string) error
+	MediaURL(filePath string) string
+}
+
+// ImageRefs tells how many foods, gallery images, revisions and uploads still use an image,
+// and whether it can be public. An image handed out is held until its food is saved, under a lock DeleteFile takes too.
+type ImageRefs interface {
+	CountImageRefs(path string) (int64, error)
+	IsImageVisibleTo(path string, userId uint64) (bool, error)
+	LockImage(key string, fn func() error) error
+	HoldImage(path string, until time.Time) error
 }
 
-type fileUpload struct{}
+var (
+	// ErrScanUnavailable is returned when the scanner cannot be reached, the file may be sent again later
+	ErrScanUnavailable = errors.New("the file could not be scanned, please try again later")
+	errInfectedFile    = errors.New("the file was rejected by the malware scan")
+)
+
+type fileUpload struct {
+	st     storage.Storage
+	refs   ImageRefs
+	scan   scanner.Scanner
+	limits Limits
+	frames video.FrameExtractor
+}
 
 // So waht is exposed is Uploader
 var _ UploadFileInterface = &fileUpload{}
 
-func NewFileUpload() *fileUpload {
-	return &fileUpload{}
+func NewFileUpload(st storage.Storage, refs ImageRefs, scan scanner.Scanner, limits Limits, frames video.FrameExtractor) *fileUpload {
+	return &fileUpload{st: st, refs: refs, scan: scan, limits: limits, frames: frames}
 }
 
 func (fu *fileUpload) UploadFile(file *multipart.FileHeader) (string, error) {
@@ -35,50 +70,306 @@ func (fu *fileUpload) UploadFile(file *multipart.FileHeader) (string, error) {
 	}
 	defer f.Close()
 
-	size := file.Size
-	// the image should not be more than 500KB
-	fmt.Println("the size: ", size)
-	if size > int64(512000) {
-		return "", errors.New("sorry, please upload an Image of 500KB or less")
-	}
-
-	// only the first 512 bytes ar used to sniff the content type of a file,
-	// so, so no need to read the entire bytes of a file
-	buffer := make([]byte, size)
-	f.Read(buffer)
-	fileType := http.DetectContentType(buffer)
-	// if the imiage is valid
-	if !strings.HasPrefix(fileType, "image") {
-		return "", errors.New("please upload a valid image")
-	}
-
-	filePath := FormatFile(file.Filename)
-	accessKey := os.Getenv("DO_SPACES_KEY")
-	secKey := os.Getenv("DO_SPACES_SECRET")
-	endpoint := os.Getenv("DO_SPACES_ENDPOINT")
-	ssl := true
-
-	// initiate a client using DigitalOcean spaces.
-	client, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(accessKey, secKey, ""),
-		Secure: ssl,
+	// the size of the form is what the client claims, the file itself is read to its end and checked again
+	if file.Size > fu.limits.MaxFileSize {
+		return "", fu.tooLarge()
+	}
+	buffer, err := ioutil.ReadAll(io.LimitReader(f, fu.limits.MaxFileSize+1))
+	if err != nil {
+		return "", errors.New("cannot read file")
+	}
+	if int64(len(buffer)) > fu.limits.MaxFileSize {
+		return "", fu.tooLarge()
+	}
+	return fu.upload(buffer, "")
+}
+
+func (fu *fileUpload) tooLarge() error {
+	return fmt.Errorf("sorry, please upload an Image of %dKB or less", fu.limits.MaxFileSize>>10)
+}
+
+// upload checks that the file is an image, of contentType when given, and stores each of its variants in each format,
+// the url of the default rendition is returned. The image is decoded and encoded again, nothing else of the file is kept.
+// The renditions are stored under the SHA-256 of the file, so the same image uploaded again is stored once, see hold.
+// Nothing is made public before the file is scanned clean: until then it is only in memory, or private under incoming/.
+func (fu *fileUpload) upload(buffer []byte, contentType string) (string, error) {
+	info, err := fu.limits.checkImage(buffer)
+	if err != nil {
+		return "", err
+	}
+	if contentType != "" && !strings.EqualFold(info.contentType, contentType) {
+		return "", fmt.Errorf("the file is not a valid %s", contentType)
+	}
+	hash := sha256.Sum256(buffer)
+	if err := fu.scanFile(bytes.NewReader(buffer), hex.EncodeToString(hash[:])); err != nil {
+		return "", err
+	}
+	width, height := info.width, info.height
+	dir := entity.ImageSetDir(hex.EncodeToString(hash[:]), width, height)
+	// the default rendition is written last, once it is there so are the others
+	found, err := fu.hold(entity.DefaultRenditionKey(dir))
+	if err != nil {
+		return "", errors.New("something went wrong")
+	}
+	if found {
+		return fu.st.URL(entity.DefaultRenditionKey(dir)), nil
+	}
+	img, err := decodeImage(buffer)
+	if err != nil {
+		return "", err
+	}
+	stored := []string{}
+	for _, variant := range entity.ImageVariants {
+		variantWidth, variantHeight := variant.Size(width, height)
+		resized := resize(img, variantWidth, variantHeight)
+		for _, format := range entity.ImageFormats {
+			key := entity.RenditionKey(dir, variant, format)
+			var encoded bytes.Buffer
+			err := encodeImage(&encoded, resized, format.Name)
+			if err == nil {
+				// the image stays private until a published food uses it, see SyncImageACL.
+				// It is cached a day only, an image made private again should not be kept long by the caches.
+				err = fu.st.Put(key, bytes.NewReader(encoded.Bytes()), int64(encoded.Len()), storage.PutOptions{
+					ContentType:  format.ContentType,
+					CacheControl: "max-age=86400",
+				})
+			}
+			if err != nil {
+				log.Printf("cannot store image %s: %v", key, err)
+				// an image is stored with all its renditions or not at all
+				for _, storedKey := range stored {
+					fu.st.Delete(storedKey)
+				}
+				return "", errors.New("something went wrong")
+			}
+			stored = append(stored, key)
+		}
+	}
+	return fu.st.URL(entity.DefaultRenditionKey(dir)), nil
+}
+
+// hold keeps the file stored under key from being deleted until the food using it is saved,
+// and tells whether it is stored already. DeleteFile takes the same lock: a file it is deleting is not found here,
+// and a file found here is held before DeleteFile counts its references.
+func (fu *fileUpload) hold(key string) (bool, error) {
+	found := false
+	err := fu.refs.LockImage(key, func() error {
+		if err := fu.refs.HoldImage(fu.st.URL(key), time.Now().Add(entity.ImageHoldTTL)); err != nil {
+			return err
+		}
+		_, err := fu.st.Stat(key)
+		if err == storage.ErrNotFound {
+			return nil
+		}
+		found = err == nil
+		return err
 	})
 	if err != nil {
-		log.Fatal(err)
-	}
-	fileBytes := bytes.NewReader(buffer)
-	cacheControl := "max-age=31536000"
-	// make it public
-	userMetadata := map[string]string{"x-amz-acl": "public-read"}
-	n, err := client.PutObject(context.Background(), "chodapi", filePath, fileBytes, size, minio.PutObjectOptions{
-		ContentType:  fileType,
-		CacheControl: cacheControl,
-		UserMetadata: userMetadata,
+		log.Printf("cannot hold file %s: %v", key, err)
+	}
+	return found, err
+}
+
+// scanFile rejects a file the scanner finds malware in. A file that cannot be scanned is rejected too.
+// The file is logged by its SHA-256 hash.
+func (fu *fileUpload) scanFile(r io.Reader, hash string) error {
+	found, err := fu.scan.Scan(r)
+	if err != nil {
+		log.Printf("cannot scan file: %v", err)
+		return ErrScanUnavailable
+	}
+	if found != "" {
+		log.Printf("rejected infected file %s: %s", hash, found)
+		return errInfectedFile
+	}
+	return nil
+}
+
+// PresignUpload returns the url the client uploads the file to, straight to the storage
+func (fu *fileUpload) PresignUpload(key string, contentType string, expires time.Duration) (string, error) {
+	return fu.st.PresignPut(key, contentType, expires)
+}
+
+// ProcessUpload checks the file the client uploaded with a presigned url and turns it into what the policy
+// of its content type says: an image, the same way as UploadFile, or a video with its poster.
+// The uploaded file itself is removed once it is processed.
+func (fu *fileUpload) ProcessUpload(key string, contentType string, maxSize int64) (*entity.UploadedMedia, error) {
+	info, err := fu.st.Stat(key)
+	if err == storage.ErrNotFound {
+		return nil, errors.New("the file was not uploaded yet")
+	}
+	if err != nil {
+		log.Printf("cannot stat upload %s: %v", key, err)
+		return nil, errors.New("something went wrong")
+	}
+	policy, ok := entity.UploadPolicyFor(contentType)
+	if !ok {
+		fu.st.Delete(key)
+		return nil, fmt.Errorf("files of type %s cannot be uploaded", contentType)
+	}
+	if maxSize > policy.MaxSize {
+		maxSize = policy.MaxSize
+	}
+	if info.Size > maxSize {
+		fu.st.Delete(key)
+		return nil, fmt.Errorf("the file is larger than the %d bytes announced", maxSize)
+	}
+	if !strings.EqualFold(info.ContentType, contentType) {
+		fu.st.Delete(key)
+		return nil, fmt.Errorf("the file should be uploaded as %s", contentType)
+	}
+	var media *entity.UploadedMedia
+	if policy.Kind == entity.MediaVideo {
+		media, err = fu.processVideo(key, contentType, info.Size, policy)
+	} else {
+		media, err = fu.processImage(key, contentType, maxSize)
+	}
+	if err == ErrScanUnavailable {
+		// the file stays in quarantine, the upload can be confirmed again once the scanner is back
+		return nil, err
+	}
+	if err != nil {
+		fu.st.Delete(key)
+		return nil, err
+	}
+	if err := fu.st.Delete(key); err != nil {
+		log.Printf("cannot delete upload %s: %v", key, err)
+	}
+	return media, nil
+}
+
+func (fu *fileUpload) processImage(key string, contentType string, maxSize int64) (*entity.UploadedMedia, error) {
+	file, err := fu.st.Get(key)
+	if err != nil {
+		log.Printf("cannot read upload %s: %v", key, err)
+		return nil, errors.New("something went wrong")
+	}
+	buffer, err := ioutil.ReadAll(io.LimitReader(file, maxSize))
+	file.Close()
+	if err != nil {
+		log.Printf("cannot read upload %s: %v", key, err)
+		return nil, errors.New("something went wrong")
+	}
+	imagePath, err := fu.upload(buffer, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.UploadedMedia{ImagePath: imagePath}, nil
+}
+
+// AssembleUpload joins the chunks of a resumable upload into a single private file under key,
+// ready for ProcessUpload. The chunks are removed once joined.
+func (fu *fileUpload) AssembleUpload(key string, chunks []string, size int64, contentType string) error {
+	if info, err := fu.st.Stat(key); err == nil && info.Size == size {
+		// the chunks were joined already, e.g the file could not be scanned the first time
+		return nil
+	}
+	readers := make([]io.Reader, 0, len(chunks))
+	for _, chunk := range chunks {
+		file, err := fu.st.Get(chunk)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		readers = append(readers, file)
+	}
+	if err := fu.st.Put(key, io.MultiReader(readers...), size, storage.PutOptions{ContentType: contentType}); err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if err := fu.st.Delete(chunk); err != nil {
+			log.Printf("cannot delete chunk %s: %v", chunk, err)
+		}
+	}
+	return nil
+}
+
+// DeleteFile removes an uploaded file. The path may be the url of the file, as it is when saved with a food.
+// The renditions of an image are removed together, once nothing uses the image anymore:
+// the same image can be used by several foods, it is kept until the last of them lets it go.
+// An image or a video handed out again a moment ago is held, it is kept too, see hold.
+func (fu *fileUpload) DeleteFile(filePath string) error {
+	return fu.refs.LockImage(entity.ImageSetKey(fu.key(filePath)), func() error {
+		refs, err := fu.refs.CountImageRefs(filePath)
+		if err != nil {
+			return err
+		}
+		if refs > 0 {
+			return nil
+		}
+		var failed error
+		for _, url := range entity.RenditionURLs(filePath) {
+			if err := fu.st.Delete(fu.key(url)); err != nil {
+				failed = err
+			}
+		}
+		return failed
 	})
+}
+
+// SyncImageACL makes each image public when a published food uses it, private otherwise.
+// The default rendition is set last, an image it is already right for is left alone.
+func (fu *fileUpload) SyncImageACL(paths ...string) error {
+	var failed error
+	for _, filePath := range paths {
+		if filePath == "" {
+			continue
+		}
+		if err := fu.syncImageACL(filePath); err != nil {
+			log.Printf("cannot set the acl of image %s: %v", filePath, err)
+			failed = err
+		}
+	}
+	return failed
+}
+
+func (fu *fileUpload) syncImageACL(filePath string) error {
+	public, err := fu.refs.IsImageVisibleTo(filePath, 0)
 	if err != nil {
-		fmt.Println("the error", err)
-		return "", errors.New("something went wrong")
+		return err
+	}
+	info, err := fu.st.Stat(fu.key(filePath))
+	if err == storage.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Public == public {
+		return nil
+	}
+	// the default rendition comes last
+	for _, url := range entity.RenditionURLs(filePath) {
+		err := fu.st.SetPublic(fu.key(url), public)
+		if err != nil && err != storage.ErrNotFound {
+			return err
+		}
 	}
-	fmt.Println("succes fully uploaded bytes:", n)
-	return filePath, nil
+	return nil
+}
+
+// SaveFile stores a private file, e.g a data export, under the given path.
+// Unlike the images, the file is not readable by the public.
+func (fu *fileUpload) SaveFile(filePath string, r io.Reader, size int64, contentType string) error {
+	return fu.st.Put(filePath, r, size, storage.PutOptions{ContentType: contentType})
+}
+
+// GetFile reads a stored file. The path may be the url of the file, like in DeleteFile.
+func (fu *fileUpload) GetFile(filePath string) (io.ReadCloser, error) {
+	return fu.st.Get(fu.key(filePath))
+}
+
+// ListFiles calls fn with each stored file whose key starts with prefix
+func (fu *fileUpload) ListFiles(prefix string, fn func(storage.ObjectInfo) error) error {
+	return fu.st.List(prefix, fn)
+}
+
+// URL is the public url of the file stored under the key
+func (fu *fileUpload) URL(key string) string {
+	return fu.st.URL(key)
+}
+
+// key returns the storage key of a file from its url
+func (fu *fileUpload) key(filePath string) string {
+	return strings.TrimPrefix(filePath, fu.st.URL(""))
 }
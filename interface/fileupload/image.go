@@ -0,0 +1,254 @@
+package fileupload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"os"
+	"strconv"
+
+	// the formats an upload can be in
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const jpegQuality = 82
+
+// imageFormats are the formats an upload can be in, by the name their decoder is registered with.
+// Any other format, e.g one registered by another package, is refused.
+var imageFormats = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+}
+
+// Limits bound the images that can be uploaded
+type Limits struct {
+	// MaxFileSize is the most an image sent with a form, or downloaded from an url, can weigh
+	MaxFileSize int64
+	// MaxSide and MaxPixels bound the size an image claims, a small file can claim a huge size
+	MaxSide   int
+	MaxPixels int64
+}
+
+func DefaultLimits() Limits {
+	return Limits{
+		MaxFileSize: 500 << 10,
+		MaxSide:     8000,
+		MaxPixels:   40000000,
+	}
+}
+
+// LimitsFromEnv reads IMAGE_MAX_FILE_SIZE, IMAGE_MAX_SIDE and IMAGE_MAX_PIXELS, the default limit is kept for those not set
+func LimitsFromEnv() (Limits, error) {
+	limits := DefaultLimits()
+	for name, limit := range map[string]*int64{
+		"IMAGE_MAX_FILE_SIZE": &limits.MaxFileSize,
+		"IMAGE_MAX_PIXELS":    &limits.MaxPixels,
+	} {
+		if value := os.Getenv(name); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n <= 0 {
+				return limits, fmt.Errorf("invalid %s %q, should be a positive number", name, value)
+			}
+			*limit = n
+		}
+	}
+	if value := os.Getenv("IMAGE_MAX_SIDE"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return limits, fmt.Errorf("invalid IMAGE_MAX_SIDE %q, should be a positive number", value)
+		}
+		limits.MaxSide = n
+	}
+	return limits, nil
+}
+
+// imageInfo is what an image is, from its header
+type imageInfo struct {
+	contentType string
+	width       int
+	height      int
+}
+
+// checkImage makes sure the file is an image in one of the allowed formats, small enough to be decoded.
+// Only the header is read, the size is the size of the image turned the right way up.
+func (l Limits) checkImage(buffer []byte) (*imageInfo, error) {
+	if isSVG(buffer) {
+		// an SVG can carry scripts, it is not accepted at all
+		return nil, errors.New("SVG images are not accepted, please upload a JPEG, PNG, GIF or WebP image")
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(buffer))
+	contentType, ok := imageFormats[format]
+	if err != nil || !ok || config.Width <= 0 || config.Height <= 0 {
+		return nil, errors.New("please upload a valid image, in JPEG, PNG, GIF or WebP")
+	}
+	if config.Width > l.MaxSide || config.Height > l.MaxSide || int64(config.Width)*int64(config.Height) > l.MaxPixels {
+		return nil, fmt.Errorf("the image is too large, it should be at most %d pixels wide and high, and %d pixels in all", l.MaxSide, l.MaxPixels)
+	}
+	info := &imageInfo{contentType: contentType, width: config.Width, height: config.Height}
+	// the orientations from 5 turn the image a quarter
+	if format == "jpeg" && jpegOrientation(buffer) >= 5 {
+		info.width, info.height = config.Height, config.Width
+	}
+	return info, nil
+}
+
+// isSVG tells whether the file is an SVG, or any XML that could hold one
+func isSVG(buffer []byte) bool {
+	head := buffer
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if !bytes.HasPrefix(head, []byte("<")) {
+		return false
+	}
+	head = bytes.ToLower(head)
+	return bytes.HasPrefix(head, []byte("<?xml")) || bytes.Contains(head, []byte("<svg"))
+}
+
+// decodeImage decodes an upload and turns it the right way up. Only the pixels are kept,
+// the EXIF data, e.g the GPS position of a phone picture, is dropped with the rest of the metadata.
+func decodeImage(buffer []byte) (image.Image, error) {
+	img, format, err := image.Decode(bytes.NewReader(buffer))
+	if _, ok := imageFormats[format]; err != nil || !ok {
+		return nil, errors.New("please upload a valid image, in JPEG, PNG, GIF or WebP")
+	}
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(buffer))
+	}
+	return img, nil
+}
+
+// resize scales the image down to the given size
+func resize(img image.Image, width int, height int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() == width && bounds.Dy() == height {
+		return img
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+func encodeImage(w io.Writer, img image.Image, format string) error {
+	switch format {
+	case "webp":
+		return nativewebp.Encode(w, img, nil)
+	case "jpeg":
+		// jpeg has no transparency, the transparent parts become white
+		bounds := img.Bounds()
+		flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+		return jpeg.Encode(w, flat, &jpeg.Options{Quality: jpegQuality})
+	}
+	return errors.New("unknown image format " + format)
+}
+
+// jpegOrientation reads the orientation tag of the EXIF data of a jpeg, 1 when there is none
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		// the image data starts, the EXIF data comes before it
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// orient applies the EXIF orientation, so the image no longer depends on it
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dw, dh := w, h
+	// 5 to 8 are turned a quarter
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,41 @@
+package fileupload
+
+import (
+	"fmt"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/security"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// MediaURLTTL is how long a signed media url can be used, at least
+const MediaURLTTL = time.Hour
+
+// the private images are served by the api under this path, see MediaURL
+const mediaPath = "/media/"
+
+// MediaURL returns the signed url of a stored image, it can be read with it until it expires, public or not.
+// The signature is made for the whole image, so its query is valid for every rendition, see entity.NewImageSet.
+// The expiry is rounded, the url of an image stays the same for a while and can be cached by the browsers.
+func (fu *fileUpload) MediaURL(filePath string) string {
+	key := fu.key(filePath)
+	expiresAt := strconv.FormatInt(time.Now().Truncate(MediaURLTTL).Add(2*MediaURLTTL).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expiresAt)
+	query.Set("signature", security.Sign(mediaMessage(key, expiresAt)))
+	return mediaPath + key + "?" + query.Encode()
+}
+
+// VerifyMediaURL checks the query of an url made by MediaURL, it fails once the url expired
+func VerifyMediaURL(key string, query url.Values, now time.Time) bool {
+	expiresAt, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || now.Unix() > expiresAt {
+		return false
+	}
+	return security.VerifySignature(mediaMessage(key, query.Get("expires")), query.Get("signature"))
+}
+
+func mediaMessage(key string, expiresAt string) string {
+	return fmt.Sprintf("media:%s:%s", entity.ImageSetKey(key), expiresAt)
+}
@@ -0,0 +1,93 @@
+package fileupload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// the addresses a remote image can not be downloaded from, so an url can not be used
+// to reach the services in our own network
+var blockedNetworks = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+}
+
+var remoteClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		// no proxy, the dialer below must see the address the image is actually fetched from
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(network, address string, c syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if isBlockedIP(net.ParseIP(host)) {
+					return fmt.Errorf("%s is not a public address", host)
+				}
+				return nil
+			},
+		}).DialContext,
+	},
+}
+
+func isBlockedIP(ip net.IP) bool {
+	if ip == nil {
+		return true
+	}
+	for _, cidr := range blockedNetworks {
+		_, network, _ := net.ParseCIDR(cidr)
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadFromURL downloads an image and uploads it the same way as UploadFile does
+func (fu *fileUpload) UploadFromURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errors.New("the image url should be a valid http or https url")
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", errors.New("the image url should be a valid http or https url")
+	}
+	resp, err := remoteClient.Do(req)
+	if err != nil {
+		return "", errors.New("cannot download the image")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cannot download the image, got status %d", resp.StatusCode)
+	}
+
+	// read one byte more than allowed, to know when the image is too large
+	buffer, err := ioutil.ReadAll(io.LimitReader(resp.Body, fu.limits.MaxFileSize+1))
+	if err != nil {
+		return "", errors.New("cannot download the image")
+	}
+	if int64(len(buffer)) > fu.limits.MaxFileSize {
+		return "", fu.tooLarge()
+	}
+	return fu.upload(buffer, "")
+}
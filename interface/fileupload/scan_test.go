@@ -0,0 +1,42 @@
+package fileupload
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+type stubScanner struct {
+	found string
+	err   error
+}
+
+func (s stubScanner) Scan(r io.Reader) (string, error) {
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return "", err
+	}
+	return s.found, s.err
+}
+
+func TestFileUpload_scanFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		scanner stubScanner
+		want    error
+	}{
+		{name: "clean", scanner: stubScanner{}},
+		{name: "infected", scanner: stubScanner{found: "Eicar-Signature"}, want: errInfectedFile},
+		{name: "scanner unreachable", scanner: stubScanner{err: errors.New("connection refused")}, want: ErrScanUnavailable},
+		{name: "scanner error reply", scanner: stubScanner{err: errors.New("clamd: INSTREAM size limit exceeded. ERROR")}, want: ErrScanUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fu := &fileUpload{scan: tt.scanner}
+			if err := fu.scanFile(strings.NewReader("file"), "hash"); err != tt.want {
+				t.Errorf("scanFile() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
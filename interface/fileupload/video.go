@@ -0,0 +1,132 @@
+package fileupload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/storage"
+	"log"
+	"os"
+	"time"
+)
+
+// the videos are stored under this prefix, by their SHA-256 hash
+const clipsPrefix = "clips/"
+
+// the extensions of the stored videos, by content type
+var videoExtensions = map[string]string{
+	"video/mp4":  ".mp4",
+	"video/webm": ".webm",
+}
+
+// posterTime is when the poster of a video is taken, a bit after its start which is often black
+const posterTime = time.Second
+
+// processVideo checks the video uploaded under key and stores it privately, with a poster taken from one of its frames.
+// A video is too large to be read in memory, it is copied to a temporary file to be checked.
+// Like the images, the same video uploaded again is stored once.
+func (fu *fileUpload) processVideo(key string, contentType string, size int64, policy entity.UploadPolicy) (*entity.UploadedMedia, error) {
+	file, err := fu.st.Get(key)
+	if err != nil {
+		log.Printf("cannot read upload %s: %v", key, err)
+		return nil, errors.New("something went wrong")
+	}
+	tmp, err := ioutil.TempFile("", "clip-*"+videoExtensions[contentType])
+	if err != nil {
+		file.Close()
+		log.Printf("cannot create temporary file: %v", err)
+		return nil, errors.New("something went wrong")
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	hasher := sha256.New()
+	copied, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(file, size))
+	file.Close()
+	if err != nil || copied != size {
+		log.Printf("cannot read upload %s: %v", key, err)
+		return nil, errors.New("something went wrong")
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	info, err := probeVideo(tmp, size)
+	if err != nil {
+		return nil, err
+	}
+	if info.contentType != contentType {
+		return nil, fmt.Errorf("the file is not a valid %s", contentType)
+	}
+	if info.duration > policy.MaxDuration {
+		return nil, fmt.Errorf("sorry, please upload a video of %s or less", policy.MaxDuration)
+	}
+	// its poster is an image, bound by the same limits
+	if info.width > fu.limits.MaxSide || info.height > fu.limits.MaxSide || int64(info.width)*int64(info.height) > fu.limits.MaxPixels {
+		return nil, fmt.Errorf("the video should be at most %dx%d", fu.limits.MaxSide, fu.limits.MaxSide)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		log.Printf("cannot read temporary file %s: %v", tmp.Name(), err)
+		return nil, errors.New("something went wrong")
+	}
+	if err := fu.scanFile(tmp, hash); err != nil {
+		return nil, err
+	}
+	posterPath, err := fu.poster(tmp.Name(), info.duration)
+	if err != nil {
+		return nil, err
+	}
+
+	videoKey := clipsPrefix + hash + videoExtensions[contentType]
+	// like an image, a video stored before is not written again, see upload
+	found, err := fu.hold(videoKey)
+	if err != nil {
+		return nil, errors.New("something went wrong")
+	}
+	if !found {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			log.Printf("cannot read temporary file %s: %v", tmp.Name(), err)
+			return nil, errors.New("something went wrong")
+		}
+		// the video stays private until a published food uses it, see SyncImageACL
+		err = fu.st.Put(videoKey, tmp, size, storage.PutOptions{
+			ContentType:  contentType,
+			CacheControl: "max-age=86400",
+		})
+		if err != nil {
+			log.Printf("cannot store video %s: %v", videoKey, err)
+			return nil, errors.New("something went wrong")
+		}
+	}
+	return &entity.UploadedMedia{
+		ImagePath: posterPath,
+		VideoPath: fu.st.URL(videoKey),
+		Duration:  info.duration,
+		Width:     info.width,
+		Height:    info.height,
+	}, nil
+}
+
+// poster takes a frame of the video and stores it as an image, with all its renditions.
+// The video is refused when no frame can be decoded from it. It has no poster when there is no frame extractor.
+func (fu *fileUpload) poster(videoPath string, duration time.Duration) (string, error) {
+	at := posterTime
+	if duration/2 < at {
+		at = duration / 2
+	}
+	frame, err := fu.frames.Frame(videoPath, at)
+	if err != nil {
+		log.Printf("cannot extract a frame of video %s: %v", videoPath, err)
+		return "", errors.New("the video cannot be decoded")
+	}
+	if frame == nil {
+		return "", nil
+	}
+	posterPath, err := fu.upload(frame, "")
+	if err != nil && err != ErrScanUnavailable && err != errInfectedFile {
+		log.Printf("cannot store the poster of video %s: %v", videoPath, err)
+		return "", errors.New("the video cannot be decoded")
+	}
+	return posterPath, err
+}
@@ -0,0 +1,123 @@
+package foodimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"learning-golang-ddd/domain/entity"
+	"strings"
+)
+
+// the columns a csv file can have, title and description are required
+var csvColumns = map[string]bool{
+	"title":       true,
+	"description": true,
+	"image":       true,
+	"ingredients": true,
+	"status":      true,
+	"publish_at":  true,
+}
+
+// Parse reads the foods of an import file. A row that cannot be read does not stop the parsing,
+// its errors are kept on the row so they are reported with the others.
+// An error is only returned when the file as a whole cannot be read, e.g a csv without a header.
+func Parse(format string, data []byte) ([]entity.ImportRow, error) {
+	switch format {
+	case entity.ImportFormatCSV:
+		return parseCSV(data)
+	case entity.ImportFormatNDJSON:
+		return parseNDJSON(data)
+	}
+	return nil, fmt.Errorf("the format should be %s or %s", entity.ImportFormatCSV, entity.ImportFormatNDJSON)
+}
+
+func parseCSV(data []byte) ([]entity.ImportRow, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("the csv file should start with a header")
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !csvColumns[name] {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"title", "description"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("the %s column is required", required)
+		}
+	}
+
+	rows := []entity.ImportRow{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		row := entity.ImportRow{Row: line}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			row.Errors = map[string]string{"invalid_row": parseErr.Err.Error()}
+			rows = append(rows, row)
+			continue
+		}
+		value := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		row.Title = value("title")
+		row.Description = value("description")
+		row.Image = value("image")
+		row.Status = value("status")
+		row.PublishAt = value("publish_at")
+		// the ingredients are a json array, the same as the ingredients form field of SaveFood
+		if raw := value("ingredients"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &row.Ingredients); err != nil {
+				row.Errors = map[string]string{"invalid_ingredients": "ingredients should be a valid json array"}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseNDJSON(data []byte) ([]entity.ImportRow, error) {
+	rows := []entity.ImportRow{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	// a single food can be larger than the default token size of 64KB
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		row := entity.ImportRow{}
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row); err != nil {
+			row = entity.ImportRow{Errors: map[string]string{"invalid_json": err.Error()}}
+		}
+		row.Row = line
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
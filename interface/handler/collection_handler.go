@@ -0,0 +1,358 @@
+package handler
+
+import (
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/auth"
+	"learning-golang-ddd/interface/fileupload"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CollectionHandler struct {
+	cAi  application.CollectionAppInterface
+	fvAi application.FavoriteAppInterface
+	fAi  application.FoodAppInterface
+	ai   auth.AuthInterface
+	ti   auth.TokenInterface
+	fui  fileupload.UploadFileInterface
+}
+
+// CollectionHandler constructor
+func NewCollectionHandler(
+	cAi application.CollectionAppInterface,
+	fvAi application.FavoriteAppInterface,
+	fAi application.FoodAppInterface,
+	ai auth.AuthInterface,
+	ti auth.TokenInterface,
+	fui fileupload.UploadFileInterface,
+) *CollectionHandler {
+	return &CollectionHandler{
+		cAi:  cAi,
+		fvAi: fvAi,
+		fAi:  fAi,
+		ai:   ai,
+		ti:   ti,
+		fui:  fui,
+	}
+}
+
+func (h *CollectionHandler) SaveFavorite(c *gin.Context) {
+	// check is the user is authenticated first
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	// lookup the metadata in redis:
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil || !food.IsVisibleTo(uId) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+
+	favorite := entity.Favorite{
+		UserID:    uId,
+		FoodID:    foodId,
+		CreatedAt: time.Now(),
+	}
+	savedFavorite, saveErr := h.fvAi.SaveFavorite(&favorite)
+	if saveErr != nil {
+		c.JSON(http.StatusUnprocessableEntity, saveErr)
+		return
+	}
+	c.JSON(http.StatusCreated, savedFavorite)
+}
+
+func (h *CollectionHandler) GetFavorites(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	favorites, err := h.fvAi.GetFavoritesByUser(uId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	entity.HideFavoriteFoodsFrom(favorites, uId)
+	c.JSON(http.StatusOK, entity.SignFavorites(favorites, h.fui.MediaURL))
+}
+
+func (h *CollectionHandler) DeleteFavorite(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	err = h.fvAi.DeleteFavorite(uId, foodId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, "favorite deleted")
+}
+
+func (h *CollectionHandler) SaveCollection(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var collection entity.Collection
+	if err := c.ShouldBindJSON(&collection); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	collection.Prepare()
+	validateErr := collection.Validate()
+	if len(validateErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, validateErr)
+		return
+	}
+
+	newCollection := entity.Collection{
+		UserID:    uId,
+		Name:      collection.Name,
+		IsPublic:  collection.IsPublic,
+		CreatedAt: collection.CreatedAt,
+		UpdatedAt: collection.UpdatedAt,
+	}
+	savedCollection, saveErr := h.cAi.SaveCollection(&newCollection)
+	if saveErr != nil {
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	c.JSON(http.StatusCreated, savedCollection)
+}
+
+func (h *CollectionHandler) GetCollection(c *gin.Context) {
+	collectionId, err := strconv.ParseUint(c.Param("collection_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	collection, err := h.cAi.GetCollection(collectionId)
+	viewer := viewerId(c, h.ti, h.ai)
+	// a private collection is reported as missing, so its existence is not leaked
+	if err != nil || !collection.CanView(viewer) {
+		c.JSON(http.StatusNotFound, "collection not found")
+		return
+	}
+	collection.HideFoodsFrom(viewer)
+	c.JSON(http.StatusOK, collection.SignURLs(h.fui.MediaURL))
+}
+
+// GetUserCollections returns all the collections of the user when they are asking for their own,
+// and only the public ones otherwise
+func (h *CollectionHandler) GetUserCollections(c *gin.Context) {
+	uId, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	publicOnly := viewerId(c, h.ti, h.ai) != uId
+	collections, err := h.cAi.GetCollectionsByUser(uId, publicOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, collections)
+}
+
+func (h *CollectionHandler) UpdateCollection(c *gin.Context) {
+	collection, ok := h.ownCollection(c)
+	if !ok {
+		return
+	}
+
+	var input entity.Collection
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	input.Prepare()
+	validateErr := input.Validate()
+	if len(validateErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, validateErr)
+		return
+	}
+
+	collection.Name = input.Name
+	collection.IsPublic = input.IsPublic
+	collection.UpdatedAt = time.Now()
+	updatedCollection, updateErr := h.cAi.UpdateCollection(collection)
+	if updateErr != nil {
+		c.JSON(http.StatusInternalServerError, updateErr)
+		return
+	}
+	updatedCollection.HideFoodsFrom(updatedCollection.UserID)
+	c.JSON(http.StatusOK, updatedCollection.SignURLs(h.fui.MediaURL))
+}
+
+func (h *CollectionHandler) DeleteCollection(c *gin.Context) {
+	collection, ok := h.ownCollection(c)
+	if !ok {
+		return
+	}
+	err := h.cAi.DeleteCollection(collection.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, "collection deleted")
+}
+
+func (h *CollectionHandler) AddCollectionItem(c *gin.Context) {
+	collection, ok := h.ownCollection(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		FoodID uint64 `json:"food_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.FoodID == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "food_id is required",
+		})
+		return
+	}
+	food, err := h.fAi.GetFood(input.FoodID)
+	if err != nil || !food.IsVisibleTo(collection.UserID) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+
+	item := entity.CollectionItem{
+		CollectionID: collection.ID,
+		FoodID:       input.FoodID,
+		CreatedAt:    time.Now(),
+	}
+	savedItem, saveErr := h.cAi.AddCollectionItem(&item)
+	if saveErr != nil {
+		c.JSON(http.StatusUnprocessableEntity, saveErr)
+		return
+	}
+	c.JSON(http.StatusCreated, savedItem)
+}
+
+func (h *CollectionHandler) RemoveCollectionItem(c *gin.Context) {
+	collection, ok := h.ownCollection(c)
+	if !ok {
+		return
+	}
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	err = h.cAi.RemoveCollectionItem(collection.ID, foodId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, "food removed from collection")
+}
+
+// ReorderCollectionItems expects the ids of all the foods of the collection in their new order:
+// {"food_ids": [3, 1, 2]}
+func (h *CollectionHandler) ReorderCollectionItems(c *gin.Context) {
+	collection, ok := h.ownCollection(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		FoodIDs []uint64 `json:"food_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	reorderErr := h.cAi.ReorderCollectionItems(collection.ID, input.FoodIDs)
+	if reorderErr != nil {
+		c.JSON(http.StatusUnprocessableEntity, reorderErr)
+		return
+	}
+
+	reordered, err := h.cAi.GetCollection(collection.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	reordered.HideFoodsFrom(reordered.UserID)
+	c.JSON(http.StatusOK, reordered.SignURLs(h.fui.MediaURL))
+}
+
+// ownCollection loads the collection of the :collection_id param and makes sure the
+// authenticated user owns it. When it returns false, the response was already written.
+func (h *CollectionHandler) ownCollection(c *gin.Context) (*entity.Collection, bool) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	collectionId, err := strconv.ParseUint(c.Param("collection_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return nil, false
+	}
+	collection, err := h.cAi.GetCollection(collectionId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	if collection.UserID != uId {
+		c.JSON(http.StatusUnauthorized, "you are not the owner of this collection")
+		return nil, false
+	}
+	return collection, true
+}
@@ -0,0 +1,290 @@
+package handler
+
+import (
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/auth"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommentHandler struct {
+	cAi application.CommentAppInterface
+	fAi application.FoodAppInterface
+	uAi application.UserAppInterface
+	ai  auth.AuthInterface
+	ti  auth.TokenInterface
+	mAi application.ModerationAppInterface
+}
+
+// CommentHandler constructor
+func NewCommentHandler(
+	cAi application.CommentAppInterface,
+	fAi application.FoodAppInterface,
+	uAi application.UserAppInterface,
+	ai auth.AuthInterface,
+	ti auth.TokenInterface,
+	mAi application.ModerationAppInterface,
+) *CommentHandler {
+	return &CommentHandler{
+		cAi: cAi,
+		fAi: fAi,
+		uAi: uAi,
+		ai:  ai,
+		ti:  ti,
+		mAi: mAi,
+	}
+}
+
+func (h *CommentHandler) SaveComment(c *gin.Context) {
+	// check is the user is authenticated first
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	// lookup the metadata in redis:
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	var comment entity.Comment
+	if err := c.ShouldBindJSON(&comment); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	comment.Prepare()
+	validateErr := comment.Validate()
+	if len(validateErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, validateErr)
+		return
+	}
+
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil || !food.IsVisibleTo(uId) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+
+	// only the parent and the body are taken from the payload
+	newComment := entity.Comment{
+		FoodID:    foodId,
+		UserID:    uId,
+		Body:      comment.Body,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+	}
+	if comment.ParentID != nil {
+		parent, err := h.cAi.GetComment(*comment.ParentID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, err.Error())
+			return
+		}
+		replyErr := newComment.ReplyTo(parent)
+		if len(replyErr) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, replyErr)
+			return
+		}
+	}
+
+	savedComment, saveErr := h.cAi.SaveComment(&newComment)
+	if saveErr != nil {
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	h.mAi.AutoFlag(entity.ReportTargetComment, savedComment.ID, uId, savedComment.Body)
+	c.JSON(http.StatusCreated, savedComment)
+}
+
+// GetComments returns a page of top level comments, each with all of its replies
+func (h *CommentHandler) GetComments(c *gin.Context) {
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	// the comments of a food are seen by whoever can see the food
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil || !food.IsVisibleTo(viewerId(c, h.ti, h.ai)) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+	page := paginationFromQuery(c)
+	threads, err := h.cAi.GetCommentThreads(foodId, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"comments":   threads,
+		"pagination": page,
+	})
+}
+
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	comment, ok := h.commentFromParams(c)
+	if !ok {
+		return
+	}
+
+	var input entity.Comment
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	input.Prepare()
+	validateErr := input.Validate()
+	if len(validateErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, validateErr)
+		return
+	}
+
+	editErr := comment.CanEdit(uId)
+	if len(editErr) > 0 {
+		c.JSON(http.StatusForbidden, editErr)
+		return
+	}
+
+	comment.Body = input.Body
+	comment.UpdatedAt = time.Now()
+	updatedComment, updateErr := h.cAi.UpdateComment(comment)
+	if updateErr != nil {
+		c.JSON(http.StatusInternalServerError, updateErr)
+		return
+	}
+	h.mAi.AutoFlag(entity.ReportTargetComment, updatedComment.ID, uId, updatedComment.Body)
+	c.JSON(http.StatusOK, updatedComment)
+}
+
+// DeleteComment soft deletes the comment, its replies stay in the thread
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	comment, ok := h.commentFromParams(c)
+	if !ok {
+		return
+	}
+	if comment.UserID != uId {
+		c.JSON(http.StatusUnauthorized, "you are not the author of this comment")
+		return
+	}
+	if comment.IsDeleted() {
+		c.JSON(http.StatusNotFound, "comment not found")
+		return
+	}
+
+	comment.SoftDelete()
+	_, deleteErr := h.cAi.UpdateComment(comment)
+	if deleteErr != nil {
+		c.JSON(http.StatusInternalServerError, deleteErr)
+		return
+	}
+	c.JSON(http.StatusOK, "comment deleted")
+}
+
+func (h *CommentHandler) HideComment(c *gin.Context) {
+	h.moderateComment(c, true)
+}
+
+func (h *CommentHandler) UnhideComment(c *gin.Context) {
+	h.moderateComment(c, false)
+}
+
+// only admins and the owner of the food can hide or unhide its comments
+func (h *CommentHandler) moderateComment(c *gin.Context, hide bool) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	user, err := h.uAi.GetUser(uId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "user not found, unauthorized")
+		return
+	}
+	comment, ok := h.commentFromParams(c)
+	if !ok {
+		return
+	}
+	food, err := h.fAi.GetFood(comment.FoodID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+	if !user.IsAdmin && uint64(user.ID) != food.UserID {
+		c.JSON(http.StatusUnauthorized, "you are not allowed to moderate this comment")
+		return
+	}
+
+	if hide {
+		comment.Hide(uint64(user.ID))
+	} else {
+		comment.Unhide()
+	}
+	updatedComment, updateErr := h.cAi.UpdateComment(comment)
+	if updateErr != nil {
+		c.JSON(http.StatusInternalServerError, updateErr)
+		return
+	}
+	c.JSON(http.StatusOK, updatedComment)
+}
+
+// commentFromParams loads the comment of the :comment_id param, making sure it belongs to the :food_id param.
+// When it returns false, the response was already written.
+func (h *CommentHandler) commentFromParams(c *gin.Context) (*entity.Comment, bool) {
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return nil, false
+	}
+	commentId, err := strconv.ParseUint(c.Param("comment_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return nil, false
+	}
+	comment, err := h.cAi.GetComment(commentId)
+	if err != nil || comment.FoodID != foodId {
+		c.JSON(http.StatusNotFound, "comment not found")
+		return nil, false
+	}
+	return comment, true
+}
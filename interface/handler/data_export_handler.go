@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/auth"
+	"learning-golang-ddd/interface/fileupload"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DataExportHandler struct {
+	eAi application.DataExportAppInterface
+	fui fileupload.UploadFileInterface
+	ai  auth.AuthInterface
+	ti  auth.TokenInterface
+}
+
+// DataExportHandler constructor
+func NewDataExportHandler(
+	eAi application.DataExportAppInterface,
+	fui fileupload.UploadFileInterface,
+	ai auth.AuthInterface,
+	ti auth.TokenInterface,
+) *DataExportHandler {
+	return &DataExportHandler{
+		eAi: eAi,
+		fui: fui,
+		ai:  ai,
+		ti:  ti,
+	}
+}
+
+// SaveDataExport asks for a copy of everything we hold about the user. The archive is built in the background,
+// GetDataExport tells when it can be downloaded. A user has at most one export being built at a time.
+func (h *DataExportHandler) SaveDataExport(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if active, err := h.eAi.GetActiveDataExport(uId); err == nil {
+		c.Header("Location", fmt.Sprintf("/users/me/exports/%d", active.ID))
+		c.JSON(http.StatusAccepted, exportResponse(active))
+		return
+	}
+	export, err := entity.NewDataExport(uId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, "cannot create the export")
+		return
+	}
+	savedExport, saveErr := h.eAi.SaveDataExport(export)
+	if saveErr != nil {
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/users/me/exports/%d", savedExport.ID))
+	c.JSON(http.StatusAccepted, exportResponse(savedExport))
+}
+
+// GetDataExport returns the status of an export, with its download url once it is ready
+func (h *DataExportHandler) GetDataExport(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	exportId, err := strconv.ParseUint(c.Param("export_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	export, err := h.eAi.GetDataExport(exportId)
+	if err != nil || export.UserID != uId {
+		c.JSON(http.StatusNotFound, "export not found")
+		return
+	}
+	c.JSON(http.StatusOK, exportResponse(export))
+}
+
+// DownloadDataExport sends the archive. The token of the link is the only credential,
+// so the link can be opened in a browser, until it expires.
+func (h *DataExportHandler) DownloadDataExport(c *gin.Context) {
+	exportId, err := strconv.ParseUint(c.Param("export_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	export, err := h.eAi.GetDataExport(exportId)
+	if err != nil || subtle.ConstantTimeCompare([]byte(export.Token), []byte(c.Query("token"))) != 1 {
+		c.JSON(http.StatusNotFound, "export not found")
+		return
+	}
+	if !export.CanDownload(time.Now()) {
+		c.JSON(http.StatusGone, "the download link has expired")
+		return
+	}
+	archive, err := h.fui.GetFile(export.Path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, "cannot read the export")
+		return
+	}
+	defer archive.Close()
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%d.zip"`, export.ID))
+	c.Header("Cache-Control", "private, no-store")
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, archive)
+}
+
+// exportResponse adds the download url to a ready export
+func exportResponse(export *entity.DataExport) gin.H {
+	response := gin.H{"export": export}
+	if export.CanDownload(time.Now()) {
+		response["download_url"] = fmt.Sprintf("/exports/%d/download?token=%s", export.ID, export.Token)
+	}
+	return response
+}
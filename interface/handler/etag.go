@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etagMatches reports whether the etag is one of the comma separated values of an
+// If-Match or If-None-Match header. "*" matches any etag.
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch makes sure the client edits the version of the food it last saw.
+// Requests without an If-Match header are let through, so the older clients keep working.
+// When it returns false, the response was already written.
+func checkIfMatch(c *gin.Context, food *entity.Food, sign entity.URLSigner) bool {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" || etagMatches(ifMatch, food.ETag()) {
+		return true
+	}
+	preconditionFailed(c, food, sign)
+	return false
+}
+
+// preconditionFailed sends the current food, with its image urls signed, so the frontend can show what changed
+func preconditionFailed(c *gin.Context, current *entity.Food, sign entity.URLSigner) {
+	c.Header("ETag", current.ETag())
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"version_mismatch": "the food was changed by someone else",
+		"food":             current.SignURLs(sign),
+	})
+}
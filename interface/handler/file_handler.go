@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/storage"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FileHandler struct {
+	st storage.Storage
+}
+
+// FileHandler constructor
+func NewFileHandler(st storage.Storage) *FileHandler {
+	return &FileHandler{st: st}
+}
+
+// GetFile serves the public files of the storages that have no web server of their own, the local disk and the memory.
+// The private files, e.g the data exports, are not found here.
+// A video is streamed in ranges, the ranges and the conditional requests are answered by http.ServeContent.
+func (h *FileHandler) GetFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	info, err := h.st.Stat(key)
+	if err != nil || !info.Public {
+		c.JSON(http.StatusNotFound, "file not found")
+		return
+	}
+	file, err := h.st.Get(key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, "file not found")
+		return
+	}
+	defer file.Close()
+	content, err := seekable(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, "cannot read the file")
+		return
+	}
+
+	if info.ContentType != "" {
+		c.Header("Content-Type", info.ContentType)
+	}
+	if info.CacheControl != "" {
+		c.Header("Cache-Control", info.CacheControl)
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, "", info.ModTime, content)
+}
+
+// seekable returns the file as it is when it can seek, e.g a file of the disk or an object of S3.
+// The files of the memory storage cannot, they are read at once.
+func seekable(file io.Reader) (io.ReadSeeker, error) {
+	if content, ok := file.(io.ReadSeeker); ok {
+		return content, nil
+	}
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// PutFile receives the files uploaded with the presigned urls of the local disk and the memory storages.
+// The url is only valid until it expires, and for the content type it was made for.
+func (h *FileHandler) PutFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	query := c.Request.URL.Query()
+	if !storage.VerifyPresignedPut(key, query, time.Now()) {
+		c.JSON(http.StatusForbidden, "invalid or expired upload url")
+		return
+	}
+	if !strings.EqualFold(c.ContentType(), query.Get("content_type")) {
+		c.JSON(http.StatusUnsupportedMediaType, "the file should be sent as "+query.Get("content_type"))
+		return
+	}
+	// the size is checked again when the upload is confirmed, this only keeps a huge body out
+	maxSize := entity.MaxUploadSizeOfAll()
+	if policy, ok := entity.UploadPolicyFor(query.Get("content_type")); ok {
+		maxSize = policy.MaxSize
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+	err := h.st.Put(key, c.Request.Body, c.Request.ContentLength, storage.PutOptions{ContentType: c.ContentType()})
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, fmt.Sprintf("the file should be at most %d bytes", maxSize))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, "the file could not be uploaded")
+		return
+	}
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/auth"
+	"learning-golang-ddd/interface/fileupload"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FoodClipHandler struct {
+	cAi  application.FoodClipAppInterface
+	fAi  application.FoodAppInterface
+	upAi application.UploadAppInterface
+	fui  fileupload.UploadFileInterface
+	ai   auth.AuthInterface
+	ti   auth.TokenInterface
+}
+
+// FoodClipHandler constructor
+func NewFoodClipHandler(
+	cAi application.FoodClipAppInterface,
+	fAi application.FoodAppInterface,
+	upAi application.UploadAppInterface,
+	fui fileupload.UploadFileInterface,
+	ai auth.AuthInterface,
+	ti auth.TokenInterface,
+) *FoodClipHandler {
+	return &FoodClipHandler{
+		cAi:  cAi,
+		fAi:  fAi,
+		upAi: upAi,
+		fui:  fui,
+		ai:   ai,
+		ti:   ti,
+	}
+}
+
+func (h *FoodClipHandler) GetClips(c *gin.Context) {
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil || !food.IsVisibleTo(viewerId(c, h.ti, h.ai)) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+	c.JSON(http.StatusOK, h.foodClips(food, food.Clips))
+}
+
+// AddClip appends the video of a confirmed upload to the clips of the food, e.g how a step is done:
+// {"upload_id": 3, "caption": "folding the dough"}. The video is uploaded like an image, see SaveUpload.
+func (h *FoodClipHandler) AddClip(c *gin.Context) {
+	food, ok := h.ownFood(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		UploadID uint64 `json:"upload_id"`
+		Caption  string `json:"caption"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	clip := entity.FoodClip{FoodID: food.ID, Caption: input.Caption}
+	clip.Prepare()
+	validateErr := clip.Validate()
+	if len(validateErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, validateErr)
+		return
+	}
+	// the food is ours, so is the upload
+	upload, attachErr := h.upAi.AttachUpload(input.UploadID, food.UserID, entity.MediaVideo)
+	if attachErr != nil {
+		c.JSON(http.StatusUnprocessableEntity, attachErr)
+		return
+	}
+	clip = *entity.NewFoodClip(food.ID, upload, clip.Caption)
+	clip.Prepare()
+
+	savedClip, saveErr := h.cAi.AddClip(&clip)
+	if saveErr != nil {
+		// the upload can be used again for the next try
+		if err := h.upAi.DetachUpload(upload.ID); err != nil {
+			log.Printf("cannot detach upload %d: %v", upload.ID, err)
+		}
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	h.fui.SyncImageACL(savedClip.Path, savedClip.PosterPath)
+	c.JSON(http.StatusCreated, h.foodClips(food, []entity.FoodClip{*savedClip})[0])
+}
+
+// UpdateClip changes the caption of the clip: {"caption": "folding the dough"}
+func (h *FoodClipHandler) UpdateClip(c *gin.Context) {
+	food, ok := h.ownFood(c)
+	if !ok {
+		return
+	}
+	clip, ok := h.clipFromParams(c, food)
+	if !ok {
+		return
+	}
+
+	var input entity.FoodClip
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	input.Prepare()
+	validateErr := input.Validate()
+	if len(validateErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, validateErr)
+		return
+	}
+
+	clip.Caption = input.Caption
+	updatedClip, updateErr := h.cAi.UpdateClip(clip)
+	if updateErr != nil {
+		c.JSON(http.StatusInternalServerError, updateErr)
+		return
+	}
+	c.JSON(http.StatusOK, h.foodClips(food, []entity.FoodClip{*updatedClip})[0])
+}
+
+func (h *FoodClipHandler) DeleteClip(c *gin.Context) {
+	food, ok := h.ownFood(c)
+	if !ok {
+		return
+	}
+	clip, ok := h.clipFromParams(c, food)
+	if !ok {
+		return
+	}
+	err := h.cAi.DeleteClip(clip.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	// the files stay while another food still uses them
+	for _, path := range []string{clip.Path, clip.PosterPath} {
+		if path == "" {
+			continue
+		}
+		if err := h.fui.DeleteFile(path); err != nil {
+			log.Printf("cannot delete clip file %s: %v", path, err)
+		}
+	}
+	h.fui.SyncImageACL(clip.Path, clip.PosterPath)
+	c.JSON(http.StatusOK, "clip deleted")
+}
+
+// ReorderClips expects the ids of all the clips of the food in their new order:
+// {"clip_ids": [3, 1, 2]}
+func (h *FoodClipHandler) ReorderClips(c *gin.Context) {
+	food, ok := h.ownFood(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ClipIDs []uint64 `json:"clip_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	reorderErr := h.cAi.ReorderClips(food.ID, input.ClipIDs)
+	if reorderErr != nil {
+		c.JSON(http.StatusUnprocessableEntity, reorderErr)
+		return
+	}
+
+	clips, err := h.cAi.GetClipsByFood(food.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, h.foodClips(food, clips))
+}
+
+// foodClips signs the urls of the clips of a food that is not public, they are private in the storage
+func (h *FoodClipHandler) foodClips(food *entity.Food, clips []entity.FoodClip) []entity.FoodClip {
+	if food.IsPublic() {
+		return clips
+	}
+	return entity.SignFoodClips(clips, h.fui.MediaURL)
+}
+
+// ownFood loads the food of the :food_id param and makes sure the authenticated user owns it.
+// When it returns false, the response was already written.
+func (h *FoodClipHandler) ownFood(c *gin.Context) (*entity.Food, bool) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return nil, false
+	}
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	if food.UserID != uId {
+		c.JSON(http.StatusUnauthorized, "you are not the owner of this food")
+		return nil, false
+	}
+	return food, true
+}
+
+func (h *FoodClipHandler) clipFromParams(c *gin.Context, food *entity.Food) (*entity.FoodClip, bool) {
+	clipId, err := strconv.ParseUint(c.Param("clip_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return nil, false
+	}
+	clip, err := h.cAi.GetClip(clipId)
+	if err != nil || clip.FoodID != food.ID {
+		c.JSON(http.StatusNotFound, "clip not found")
+		return nil, false
+	}
+	return clip, true
+}
@@ -1,25 +1,32 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"learning-golang-ddd/application"
 	"learning-golang-ddd/domain/entity"
 	"learning-golang-ddd/infrastructure/auth"
+	"learning-golang-ddd/infrastructure/event"
 	"learning-golang-ddd/interface/fileupload"
+	"log"
 	"net/http"
-	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type FoodHandler struct {
-	fAi application.FoodAppInterface
-	uAi application.UserAppInterface
-	fui fileupload.UploadFileInterface
-	ai  auth.AuthInterface
-	ti  auth.TokenInterface
+	fAi  application.FoodAppInterface
+	uAi  application.UserAppInterface
+	fui  fileupload.UploadFileInterface
+	ai   auth.AuthInterface
+	ti   auth.TokenInterface
+	pi   event.PublisherInterface
+	mAi  application.ModerationAppInterface
+	upAi application.UploadAppInterface
 }
 
 // FoodHandler constructor
@@ -29,13 +36,19 @@ func NewFoodHandler(
 	fui fileupload.UploadFileInterface,
 	ai auth.AuthInterface,
 	ti auth.TokenInterface,
+	pi event.PublisherInterface,
+	mAi application.ModerationAppInterface,
+	upAi application.UploadAppInterface,
 ) *FoodHandler {
 	return &FoodHandler{
-		fAi: fAi,
-		uAi: uAi,
-		fui: fui,
-		ai:  ai,
-		ti:  ti,
+		fAi:  fAi,
+		uAi:  uAi,
+		fui:  fui,
+		ai:   ai,
+		ti:   ti,
+		pi:   pi,
+		mAi:  mAi,
+		upAi: upAi,
 	}
 }
 
@@ -69,16 +82,34 @@ func (h *FoodHandler) SaveFood(c *gin.Context) {
 
 	// we initialize a new food for the purpose of validating:
 	// in case the payload is empty or an invalid data type is used
+	ingredients, err := parseIngredients(c.PostForm("ingredients"))
+	if err != nil {
+		saveFoodError["invalid_ingredients"] = "ingredients should be a valid json array"
+		c.JSON(http.StatusUnprocessableEntity, saveFoodError)
+		return
+	}
 	emptyFood := entity.Food{}
 	emptyFood.Title = title
 	emptyFood.Description = description
+	emptyFood.Ingredients = ingredients
 	saveFoodError = emptyFood.Validate("")
 	if len(saveFoodError) > 0 {
 		c.JSON(http.StatusUnprocessableEntity, saveFoodError)
 		return
 	}
+	// foods are published right away unless a draft or a schedule is asked for
+	status, publishAt, statusErr := parseStatus(c.DefaultPostForm("status", string(entity.FoodStatusPublished)), c.PostForm("publish_at"))
+	if len(statusErr) == 0 {
+		statusErr = emptyFood.TransitionTo(status, publishAt)
+	}
+	if len(statusErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, statusErr)
+		return
+	}
+	// the image is either sent with the form, or uploaded beforehand and given as upload_id
+	uploadId := c.PostForm("upload_id")
 	file, err := c.FormFile("food_image")
-	if err != nil {
+	if err != nil && uploadId == "" {
 		saveFoodError["invalid_file"] = "a valid file is required"
 		c.JSON(http.StatusUnprocessableEntity, saveFoodError)
 		return
@@ -89,11 +120,21 @@ func (h *FoodHandler) SaveFood(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, "user not found, unauthorized")
 		return
 	}
-	uploadedFile, err := h.fui.UploadFile(file)
-	if err != nil {
-		saveFoodError["upload_error"] = err.Error() // this error can be any we defined the UploadFile method
-		c.JSON(http.StatusUnprocessableEntity, saveFoodError)
-		return
+	var uploadedFile string
+	if uploadId != "" {
+		var attachErr map[string]string
+		uploadedFile, attachErr = attachUpload(h.upAi, uId, uploadId)
+		if attachErr != nil {
+			c.JSON(http.StatusUnprocessableEntity, attachErr)
+			return
+		}
+	} else {
+		uploadedFile, err = h.fui.UploadFile(file)
+		if err != nil {
+			saveFoodError["upload_error"] = err.Error() // this error can be any we defined the UploadFile method
+			c.JSON(http.StatusUnprocessableEntity, saveFoodError)
+			return
+		}
 	}
 
 	var food = entity.Food{}
@@ -101,12 +142,89 @@ func (h *FoodHandler) SaveFood(c *gin.Context) {
 	food.Title = title
 	food.Description = description
 	food.FoodImage = uploadedFile
+	food.Ingredients = ingredients
+	food.UpdatedBy = uId
+	food.Status = emptyFood.Status
+	food.PublishAt = emptyFood.PublishAt
+	food.PublishedAt = emptyFood.PublishedAt
 	savedFood, saveErr := h.fAi.SaveFood(&food)
+	if saveErr != nil && uploadId != "" {
+		// the upload can be used again for the next try
+		detachUpload(h.upAi, uploadId)
+	}
 	if saveErr != nil {
 		c.JSON(http.StatusInternalServerError, saveErr)
 		return
 	}
-	c.JSON(http.StatusCreated, savedFood)
+	h.mAi.AutoFlag(entity.ReportTargetFood, savedFood.ID, uId, savedFood.Title, savedFood.Description)
+	h.fui.SyncImageACL(savedFood.FoodImage)
+	if savedFood.Status == entity.FoodStatusPublished {
+		h.publishEvent(savedFood)
+	}
+	c.JSON(http.StatusCreated, savedFood.SignURLs(h.fui.MediaURL))
+}
+
+// UpdateFoodStatus moves the food through its lifecycle:
+// {"status": "scheduled", "publish_at": "2021-08-01T10:00:00Z"}
+func (h *FoodHandler) UpdateFoodStatus(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	var input struct {
+		Status    string `json:"status"`
+		PublishAt string `json:"publish_at"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	status, publishAt, statusErr := parseStatus(input.Status, input.PublishAt)
+	if len(statusErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, statusErr)
+		return
+	}
+
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil || !food.IsVisibleTo(uId) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+	if food.UserID != uId {
+		c.JSON(http.StatusUnauthorized, "you are not the owner of this food")
+		return
+	}
+
+	statusErr = food.TransitionTo(status, publishAt)
+	if len(statusErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, statusErr)
+		return
+	}
+	updatedFood, updateErr := h.fAi.UpdateFoodStatus(food)
+	if updateErr != nil {
+		c.JSON(http.StatusInternalServerError, updateErr)
+		return
+	}
+	// the images become public with the food, and private again when it is not published anymore
+	h.fui.SyncImageACL(updatedFood.ImagePaths()...)
+	if updatedFood.Status == entity.FoodStatusPublished {
+		h.publishEvent(updatedFood)
+	}
+	c.JSON(http.StatusOK, updatedFood.SignURLs(h.fui.MediaURL))
 }
 
 func (h *FoodHandler) UpdateFood(c *gin.Context) {
@@ -145,9 +263,18 @@ func (h *FoodHandler) UpdateFood(c *gin.Context) {
 
 	// we initialize a new food for the purpose of validating:
 	// in case the payload is empty or an invalid data type is used
+	// the ingredients are optional on update, when they are not given the old ones are kept
+	_, hasIngredients := c.GetPostForm("ingredients")
+	ingredients, err := parseIngredients(c.PostForm("ingredients"))
+	if err != nil {
+		updateFoodError["invalid_ingredients"] = "ingredients should be a valid json array"
+		c.JSON(http.StatusUnprocessableEntity, updateFoodError)
+		return
+	}
 	emptyFood := entity.Food{}
 	emptyFood.Title = title
 	emptyFood.Description = description
+	emptyFood.Ingredients = ingredients
 	updateFoodError = emptyFood.Validate("update")
 	if len(updateFoodError) > 0 {
 		c.JSON(http.StatusUnprocessableEntity, updateFoodError)
@@ -173,19 +300,29 @@ func (h *FoodHandler) UpdateFood(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, "you are not the owner of this food")
 		return
 	}
+	if !checkIfMatch(c, food, h.fui.MediaURL) {
+		return
+	}
 
 	// since this is an update request, a new image may or may not be given.
 	// - If not image is given, an error occurs. We know this that is why we ignored
 	//   the error and instead check if the file is nil.
 	// - if not nil, we process the file by calling the "UploadFile" method.
 	// - if nil, we used the old one whose path is saved in the database
+	// the upload_id of a confirmed upload can be given instead of the file
 	file, _ := c.FormFile("food_image")
-	if file != nil {
+	previousImage := food.FoodImage
+	uploadId := c.PostForm("upload_id")
+	if uploadId != "" {
+		var attachErr map[string]string
+		food.FoodImage, attachErr = attachUpload(h.upAi, uId, uploadId)
+		if attachErr != nil {
+			c.JSON(http.StatusUnprocessableEntity, attachErr)
+			return
+		}
+	} else if file != nil {
+		// the url of the image is saved, wherever the storage keeps it
 		food.FoodImage, err = h.fui.UploadFile(file)
-		// since i am using Digital Ocean (DO) spaces to save image,
-		// i am appending my DO url here. You can comment this line
-		// since you may be using Digital Ocean Spaces.
-		food.FoodImage = os.Getenv("DO_SPACES_URL") + food.FoodImage
 		if err != nil {
 			c.JSON(http.StatusUnprocessableEntity, gin.H{
 				"upload_error": err.Error(),
@@ -197,23 +334,208 @@ func (h *FoodHandler) UpdateFood(c *gin.Context) {
 	// we dont need to update user's id
 	food.Title = title
 	food.Description = description
+	if hasIngredients {
+		food.Ingredients = ingredients
+	}
+	food.UpdatedBy = uId
+	food.UpdatedAt = time.Now()
+	updatedFood, updateFoodErr := h.fAi.UpdateFood(food)
+	if updateFoodErr != nil && uploadId != "" {
+		// the upload can be used again for the next try
+		detachUpload(h.upAi, uploadId)
+	}
+	if _, ok := updateFoodErr["version_mismatch"]; ok {
+		// someone else saved the food between our read and our write
+		current, err := h.fAi.GetFood(foodId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+		preconditionFailed(c, current, h.fui.MediaURL)
+		return
+	}
+	if _, ok := updateFoodErr["revision_conflict"]; ok {
+		c.JSON(http.StatusConflict, updateFoodErr)
+		return
+	}
+	if updateFoodErr != nil {
+		c.JSON(http.StatusInternalServerError, updateFoodErr)
+		return
+	}
+	h.mAi.AutoFlag(entity.ReportTargetFood, updatedFood.ID, uId, updatedFood.Title, updatedFood.Description)
+	if updatedFood.FoodImage != previousImage {
+		h.fui.SyncImageACL(previousImage, updatedFood.FoodImage)
+	}
+
+	c.Header("ETag", updatedFood.ETag())
+	c.JSON(http.StatusOK, updatedFood.SignURLs(h.fui.MediaURL))
+}
+
+// PatchFood applies a JSON Merge Patch or a JSON Patch to the food.
+// Unlike UpdateFood, only the fields that changed are validated.
+func (h *FoodHandler) PatchFood(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil || !food.IsVisibleTo(uId) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+	if food.UserID != uId {
+		c.JSON(http.StatusUnauthorized, "you are not the owner of this food")
+		return
+	}
+	if !checkIfMatch(c, food, h.fui.MediaURL) {
+		return
+	}
+
+	doc := newFoodPatchDocument(food)
+	patched, patchErr := applyFoodPatch(doc, c.ContentType(), patch)
+	if _, ok := patchErr["invalid_content_type"]; ok {
+		c.JSON(http.StatusUnsupportedMediaType, patchErr)
+		return
+	}
+	if len(patchErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, patchErr)
+		return
+	}
+	changed := doc.changedFields(patched)
+	if len(changed) == 0 {
+		c.Header("ETag", food.ETag())
+		c.JSON(http.StatusOK, food.SignURLs(h.fui.MediaURL))
+		return
+	}
+
+	for _, field := range changed {
+		switch field {
+		case "title":
+			food.Title = patched.Title
+		case "description":
+			food.Description = patched.Description
+		case "food_image":
+			// an image can not be uploaded with json, the cover has to be one of the gallery images
+			if !hasGalleryImage(food, patched.FoodImage) {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"invalid_food_image": "the food image should be one of the food's images",
+				})
+				return
+			}
+			food.FoodImage = patched.FoodImage
+		case "ingredients":
+			food.Ingredients = patched.ingredients(doc)
+		}
+	}
+	patchFoodError := food.ValidateFields(changed)
+	if len(patchFoodError) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, patchFoodError)
+		return
+	}
+
+	food.UpdatedBy = uId
 	food.UpdatedAt = time.Now()
 	updatedFood, updateFoodErr := h.fAi.UpdateFood(food)
+	if _, ok := updateFoodErr["version_mismatch"]; ok {
+		current, err := h.fAi.GetFood(foodId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+		preconditionFailed(c, current, h.fui.MediaURL)
+		return
+	}
+	if _, ok := updateFoodErr["revision_conflict"]; ok {
+		c.JSON(http.StatusConflict, updateFoodErr)
+		return
+	}
+	if _, ok := updateFoodErr["unique_title"]; ok {
+		c.JSON(http.StatusUnprocessableEntity, updateFoodErr)
+		return
+	}
 	if updateFoodErr != nil {
 		c.JSON(http.StatusInternalServerError, updateFoodErr)
 		return
 	}
+	h.mAi.AutoFlag(entity.ReportTargetFood, updatedFood.ID, uId, updatedFood.Title, updatedFood.Description)
+
+	c.Header("ETag", updatedFood.ETag())
+	c.JSON(http.StatusOK, updatedFood.SignURLs(h.fui.MediaURL))
+}
 
-	c.JSON(http.StatusOK, updatedFood)
+func hasGalleryImage(food *entity.Food, path string) bool {
+	for _, image := range food.Images {
+		if image.Path == path {
+			return true
+		}
+	}
+	return false
 }
 
 func (h *FoodHandler) GetAllFood(c *gin.Context) {
-	allfood, err := h.fAi.GetAllFood()
+	filter := entity.FoodFilter{ViewerID: viewerId(c, h.ti, h.ai)}
+	filterErr := map[string]string{}
+	if minCalories := c.Query("min_calories"); minCalories != "" {
+		value, err := strconv.ParseFloat(minCalories, 64)
+		if err != nil {
+			filterErr["invalid_min_calories"] = "min_calories should be a number"
+		}
+		filter.MinCalories = &value
+	}
+	if maxCalories := c.Query("max_calories"); maxCalories != "" {
+		value, err := strconv.ParseFloat(maxCalories, 64)
+		if err != nil {
+			filterErr["invalid_max_calories"] = "max_calories should be a number"
+		}
+		filter.MaxCalories = &value
+	}
+	if len(filterErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, filterErr)
+		return
+	}
+	// e.g ?exclude_allergens=gluten,milk
+	for _, allergen := range strings.Split(c.Query("exclude_allergens"), ",") {
+		if allergen = strings.ToLower(strings.TrimSpace(allergen)); allergen != "" {
+			filter.ExcludeAllergens = append(filter.ExcludeAllergens, allergen)
+		}
+	}
+
+	// e.g ?sort=rating, the newest foods come first by default
+	switch sort := c.Query("sort"); sort {
+	case "", "rating":
+		filter.SortBy = sort
+	default:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_sort": "sort should be rating",
+		})
+		return
+	}
+
+	allfood, err := h.fAi.GetAllFoodByFilter(&filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, err.Error())
 		return
 	}
-	c.JSON(http.StatusOK, allfood)
+	c.JSON(http.StatusOK, entity.SignFoods(allfood, h.fui.MediaURL))
 }
 
 func (h *FoodHandler) GetFoodAndCreator(c *gin.Context) {
@@ -227,13 +549,23 @@ func (h *FoodHandler) GetFoodAndCreator(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, err.Error())
 		return
 	}
+	// drafts are only visible to their owner
+	if !food.IsVisibleTo(viewerId(c, h.ti, h.ai)) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
 	user, err := h.uAi.GetUser(food.UserID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, err.Error())
 		return
 	}
+	c.Header("ETag", food.ETag())
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, food.ETag()) {
+		c.Status(http.StatusNotModified)
+		return
+	}
 	foodAndUser := map[string]interface{}{
-		"food":    food,
+		"food":    food.SignURLs(h.fui.MediaURL),
 		"creator": user.PublicUser(),
 	}
 	c.JSON(http.StatusOK, foodAndUser)
@@ -255,10 +587,122 @@ func (h *FoodHandler) DeleteFood(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, err.Error())
 		return
 	}
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+	if food.UserID != metadata.UserId {
+		c.JSON(http.StatusUnauthorized, "you are not the owner of this food")
+		return
+	}
+	if !checkIfMatch(c, food, h.fui.MediaURL) {
+		return
+	}
+	// the food is moved to the trash, see RestoreFood
 	err = h.fAi.DeleteFood(foodId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.fui.SyncImageACL(food.ImagePaths()...)
 	c.JSON(http.StatusOK, "food deleted")
 }
+
+// GetTrash lists the foods of the authenticated user that are in the trash
+func (h *FoodHandler) GetTrash(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	foods, err := h.fAi.GetDeletedFoodsByUser(uId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, entity.SignFoods(foods, h.fui.MediaURL))
+}
+
+func (h *FoodHandler) RestoreFood(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	food, err := h.fAi.GetDeletedFood(foodId)
+	if err != nil || food.UserID != uId {
+		c.JSON(http.StatusNotFound, "food not found in the trash")
+		return
+	}
+	err = h.fAi.RestoreFood(foodId)
+	if errors.Is(err, entity.ErrTitleTaken) {
+		c.JSON(http.StatusConflict, map[string]string{"unique_title": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	restoredFood, err := h.fAi.GetFood(foodId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.fui.SyncImageACL(restoredFood.ImagePaths()...)
+	c.JSON(http.StatusOK, restoredFood.SignURLs(h.fui.MediaURL))
+}
+
+// the ingredients are sent as a json array in the "ingredients" form field:
+// [{"name": "egg", "quantity": 2, "unit": "piece"}]
+func parseIngredients(raw string) ([]entity.Ingredient, error) {
+	ingredients := []entity.Ingredient{}
+	if strings.TrimSpace(raw) == "" {
+		return ingredients, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &ingredients); err != nil {
+		return nil, err
+	}
+	for i := range ingredients {
+		ingredients[i].Prepare()
+	}
+	return ingredients, nil
+}
+
+// parseStatus reads the status and the optional RFC 3339 publish_at date of a food
+func parseStatus(rawStatus string, rawPublishAt string) (entity.FoodStatus, *time.Time, map[string]string) {
+	var errorMessages = make(map[string]string)
+
+	status := entity.FoodStatus(strings.ToLower(strings.TrimSpace(rawStatus)))
+	if rawPublishAt == "" {
+		return status, nil, errorMessages
+	}
+	publishAt, err := time.Parse(time.RFC3339, rawPublishAt)
+	if err != nil {
+		errorMessages["invalid_publish_at"] = "publish_at should be a RFC 3339 date"
+		return status, nil, errorMessages
+	}
+	return status, &publishAt, errorMessages
+}
+
+func (h *FoodHandler) publishEvent(food *entity.Food) {
+	err := h.pi.Publish(entity.NewFoodPublishedEvent(food))
+	if err != nil {
+		log.Printf("cannot publish %s event for food %d: %v", entity.EventFoodPublished, food.ID, err)
+	}
+}
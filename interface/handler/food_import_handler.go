@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/auth"
+	"learning-golang-ddd/interface/foodimport"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FoodImportHandler struct {
+	iAi application.FoodImportAppInterface
+	ai  auth.AuthInterface
+	ti  auth.TokenInterface
+}
+
+// FoodImportHandler constructor
+func NewFoodImportHandler(iAi application.FoodImportAppInterface, ai auth.AuthInterface, ti auth.TokenInterface) *FoodImportHandler {
+	return &FoodImportHandler{
+		iAi: iAi,
+		ai:  ai,
+		ti:  ti,
+	}
+}
+
+// SaveFoodImport accepts a csv or ndjson file of foods, either as the "file" field of a multipart form
+// or as the request body. The foods are imported in the background, the import can be followed with GetFoodImport.
+func (h *FoodImportHandler) SaveFoodImport(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var importError = make(map[string]string)
+	var data []byte
+	format := strings.ToLower(c.Query("format"))
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			importError["invalid_file"] = "a valid file is required"
+			c.JSON(http.StatusUnprocessableEntity, importError)
+			return
+		}
+		if format == "" {
+			format = importFormat(c.PostForm("format"), path.Ext(file.Filename))
+		}
+		f, err := file.Open()
+		if err != nil {
+			importError["invalid_file"] = "cannot open file"
+			c.JSON(http.StatusUnprocessableEntity, importError)
+			return
+		}
+		defer f.Close()
+		data, err = ioutil.ReadAll(f)
+		if err != nil {
+			importError["invalid_file"] = "cannot read file"
+			c.JSON(http.StatusUnprocessableEntity, importError)
+			return
+		}
+	} else {
+		if format == "" {
+			format = importFormat("", c.ContentType())
+		}
+		data, err = c.GetRawData()
+		if err != nil {
+			importError["invalid_file"] = "cannot read file"
+			c.JSON(http.StatusUnprocessableEntity, importError)
+			return
+		}
+	}
+	if int64(len(data)) > entity.MaxImportSize {
+		importError["too_large"] = fmt.Sprintf("a file can weigh at most %dKB", entity.MaxImportSize>>10)
+		c.JSON(http.StatusRequestEntityTooLarge, importError)
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", c.PostForm("dry_run")))
+
+	// the file is read once here, so a broken file is reported right away instead of by the job
+	rows, err := foodimport.Parse(format, data)
+	if err != nil {
+		importError["invalid_file"] = err.Error()
+		c.JSON(http.StatusUnprocessableEntity, importError)
+		return
+	}
+	if len(rows) == 0 {
+		importError["invalid_file"] = "the file has no foods"
+		c.JSON(http.StatusUnprocessableEntity, importError)
+		return
+	}
+	if len(rows) > entity.MaxImportRows {
+		importError["too_many_rows"] = fmt.Sprintf("a file can have at most %d foods", entity.MaxImportRows)
+		c.JSON(http.StatusUnprocessableEntity, importError)
+		return
+	}
+
+	foodImport, saveErr := h.iAi.SaveFoodImport(entity.NewFoodImport(uId, format, dryRun, string(data), len(rows)))
+	if saveErr != nil {
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	foodImport.RowErrors = []entity.ImportRowError{}
+	c.Header("Location", fmt.Sprintf("/food/import/%d", foodImport.ID))
+	c.JSON(http.StatusAccepted, foodImport)
+}
+
+// GetFoodImport returns the progress of an import, with the errors of the rows that failed
+func (h *FoodImportHandler) GetFoodImport(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	importId, err := strconv.ParseUint(c.Param("import_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	foodImport, err := h.iAi.GetFoodImport(importId)
+	// the imports of other users are not found, rather than forbidden
+	if err != nil || foodImport.UserID != uId {
+		c.JSON(http.StatusNotFound, "import not found")
+		return
+	}
+	c.JSON(http.StatusOK, foodImport)
+}
+
+// importFormat guesses the format from the form field, the file extension or the content type
+func importFormat(field string, hint string) string {
+	if field != "" {
+		return strings.ToLower(field)
+	}
+	switch strings.ToLower(hint) {
+	case ".csv", "text/csv":
+		return entity.ImportFormatCSV
+	case ".ndjson", ".jsonl", "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return entity.ImportFormatNDJSON
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"learning-golang-ddd/domain/entity"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch"
+)
+
+const (
+	contentTypeMergePatch = "application/merge-patch+json"
+	contentTypeJSONPatch  = "application/json-patch+json"
+)
+
+var errUnsupportedPatch = errors.New("use application/merge-patch+json or application/json-patch+json")
+
+// foodPatchDocument is the part of a food that can be changed with PATCH.
+// The status and the gallery have their own endpoints.
+type foodPatchDocument struct {
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	FoodImage   string            `json:"food_image"`
+	Ingredients []patchIngredient `json:"ingredients"`
+}
+
+type patchIngredient struct {
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+}
+
+func newFoodPatchDocument(food *entity.Food) foodPatchDocument {
+	doc := foodPatchDocument{
+		Title:       food.Title,
+		Description: food.Description,
+		FoodImage:   food.FoodImage,
+		Ingredients: []patchIngredient{},
+	}
+	for _, ingredient := range food.Ingredients {
+		doc.Ingredients = append(doc.Ingredients, patchIngredient{
+			Name:     ingredient.Name,
+			Quantity: ingredient.Quantity,
+			Unit:     ingredient.Unit,
+		})
+	}
+	return doc
+}
+
+// changedFields returns the json names of the fields that differ between the two documents
+func (d foodPatchDocument) changedFields(patched foodPatchDocument) []string {
+	var fields []string
+	if d.Title != patched.Title {
+		fields = append(fields, "title")
+	}
+	if d.Description != patched.Description {
+		fields = append(fields, "description")
+	}
+	if d.FoodImage != patched.FoodImage {
+		fields = append(fields, "food_image")
+	}
+	if len(d.Ingredients) != len(patched.Ingredients) ||
+		(len(d.Ingredients) > 0 && !reflect.DeepEqual(d.Ingredients, patched.Ingredients)) {
+		fields = append(fields, "ingredients")
+	}
+	return fields
+}
+
+// ingredients converts the patched ingredients back to entities. The ingredients kept from the original
+// document were prepared when they were saved, only the incoming ones are, so no name is escaped twice.
+func (d foodPatchDocument) ingredients(original foodPatchDocument) []entity.Ingredient {
+	kept := make(map[patchIngredient]bool, len(original.Ingredients))
+	for _, ingredient := range original.Ingredients {
+		kept[ingredient] = true
+	}
+	ingredients := make([]entity.Ingredient, 0, len(d.Ingredients))
+	for _, patched := range d.Ingredients {
+		ingredient := entity.Ingredient{
+			Name:     patched.Name,
+			Quantity: patched.Quantity,
+			Unit:     patched.Unit,
+		}
+		if !kept[patched] {
+			ingredient.Prepare()
+		}
+		ingredients = append(ingredients, ingredient)
+	}
+	return ingredients
+}
+
+// applyFoodPatch applies a RFC 7396 JSON Merge Patch or a RFC 6902 JSON Patch to the document,
+// depending on the content type. Plain application/json is treated as a merge patch.
+// The errors are keyed, the same way as the validation errors.
+func applyFoodPatch(doc foodPatchDocument, contentType string, patch []byte) (foodPatchDocument, map[string]string) {
+	original, err := json.Marshal(doc)
+	if err != nil {
+		return doc, map[string]string{"invalid_patch": err.Error()}
+	}
+
+	var patched []byte
+	switch contentType {
+	case contentTypeMergePatch, "application/json":
+		patched, err = jsonpatch.MergePatch(original, patch)
+	case contentTypeJSONPatch:
+		var operations jsonpatch.Patch
+		operations, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			patched, err = operations.Apply(original)
+		}
+	default:
+		return doc, map[string]string{"invalid_content_type": errUnsupportedPatch.Error()}
+	}
+	if err != nil {
+		return doc, map[string]string{"invalid_patch": err.Error()}
+	}
+
+	// only the known fields can be patched, anything else is most likely a typo
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patched, &fields); err != nil {
+		return doc, map[string]string{"invalid_json": "invalid json"}
+	}
+	for name := range fields {
+		switch name {
+		case "title", "description", "food_image", "ingredients":
+		default:
+			return doc, map[string]string{"unknown_field": fmt.Sprintf("%s cannot be patched", name)}
+		}
+	}
+
+	result := foodPatchDocument{}
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return doc, map[string]string{"invalid_json": "invalid json"}
+	}
+	return result, nil
+}
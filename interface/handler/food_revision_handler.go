@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/auth"
+	"learning-golang-ddd/interface/fileupload"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FoodRevisionHandler struct {
+	rAi application.FoodRevisionAppInterface
+	fAi application.FoodAppInterface
+	ai  auth.AuthInterface
+	ti  auth.TokenInterface
+	fui fileupload.UploadFileInterface
+}
+
+// FoodRevisionHandler constructor
+func NewFoodRevisionHandler(
+	rAi application.FoodRevisionAppInterface,
+	fAi application.FoodAppInterface,
+	ai auth.AuthInterface,
+	ti auth.TokenInterface,
+	fui fileupload.UploadFileInterface,
+) *FoodRevisionHandler {
+	return &FoodRevisionHandler{
+		rAi: rAi,
+		fAi: fAi,
+		ai:  ai,
+		ti:  ti,
+		fui: fui,
+	}
+}
+
+func (h *FoodRevisionHandler) GetRevisions(c *gin.Context) {
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	// the revisions of a food are seen by whoever can see the food
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil || !food.IsVisibleTo(viewerId(c, h.ti, h.ai)) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+	revisions, err := h.rAi.GetRevisions(foodId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, entity.SignFoodRevisions(revisions, h.fui.MediaURL))
+}
+
+// DiffRevisions compares the :rev revision with the one given in ?against=, or with the previous revision by default
+func (h *FoodRevisionHandler) DiffRevisions(c *gin.Context) {
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	against := rev - 1
+	if c.Query("against") != "" {
+		against, err = strconv.Atoi(c.Query("against"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "invalid request")
+			return
+		}
+	}
+
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil || !food.IsVisibleTo(viewerId(c, h.ti, h.ai)) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+
+	to, err := h.rAi.GetRevision(foodId, rev)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+	from, err := h.rAi.GetRevision(foodId, against)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":    from.Revision,
+		"to":      to.Revision,
+		"changes": from.Diff(to),
+	})
+}
+
+// RestoreRevision copies the revision back onto the food. The restore itself is saved as a new revision.
+func (h *FoodRevisionHandler) RestoreRevision(c *gin.Context) {
+	// check if the user is authenticated first
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	// lookup the metadata in redis:
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+	// only the owner can restore the food
+	if food.UserID != uId {
+		c.JSON(http.StatusUnauthorized, "you are not the owner of this food")
+		return
+	}
+	if !checkIfMatch(c, food, h.fui.MediaURL) {
+		return
+	}
+
+	revision, err := h.rAi.GetRevision(foodId, rev)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+	previousImage := food.FoodImage
+	if err := revision.ApplyTo(food); err != nil {
+		c.JSON(http.StatusInternalServerError, "the revision is corrupted")
+		return
+	}
+
+	food.UpdatedBy = uId
+	food.UpdatedAt = time.Now()
+	restoredFood, restoreErr := h.fAi.UpdateFood(food)
+	if _, ok := restoreErr["version_mismatch"]; ok {
+		current, err := h.fAi.GetFood(foodId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+		preconditionFailed(c, current, h.fui.MediaURL)
+		return
+	}
+	if _, ok := restoreErr["revision_conflict"]; ok {
+		c.JSON(http.StatusConflict, restoreErr)
+		return
+	}
+	if restoreErr != nil {
+		c.JSON(http.StatusInternalServerError, restoreErr)
+		return
+	}
+	if restoredFood.FoodImage != previousImage {
+		h.fui.SyncImageACL(previousImage, restoredFood.FoodImage)
+	}
+	c.Header("ETag", restoredFood.ETag())
+	c.JSON(http.StatusOK, restoredFood.SignURLs(h.fui.MediaURL))
+}
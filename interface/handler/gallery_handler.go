@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/auth"
+	"learning-golang-ddd/interface/fileupload"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GalleryHandler struct {
+	gAi  application.GalleryAppInterface
+	fAi  application.FoodAppInterface
+	fui  fileupload.UploadFileInterface
+	ai   auth.AuthInterface
+	ti   auth.TokenInterface
+	upAi application.UploadAppInterface
+}
+
+// GalleryHandler constructor
+func NewGalleryHandler(
+	gAi application.GalleryAppInterface,
+	fAi application.FoodAppInterface,
+	fui fileupload.UploadFileInterface,
+	ai auth.AuthInterface,
+	ti auth.TokenInterface,
+	upAi application.UploadAppInterface,
+) *GalleryHandler {
+	return &GalleryHandler{
+		gAi:  gAi,
+		fAi:  fAi,
+		fui:  fui,
+		ai:   ai,
+		ti:   ti,
+		upAi: upAi,
+	}
+}
+
+func (h *GalleryHandler) GetImages(c *gin.Context) {
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil || !food.IsVisibleTo(viewerId(c, h.ti, h.ai)) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+	c.JSON(http.StatusOK, h.galleryImages(food, food.Images))
+}
+
+// AddImage uploads a new image to the gallery. The multipart form has the "image" file, or the "upload_id"
+// of a confirmed upload, an optional "alt_text" and an optional "cover" flag.
+func (h *GalleryHandler) AddImage(c *gin.Context) {
+	food, ok := h.ownFood(c)
+	if !ok {
+		return
+	}
+
+	var addImageError = make(map[string]string)
+	image := entity.GalleryImage{
+		FoodID:  food.ID,
+		AltText: c.PostForm("alt_text"),
+		IsCover: c.PostForm("cover") == "true",
+	}
+	image.Prepare()
+	addImageError = image.Validate()
+	if len(addImageError) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, addImageError)
+		return
+	}
+	uploadId := c.PostForm("upload_id")
+	if uploadId != "" {
+		// the food is ours, so is the upload
+		uploadedFile, attachErr := attachUpload(h.upAi, food.UserID, uploadId)
+		if attachErr != nil {
+			c.JSON(http.StatusUnprocessableEntity, attachErr)
+			return
+		}
+		image.Path = uploadedFile
+	} else {
+		file, err := c.FormFile("image")
+		if err != nil {
+			addImageError["invalid_file"] = "a valid file is required"
+			c.JSON(http.StatusUnprocessableEntity, addImageError)
+			return
+		}
+		uploadedFile, err := h.fui.UploadFile(file)
+		if err != nil {
+			addImageError["upload_error"] = err.Error()
+			c.JSON(http.StatusUnprocessableEntity, addImageError)
+			return
+		}
+		image.Path = uploadedFile
+	}
+
+	savedImage, saveErr := h.gAi.AddImage(&image)
+	if saveErr != nil && uploadId != "" {
+		// the upload can be used again for the next try
+		detachUpload(h.upAi, uploadId)
+	}
+	if saveErr != nil {
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	h.fui.SyncImageACL(savedImage.Path)
+	c.JSON(http.StatusCreated, h.galleryImages(food, []entity.GalleryImage{*savedImage})[0])
+}
+
+// UpdateImage changes the alt text of the image, and makes it the cover when asked:
+// {"alt_text": "the finished dish", "is_cover": true}
+func (h *GalleryHandler) UpdateImage(c *gin.Context) {
+	food, ok := h.ownFood(c)
+	if !ok {
+		return
+	}
+	image, ok := h.imageFromParams(c, food)
+	if !ok {
+		return
+	}
+
+	var input entity.GalleryImage
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	input.Prepare()
+	validateErr := input.Validate()
+	if len(validateErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, validateErr)
+		return
+	}
+
+	image.AltText = input.AltText
+	updatedImage, updateErr := h.gAi.UpdateImage(image)
+	if updateErr != nil {
+		c.JSON(http.StatusInternalServerError, updateErr)
+		return
+	}
+	if input.IsCover && !image.IsCover {
+		coverErr := h.gAi.SetCoverImage(food.ID, image.ID)
+		if coverErr != nil {
+			c.JSON(http.StatusInternalServerError, coverErr)
+			return
+		}
+		updatedImage.IsCover = true
+	}
+	c.JSON(http.StatusOK, h.galleryImages(food, []entity.GalleryImage{*updatedImage})[0])
+}
+
+func (h *GalleryHandler) DeleteImage(c *gin.Context) {
+	food, ok := h.ownFood(c)
+	if !ok {
+		return
+	}
+	image, ok := h.imageFromParams(c, food)
+	if !ok {
+		return
+	}
+	err := h.gAi.DeleteImage(image.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	// the file stays while another food, or a revision, still uses it
+	if err := h.fui.DeleteFile(image.Path); err != nil {
+		log.Printf("cannot delete image %s: %v", image.Path, err)
+	}
+	h.fui.SyncImageACL(image.Path)
+	c.JSON(http.StatusOK, "image deleted")
+}
+
+// ReorderImages expects the ids of all the images of the food in their new order:
+// {"image_ids": [3, 1, 2]}
+func (h *GalleryHandler) ReorderImages(c *gin.Context) {
+	food, ok := h.ownFood(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ImageIDs []uint64 `json:"image_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	reorderErr := h.gAi.ReorderImages(food.ID, input.ImageIDs)
+	if reorderErr != nil {
+		c.JSON(http.StatusUnprocessableEntity, reorderErr)
+		return
+	}
+
+	images, err := h.gAi.GetImagesByFood(food.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, h.galleryImages(food, images))
+}
+
+// galleryImages signs the urls of the images of a food that is not public, they are private in the storage
+func (h *GalleryHandler) galleryImages(food *entity.Food, images []entity.GalleryImage) []entity.GalleryImage {
+	if food.IsPublic() {
+		return images
+	}
+	return entity.SignGalleryImages(images, h.fui.MediaURL)
+}
+
+// ownFood loads the food of the :food_id param and makes sure the authenticated user owns it.
+// When it returns false, the response was already written.
+func (h *GalleryHandler) ownFood(c *gin.Context) (*entity.Food, bool) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return nil, false
+	}
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	if food.UserID != uId {
+		c.JSON(http.StatusUnauthorized, "you are not the owner of this food")
+		return nil, false
+	}
+	return food, true
+}
+
+func (h *GalleryHandler) imageFromParams(c *gin.Context, food *entity.Food) (*entity.GalleryImage, bool) {
+	imageId, err := strconv.ParseUint(c.Param("image_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return nil, false
+	}
+	image, err := h.gAi.GetImage(imageId)
+	if err != nil || image.FoodID != food.ID {
+		c.JSON(http.StatusNotFound, "image not found")
+		return nil, false
+	}
+	return image, true
+}
@@ -0,0 +1,358 @@
+package handler
+
+import (
+	"fmt"
+	"html"
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/auth"
+	"learning-golang-ddd/interface/calendar"
+	"learning-golang-ddd/interface/fileupload"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MealPlanHandler struct {
+	mAi application.MealPlanAppInterface
+	fAi application.FoodAppInterface
+	ai  auth.AuthInterface
+	ti  auth.TokenInterface
+	fui fileupload.UploadFileInterface
+}
+
+// MealPlanHandler constructor
+func NewMealPlanHandler(
+	mAi application.MealPlanAppInterface,
+	fAi application.FoodAppInterface,
+	ai auth.AuthInterface,
+	ti auth.TokenInterface,
+	fui fileupload.UploadFileInterface,
+) *MealPlanHandler {
+	return &MealPlanHandler{
+		mAi: mAi,
+		fAi: fAi,
+		ai:  ai,
+		ti:  ti,
+		fui: fui,
+	}
+}
+
+type mealPlanEntryInput struct {
+	Date     string  `json:"date"`
+	Slot     string  `json:"slot"`
+	FoodID   uint64  `json:"food_id"`
+	Servings float64 `json:"servings"`
+	Note     string  `json:"note"`
+}
+
+// GetMealPlan returns the plan between the from and to days, both included.
+// Without them, the current week is returned.
+func (h *MealPlanHandler) GetMealPlan(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	from := entity.WeekStart(time.Now())
+	if rawFrom := c.Query("from"); rawFrom != "" {
+		if from, err = entity.ParseDate(rawFrom); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"invalid_from": "from should be a date like 2021-07-26"})
+			return
+		}
+	}
+	to := from.AddDate(0, 0, 6)
+	if rawTo := c.Query("to"); rawTo != "" {
+		if to, err = entity.ParseDate(rawTo); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"invalid_to": "to should be a date like 2021-07-26"})
+			return
+		}
+	}
+	if to.Before(from) || to.Sub(from) >= entity.MaxMealPlanDays*24*time.Hour {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_range": fmt.Sprintf("to should be after from, and at most %d days later", entity.MaxMealPlanDays-1),
+		})
+		return
+	}
+
+	plan, err := h.mAi.GetMealPlan(uId, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	plan.HideFoodsFrom(uId)
+	c.JSON(http.StatusOK, plan.SignURLs(h.fui.MediaURL))
+}
+
+func (h *MealPlanHandler) SaveMealPlanEntry(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var input mealPlanEntryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	entry := entity.MealPlanEntry{UserID: uId}
+	if entryErr := h.applyEntryInput(&entry, &input, uId); len(entryErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, entryErr)
+		return
+	}
+
+	savedEntry, saveErr := h.mAi.SaveMealPlanEntry(&entry)
+	if saveErr != nil {
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	c.JSON(http.StatusCreated, savedEntry.SignURLs(h.fui.MediaURL))
+}
+
+func (h *MealPlanHandler) UpdateMealPlanEntry(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	entryId, err := strconv.ParseUint(c.Param("entry_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	var input mealPlanEntryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	entry, err := h.mAi.GetMealPlanEntry(entryId)
+	if err != nil || entry.UserID != uId {
+		c.JSON(http.StatusNotFound, "meal plan entry not found")
+		return
+	}
+	createdAt := entry.CreatedAt
+	if entryErr := h.applyEntryInput(entry, &input, uId); len(entryErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, entryErr)
+		return
+	}
+	entry.CreatedAt = createdAt
+
+	updatedEntry, updateErr := h.mAi.UpdateMealPlanEntry(entry)
+	if updateErr != nil {
+		c.JSON(http.StatusInternalServerError, updateErr)
+		return
+	}
+	c.JSON(http.StatusOK, updatedEntry.SignURLs(h.fui.MediaURL))
+}
+
+func (h *MealPlanHandler) DeleteMealPlanEntry(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	entryId, err := strconv.ParseUint(c.Param("entry_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	entry, err := h.mAi.GetMealPlanEntry(entryId)
+	if err != nil || entry.UserID != uId {
+		c.JSON(http.StatusNotFound, "meal plan entry not found")
+		return
+	}
+	if err := h.mAi.DeleteMealPlanEntry(entryId); err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, "meal plan entry deleted")
+}
+
+// CopyMealPlanWeek copies the week of the from day to the week of the to day.
+// With replace, the meals already planned in the target week are removed first.
+func (h *MealPlanHandler) CopyMealPlanWeek(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var input struct {
+		From    string `json:"from"`
+		To      string `json:"to"`
+		Replace bool   `json:"replace"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	copyErr := map[string]string{}
+	from, err := entity.ParseDate(input.From)
+	if err != nil {
+		copyErr["invalid_from"] = "from should be a date like 2021-07-26"
+	}
+	to, err := entity.ParseDate(input.To)
+	if err != nil {
+		copyErr["invalid_to"] = "to should be a date like 2021-07-26"
+	}
+	if len(copyErr) == 0 && entity.WeekStart(from).Equal(entity.WeekStart(to)) {
+		copyErr["same_week"] = "a week cannot be copied to itself"
+	}
+	if len(copyErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, copyErr)
+		return
+	}
+
+	copies, err := h.mAi.CopyMealPlanWeek(uId, from, to, input.Replace)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, "database error, please try again")
+		return
+	}
+	weekStart := entity.WeekStart(to)
+	plan := entity.NewMealPlan(weekStart, weekStart.AddDate(0, 0, 6), copies)
+	plan.HideFoodsFrom(uId)
+	c.JSON(http.StatusCreated, plan.SignURLs(h.fui.MediaURL))
+}
+
+// SaveCalendarFeed returns the url of the iCalendar feed of the meal plan. Every call gives a new url,
+// the previous one stops working.
+func (h *MealPlanHandler) SaveCalendarFeed(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	feed, err := entity.NewCalendarFeed(uId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, "cannot create the calendar feed")
+		return
+	}
+	savedFeed, saveErr := h.mAi.SaveCalendarFeed(feed)
+	if saveErr != nil {
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"url": fmt.Sprintf("/calendar/%s/meal-plan.ics", savedFeed.Token),
+	})
+}
+
+// GetCalendar is the iCalendar feed calendar apps subscribe to. It holds the meals from four weeks ago
+// to twelve weeks ahead.
+func (h *MealPlanHandler) GetCalendar(c *gin.Context) {
+	feed, err := h.mAi.GetCalendarFeed(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, "calendar not found")
+		return
+	}
+	weekStart := entity.WeekStart(time.Now())
+	plan, err := h.mAi.GetMealPlan(feed.UserID, weekStart.AddDate(0, 0, -28), weekStart.AddDate(0, 0, 12*7-1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	plan.HideFoodsFrom(feed.UserID)
+	events := []calendar.Event{}
+	for _, day := range plan.Days {
+		for _, slot := range entity.MealSlots {
+			for _, entry := range day.Meals[slot] {
+				events = append(events, mealPlanEvent(&entry))
+			}
+		}
+	}
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", `inline; filename="meal-plan.ics"`)
+	c.Status(http.StatusOK)
+	calendar.Write(c.Writer, "Meal plan", events)
+}
+
+// applyEntryInput validates the input and sets it on the entry. The food has to be visible to the user.
+func (h *MealPlanHandler) applyEntryInput(entry *entity.MealPlanEntry, input *mealPlanEntryInput, uId uint64) map[string]string {
+	entry.Slot = entity.MealSlot(input.Slot)
+	entry.FoodID = input.FoodID
+	entry.Servings = input.Servings
+	entry.Note = input.Note
+	entry.Prepare()
+
+	entryErr := entry.Validate()
+	date, err := entity.ParseDate(input.Date)
+	if err != nil {
+		entryErr["invalid_date"] = "date should be a date like 2021-07-26"
+	}
+	entry.Date = date
+	if len(entryErr) > 0 {
+		return entryErr
+	}
+	food, err := h.fAi.GetFood(entry.FoodID)
+	if err != nil || !food.IsVisibleTo(uId) {
+		entryErr["food_not_found"] = "food not found"
+		return entryErr
+	}
+	entry.Food = food
+	return entryErr
+}
+
+func mealPlanEvent(entry *entity.MealPlanEntry) calendar.Event {
+	title := "a food"
+	if entry.Food != nil {
+		title = html.UnescapeString(entry.Food.Title)
+	}
+	slot := string(entry.Slot)
+	description := strconv.FormatFloat(entry.Servings, 'f', -1, 64) + " serving"
+	if entry.Servings != 1 {
+		description += "s"
+	}
+	if entry.Note != "" {
+		description += "\n" + html.UnescapeString(entry.Note)
+	}
+	return calendar.Event{
+		UID:         fmt.Sprintf("meal-plan-entry-%d@learning-golang-ddd", entry.ID),
+		Summary:     strings.ToUpper(slot[:1]) + slot[1:] + ": " + title,
+		Description: description,
+		Start:       entry.Start(),
+		End:         entry.Start().Add(entity.MealDuration),
+	}
+}
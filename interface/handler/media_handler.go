@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"fmt"
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/auth"
+	"learning-golang-ddd/infrastructure/storage"
+	"learning-golang-ddd/interface/fileupload"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MediaHandler struct {
+	st  storage.Storage
+	gAi application.GalleryAppInterface
+	ai  auth.AuthInterface
+	ti  auth.TokenInterface
+}
+
+// MediaHandler constructor
+func NewMediaHandler(st storage.Storage, gAi application.GalleryAppInterface, ai auth.AuthInterface, ti auth.TokenInterface) *MediaHandler {
+	return &MediaHandler{
+		st:  st,
+		gAi: gAi,
+		ai:  ai,
+		ti:  ti,
+	}
+}
+
+// GetMedia serves the stored images and videos, private ones included, e.g the image of a draft.
+// A private image is served with the signed query of its media url until it expires,
+// or to a signed in user who can see a food using it.
+// The ranges and the conditional requests are answered by http.ServeContent.
+func (h *MediaHandler) GetMedia(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	info, err := h.st.Stat(key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, "file not found")
+		return
+	}
+	signed := fileupload.VerifyMediaURL(key, c.Request.URL.Query(), time.Now())
+	if !info.Public && !signed && !h.canView(c, key) {
+		c.JSON(http.StatusNotFound, "file not found")
+		return
+	}
+	file, err := h.st.Get(key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, "file not found")
+		return
+	}
+	defer file.Close()
+	content, err := seekable(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, "cannot read the file")
+		return
+	}
+
+	if info.ContentType != "" {
+		c.Header("Content-Type", info.ContentType)
+	}
+	switch {
+	case info.Public:
+		c.Header("Cache-Control", info.CacheControl)
+	case signed:
+		// the browser keeps the image until the url expires, nobody else does
+		expiresAt, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", expiresAt-time.Now().Unix()))
+	default:
+		c.Header("Cache-Control", "private, no-cache")
+	}
+	// the file of a key only changes when it is stored again, its time and size tell a version
+	c.Header("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime.Unix(), info.Size))
+	c.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, "", info.ModTime, content)
+}
+
+// canView tells whether the signed in user can see a food, revision or upload using the image
+func (h *MediaHandler) canView(c *gin.Context, key string) bool {
+	userId := viewerId(c, h.ti, h.ai)
+	if userId == 0 {
+		return false
+	}
+	visible, err := h.gAi.IsImageVisibleTo(h.st.URL(entity.ImageSetKey(key)), userId)
+	if err != nil {
+		log.Printf("cannot check who can see image %s: %v", key, err)
+		return false
+	}
+	return visible
+}
@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/auth"
+	"learning-golang-ddd/infrastructure/event"
+	"learning-golang-ddd/interface/fileupload"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ModerationHandler struct {
+	mAi application.ModerationAppInterface
+	fAi application.FoodAppInterface
+	cAi application.CommentAppInterface
+	uAi application.UserAppInterface
+	ai  auth.AuthInterface
+	ti  auth.TokenInterface
+	pi  event.PublisherInterface
+	fui fileupload.UploadFileInterface
+}
+
+// ModerationHandler constructor
+func NewModerationHandler(
+	mAi application.ModerationAppInterface,
+	fAi application.FoodAppInterface,
+	cAi application.CommentAppInterface,
+	uAi application.UserAppInterface,
+	ai auth.AuthInterface,
+	ti auth.TokenInterface,
+	pi event.PublisherInterface,
+	fui fileupload.UploadFileInterface,
+) *ModerationHandler {
+	return &ModerationHandler{
+		mAi: mAi,
+		fAi: fAi,
+		cAi: cAi,
+		uAi: uAi,
+		ai:  ai,
+		ti:  ti,
+		pi:  pi,
+		fui: fui,
+	}
+}
+
+type reportInput struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+// ReportFood puts the food in the moderation queue: {"reason": "spam", "details": "..."}
+func (h *ModerationHandler) ReportFood(c *gin.Context) {
+	user, ok := h.authenticatedUser(c)
+	if !ok {
+		return
+	}
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil || !food.IsVisibleTo(uint64(user.ID)) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+	h.saveReport(c, user, entity.ReportTargetFood, food.ID, food.UserID)
+}
+
+func (h *ModerationHandler) ReportComment(c *gin.Context) {
+	user, ok := h.authenticatedUser(c)
+	if !ok {
+		return
+	}
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	commentId, err := strconv.ParseUint(c.Param("comment_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil || !food.IsVisibleTo(uint64(user.ID)) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+	comment, err := h.cAi.GetComment(commentId)
+	if err != nil || comment.FoodID != food.ID || comment.IsDeleted() {
+		c.JSON(http.StatusNotFound, "comment not found")
+		return
+	}
+	h.saveReport(c, user, entity.ReportTargetComment, comment.ID, comment.UserID)
+}
+
+func (h *ModerationHandler) saveReport(c *gin.Context, user *entity.User, target entity.ReportTarget, targetId uint64, authorId uint64) {
+	var input reportInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	reporterId := uint64(user.ID)
+	if reporterId == authorId {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"own_content": "you cannot report your own " + string(target)})
+		return
+	}
+	report := entity.Report{
+		ReporterID: &reporterId,
+		TargetType: target,
+		TargetID:   targetId,
+		AuthorID:   authorId,
+		Reason:     entity.ReportReason(input.Reason),
+		Details:    input.Details,
+	}
+	report.Prepare()
+	if reportErr := report.Validate(); len(reportErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, reportErr)
+		return
+	}
+	savedReport, saveErr := h.mAi.SaveReport(&report)
+	if _, ok := saveErr["already_reported"]; ok {
+		c.JSON(http.StatusConflict, saveErr)
+		return
+	}
+	if saveErr != nil {
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	c.JSON(http.StatusCreated, savedReport)
+}
+
+// GetReports is the moderation queue, the open reports oldest first. Admins only.
+// ?status=resolved lists the decisions made.
+func (h *ModerationHandler) GetReports(c *gin.Context) {
+	if _, ok := h.admin(c); !ok {
+		return
+	}
+	status := entity.ReportStatus(c.DefaultQuery("status", string(entity.ReportStatusOpen)))
+	if status != entity.ReportStatusOpen && status != entity.ReportStatusResolved {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"invalid_status": "status should be open or resolved"})
+		return
+	}
+	page := paginationFromQuery(c)
+	reports, err := h.mAi.GetReports(status, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"reports":    reports,
+		"pagination": page,
+	})
+}
+
+func (h *ModerationHandler) ApproveReport(c *gin.Context) {
+	h.moderateReport(c, entity.ModerationApprove)
+}
+
+func (h *ModerationHandler) HideReported(c *gin.Context) {
+	h.moderateReport(c, entity.ModerationHide)
+}
+
+func (h *ModerationHandler) BanReported(c *gin.Context) {
+	h.moderateReport(c, entity.ModerationBan)
+}
+
+// moderateReport applies the decision to the reported content, every open report of the content is resolved with it
+func (h *ModerationHandler) moderateReport(c *gin.Context, action entity.ModerationAction) {
+	moderator, ok := h.admin(c)
+	if !ok {
+		return
+	}
+	reportId, err := strconv.ParseUint(c.Param("report_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	report, err := h.mAi.GetReport(reportId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, "report not found")
+		return
+	}
+	if action == entity.ModerationBan {
+		author, err := h.uAi.GetUser(report.AuthorID)
+		if err == nil && author.IsAdmin {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"cannot_ban_admin": "admins cannot be banned"})
+			return
+		}
+	}
+	resolved, moderateErr := h.mAi.ModerateReport(report, action, uint64(moderator.ID))
+	if _, ok := moderateErr["report_resolved"]; ok {
+		c.JSON(http.StatusConflict, moderateErr)
+		return
+	}
+	if moderateErr != nil {
+		c.JSON(http.StatusInternalServerError, moderateErr)
+		return
+	}
+	if action != entity.ModerationApprove {
+		if err := h.pi.Publish(entity.NewContentHiddenEvent(&resolved[0])); err != nil {
+			log.Printf("cannot publish %s event for %s %d: %v", entity.EventContentHidden, report.TargetType, report.TargetID, err)
+		}
+	}
+	// the images of a hidden food are private again
+	if report.TargetType == entity.ReportTargetFood {
+		if food, err := h.fAi.GetFood(report.TargetID); err == nil {
+			h.fui.SyncImageACL(food.ImagePaths()...)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"resolved": resolved})
+}
+
+// admin loads the user making the request, answering itself when they are not an admin
+func (h *ModerationHandler) admin(c *gin.Context) (*entity.User, bool) {
+	user, ok := h.authenticatedUser(c)
+	if !ok {
+		return nil, false
+	}
+	if !user.IsAdmin {
+		c.JSON(http.StatusUnauthorized, "only admins can moderate content")
+		return nil, false
+	}
+	return user, true
+}
+
+// authenticatedUser loads the user making the request. When it returns false, the response was already written.
+func (h *ModerationHandler) authenticatedUser(c *gin.Context) (*entity.User, bool) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	user, err := h.uAi.GetUser(uId)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "user not found, unauthorized")
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/infrastructure/auth"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	nAi application.NotificationAppInterface
+	ai  auth.AuthInterface
+	ti  auth.TokenInterface
+}
+
+// NotificationHandler constructor
+func NewNotificationHandler(
+	nAi application.NotificationAppInterface,
+	ai auth.AuthInterface,
+	ti auth.TokenInterface,
+) *NotificationHandler {
+	return &NotificationHandler{
+		nAi: nAi,
+		ai:  ai,
+		ti:  ti,
+	}
+}
+
+// GetNotifications returns a page of the user's notifications, the newest first
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	page := paginationFromQuery(c)
+	notifications, err := h.nAi.GetNotifications(uId, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"pagination":    page,
+	})
+}
+
+func (h *NotificationHandler) ReadNotification(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	notificationId, err := strconv.ParseUint(c.Param("notification_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	notification, err := h.nAi.GetNotification(notificationId)
+	if err != nil || notification.UserID != uId {
+		c.JSON(http.StatusNotFound, "notification not found")
+		return
+	}
+	notification.MarkRead()
+	if updateErr := h.nAi.MarkNotificationRead(notification); updateErr != nil {
+		c.JSON(http.StatusInternalServerError, updateErr)
+		return
+	}
+	c.JSON(http.StatusOK, notification)
+}
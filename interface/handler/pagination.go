@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"learning-golang-ddd/domain/entity"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// paginationFromQuery reads the ?page= and ?per_page= query params. Invalid values fall back to the defaults.
+func paginationFromQuery(c *gin.Context) *entity.Pagination {
+	page, _ := strconv.Atoi(c.Query("page"))
+	perPage, _ := strconv.Atoi(c.Query("per_page"))
+	pagination := &entity.Pagination{Page: page, PerPage: perPage}
+	pagination.Prepare()
+	return pagination
+}
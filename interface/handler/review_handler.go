@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/auth"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReviewHandler struct {
+	rAi  application.RatingAppInterface
+	rvAi application.ReviewAppInterface
+	fAi  application.FoodAppInterface
+	ai   auth.AuthInterface
+	ti   auth.TokenInterface
+}
+
+// ReviewHandler constructor
+func NewReviewHandler(
+	rAi application.RatingAppInterface,
+	rvAi application.ReviewAppInterface,
+	fAi application.FoodAppInterface,
+	ai auth.AuthInterface,
+	ti auth.TokenInterface,
+) *ReviewHandler {
+	return &ReviewHandler{
+		rAi:  rAi,
+		rvAi: rvAi,
+		fAi:  fAi,
+		ai:   ai,
+		ti:   ti,
+	}
+}
+
+func (h *ReviewHandler) RateFood(c *gin.Context) {
+	// check is the user is authenticated first
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	// lookup the metadata in redis:
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	var rating entity.Rating
+	if err := c.ShouldBindJSON(&rating); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	validateErr := rating.Validate()
+	if len(validateErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, validateErr)
+		return
+	}
+
+	// check if the food exist
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil || !food.IsVisibleTo(uId) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+
+	rating.Prepare()
+	rating.FoodID = foodId
+	rating.UserID = uId
+	savedRating, saveErr := h.rAi.SaveRating(&rating)
+	if saveErr != nil {
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	c.JSON(http.StatusOK, savedRating)
+}
+
+func (h *ReviewHandler) DeleteRating(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	err = h.rAi.DeleteRating(foodId, uId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, "rating deleted")
+}
+
+func (h *ReviewHandler) SaveReview(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	var review entity.Review
+	if err := c.ShouldBindJSON(&review); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	review.Prepare()
+	validateErr := review.Validate()
+	if len(validateErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, validateErr)
+		return
+	}
+
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil || !food.IsVisibleTo(uId) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+
+	review.FoodID = foodId
+	review.UserID = uId
+	savedReview, saveErr := h.rvAi.SaveReview(&review)
+	if saveErr != nil {
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	c.JSON(http.StatusCreated, savedReview)
+}
+
+func (h *ReviewHandler) GetReviews(c *gin.Context) {
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	// the reviews of a food are seen by whoever can see the food
+	food, err := h.fAi.GetFood(foodId)
+	if err != nil || !food.IsVisibleTo(viewerId(c, h.ti, h.ai)) {
+		c.JSON(http.StatusNotFound, "food not found")
+		return
+	}
+	reviews, err := h.rvAi.GetReviewsByFood(foodId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, reviews)
+}
+
+func (h *ReviewHandler) UpdateReview(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	reviewId, err := strconv.ParseUint(c.Param("review_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	var input entity.Review
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	input.Prepare()
+	validateErr := input.Validate()
+	if len(validateErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, validateErr)
+		return
+	}
+
+	review, err := h.rvAi.GetReview(reviewId)
+	if err != nil || review.FoodID != foodId {
+		c.JSON(http.StatusNotFound, "review not found")
+		return
+	}
+	// only the author can edit the review
+	if review.UserID != uId {
+		c.JSON(http.StatusUnauthorized, "you are not the author of this review")
+		return
+	}
+
+	review.Body = input.Body
+	review.UpdatedAt = time.Now()
+	updatedReview, updateErr := h.rvAi.UpdateReview(review)
+	if updateErr != nil {
+		c.JSON(http.StatusInternalServerError, updateErr)
+		return
+	}
+	c.JSON(http.StatusOK, updatedReview)
+}
+
+func (h *ReviewHandler) DeleteReview(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	foodId, err := strconv.ParseUint(c.Param("food_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	reviewId, err := strconv.ParseUint(c.Param("review_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	review, err := h.rvAi.GetReview(reviewId)
+	if err != nil || review.FoodID != foodId {
+		c.JSON(http.StatusNotFound, "review not found")
+		return
+	}
+	// only the author can delete the review
+	if review.UserID != uId {
+		c.JSON(http.StatusUnauthorized, "you are not the author of this review")
+		return
+	}
+	err = h.rvAi.DeleteReview(reviewId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, "review deleted")
+}
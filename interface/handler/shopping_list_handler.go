@@ -0,0 +1,371 @@
+package handler
+
+import (
+	"fmt"
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/auth"
+	"learning-golang-ddd/infrastructure/security"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShoppingListHandler struct {
+	sAi application.ShoppingListAppInterface
+	fAi application.FoodAppInterface
+	mAi application.MealPlanAppInterface
+	ai  auth.AuthInterface
+	ti  auth.TokenInterface
+}
+
+// ShoppingListHandler constructor
+func NewShoppingListHandler(
+	sAi application.ShoppingListAppInterface,
+	fAi application.FoodAppInterface,
+	mAi application.MealPlanAppInterface,
+	ai auth.AuthInterface,
+	ti auth.TokenInterface,
+) *ShoppingListHandler {
+	return &ShoppingListHandler{
+		sAi: sAi,
+		fAi: fAi,
+		mAi: mAi,
+		ai:  ai,
+		ti:  ti,
+	}
+}
+
+// the most foods a list can be generated from
+const maxShoppingListFoods = 100
+
+type shoppingListInput struct {
+	Name    string   `json:"name"`
+	FoodIDs []uint64 `json:"food_ids"`
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+}
+
+type shoppingListItemInput struct {
+	Name     *string  `json:"name"`
+	Quantity *float64 `json:"quantity"`
+	Unit     *string  `json:"unit"`
+	Aisle    *string  `json:"aisle"`
+	Checked  *bool    `json:"checked"`
+}
+
+// SaveShoppingList generates a list from the selected foods, from the meal plan between from and to, or both.
+// A food listed twice, or planned for several meals, is bought for each time it is cooked.
+func (h *ShoppingListHandler) SaveShoppingList(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var input shoppingListInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	list := entity.ShoppingList{UserID: uId, Name: input.Name}
+	list.Prepare()
+	listErr := list.Validate()
+
+	servings := map[uint64]float64{}
+	for _, foodId := range input.FoodIDs {
+		servings[foodId]++
+	}
+	if input.From != "" || input.To != "" {
+		from, fromErr := entity.ParseDate(input.From)
+		to, toErr := entity.ParseDate(input.To)
+		switch {
+		case fromErr != nil:
+			listErr["invalid_from"] = "from should be a date like 2021-07-26"
+		case toErr != nil:
+			listErr["invalid_to"] = "to should be a date like 2021-07-26"
+		case to.Before(from) || to.Sub(from) >= entity.MaxMealPlanDays*24*time.Hour:
+			listErr["invalid_range"] = fmt.Sprintf("to should be after from, and at most %d days later", entity.MaxMealPlanDays-1)
+		default:
+			plan, err := h.mAi.GetMealPlan(uId, from, to)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, err.Error())
+				return
+			}
+			for _, day := range plan.Days {
+				for _, entries := range day.Meals {
+					for _, entry := range entries {
+						servings[entry.FoodID] += entry.Servings
+					}
+				}
+			}
+		}
+	}
+	if len(listErr) == 0 && len(servings) == 0 {
+		listErr["foods_required"] = "select some foods or a meal plan with planned meals"
+	}
+	if len(servings) > maxShoppingListFoods {
+		listErr["too_many_foods"] = fmt.Sprintf("a list can be made of at most %d foods", maxShoppingListFoods)
+	}
+	if len(listErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, listErr)
+		return
+	}
+
+	foods := make([]entity.Food, 0, len(servings))
+	for foodId := range servings {
+		food, err := h.fAi.GetFood(foodId)
+		if err != nil || !food.IsVisibleTo(uId) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"invalid_food": fmt.Sprintf("food %d not found", foodId)})
+			return
+		}
+		foods = append(foods, *food)
+	}
+
+	savedList, saveErr := h.sAi.GenerateShoppingList(&list, foods, servings)
+	if saveErr != nil {
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	c.JSON(http.StatusCreated, shoppingListResponse(savedList))
+}
+
+func (h *ShoppingListHandler) GetShoppingLists(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	lists, err := h.sAi.GetShoppingListsByUser(uId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, lists)
+}
+
+func (h *ShoppingListHandler) GetShoppingList(c *gin.Context) {
+	list, ok := h.ownedShoppingList(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, shoppingListResponse(list))
+}
+
+func (h *ShoppingListHandler) DeleteShoppingList(c *gin.Context) {
+	list, ok := h.ownedShoppingList(c)
+	if !ok {
+		return
+	}
+	if err := h.sAi.DeleteShoppingList(list.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, "shopping list deleted")
+}
+
+// SaveShoppingListItem adds an item by hand, it is never merged with the ingredients of the foods
+func (h *ShoppingListHandler) SaveShoppingListItem(c *gin.Context) {
+	list, ok := h.ownedShoppingList(c)
+	if !ok {
+		return
+	}
+	var input shoppingListItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	item := entity.ShoppingListItem{ListID: list.ID, Manual: true}
+	input.apply(&item)
+	item.Prepare()
+	if itemErr := item.Validate(); len(itemErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, itemErr)
+		return
+	}
+	savedItem, saveErr := h.sAi.SaveShoppingListItem(&item)
+	if saveErr != nil {
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	c.JSON(http.StatusCreated, savedItem)
+}
+
+// UpdateShoppingListItem changes only the given fields, e.g {"checked": true} checks an item off
+func (h *ShoppingListHandler) UpdateShoppingListItem(c *gin.Context) {
+	list, ok := h.ownedShoppingList(c)
+	if !ok {
+		return
+	}
+	item, ok := h.listItem(c, list)
+	if !ok {
+		return
+	}
+	var input shoppingListItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	createdAt := item.CreatedAt
+	input.apply(item)
+	item.Prepare()
+	item.CreatedAt = createdAt
+	if itemErr := item.Validate(); len(itemErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, itemErr)
+		return
+	}
+	updatedItem, updateErr := h.sAi.UpdateShoppingListItem(item)
+	if updateErr != nil {
+		c.JSON(http.StatusInternalServerError, updateErr)
+		return
+	}
+	c.JSON(http.StatusOK, updatedItem)
+}
+
+func (h *ShoppingListHandler) DeleteShoppingListItem(c *gin.Context) {
+	list, ok := h.ownedShoppingList(c)
+	if !ok {
+		return
+	}
+	item, ok := h.listItem(c, list)
+	if !ok {
+		return
+	}
+	if err := h.sAi.DeleteShoppingListItem(item.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, "item deleted")
+}
+
+// ShareShoppingList returns a signed read-only link to the list. Sharing again revokes the previous links.
+func (h *ShoppingListHandler) ShareShoppingList(c *gin.Context) {
+	list, ok := h.ownedShoppingList(c)
+	if !ok {
+		return
+	}
+	if err := list.Share(); err != nil {
+		c.JSON(http.StatusInternalServerError, "cannot share the shopping list")
+		return
+	}
+	if updateErr := h.sAi.UpdateShoppingListShare(list); updateErr != nil {
+		c.JSON(http.StatusInternalServerError, updateErr)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"share_url": fmt.Sprintf("/shared/shopping-lists/%d?sig=%s", list.ID, security.Sign(shoppingListShareMessage(list))),
+	})
+}
+
+// UnshareShoppingList revokes every link to the list
+func (h *ShoppingListHandler) UnshareShoppingList(c *gin.Context) {
+	list, ok := h.ownedShoppingList(c)
+	if !ok {
+		return
+	}
+	list.Unshare()
+	if updateErr := h.sAi.UpdateShoppingListShare(list); updateErr != nil {
+		c.JSON(http.StatusInternalServerError, updateErr)
+		return
+	}
+	c.JSON(http.StatusOK, "shopping list is no longer shared")
+}
+
+// GetSharedShoppingList reads a list with a link made by ShareShoppingList, the signature is the only credential
+func (h *ShoppingListHandler) GetSharedShoppingList(c *gin.Context) {
+	listId, err := strconv.ParseUint(c.Param("list_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return
+	}
+	list, err := h.sAi.GetShoppingList(listId)
+	if err != nil || !list.IsShared() || !security.VerifySignature(shoppingListShareMessage(list), c.Query("sig")) {
+		c.JSON(http.StatusNotFound, "shopping list not found")
+		return
+	}
+	c.Header("Cache-Control", "private, no-store")
+	c.JSON(http.StatusOK, shoppingListResponse(list))
+}
+
+// ownedShoppingList loads the list of the url, it answers itself when the list is not the user's
+func (h *ShoppingListHandler) ownedShoppingList(c *gin.Context) (*entity.ShoppingList, bool) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	listId, err := strconv.ParseUint(c.Param("list_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return nil, false
+	}
+	list, err := h.sAi.GetShoppingList(listId)
+	if err != nil || list.UserID != uId {
+		c.JSON(http.StatusNotFound, "shopping list not found")
+		return nil, false
+	}
+	return list, true
+}
+
+func (h *ShoppingListHandler) listItem(c *gin.Context, list *entity.ShoppingList) (*entity.ShoppingListItem, bool) {
+	itemId, err := strconv.ParseUint(c.Param("item_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return nil, false
+	}
+	item, err := h.sAi.GetShoppingListItem(itemId)
+	if err != nil || item.ListID != list.ID {
+		c.JSON(http.StatusNotFound, "item not found")
+		return nil, false
+	}
+	return item, true
+}
+
+func (input *shoppingListItemInput) apply(item *entity.ShoppingListItem) {
+	if input.Name != nil {
+		item.Name = *input.Name
+	}
+	if input.Quantity != nil {
+		item.Quantity = *input.Quantity
+	}
+	if input.Unit != nil {
+		item.Unit = *input.Unit
+	}
+	if input.Aisle != nil {
+		item.Aisle = *input.Aisle
+	}
+	if input.Checked != nil {
+		item.Checked = *input.Checked
+	}
+}
+
+// the signed message carries the nonce of the list, so a new nonce revokes the links made before
+func shoppingListShareMessage(list *entity.ShoppingList) string {
+	return fmt.Sprintf("shopping-list:%d:%s", list.ID, list.ShareNonce)
+}
+
+func shoppingListResponse(list *entity.ShoppingList) gin.H {
+	return gin.H{"shopping_list": list, "aisles": list.Aisles()}
+}
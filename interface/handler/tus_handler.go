@@ -0,0 +1,361 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/auth"
+	"learning-golang-ddd/interface/fileupload"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,expiration,termination"
+	// the content type of the chunks sent with PATCH
+	tusChunkContentType = "application/offset+octet-stream"
+)
+
+// TusHandler receives resumable uploads with the tus 1.0 protocol, https://tus.io/protocols/resumable-upload.
+// A tus upload is an entity.Upload whose file comes in chunks, once complete it is confirmed like the presigned ones
+// and its id can be given as the upload_id of a food.
+type TusHandler struct {
+	upAi application.UploadAppInterface
+	fui  fileupload.UploadFileInterface
+	ai   auth.AuthInterface
+	ti   auth.TokenInterface
+}
+
+// TusHandler constructor
+func NewTusHandler(
+	upAi application.UploadAppInterface,
+	fui fileupload.UploadFileInterface,
+	ai auth.AuthInterface,
+	ti auth.TokenInterface,
+) *TusHandler {
+	return &TusHandler{
+		upAi: upAi,
+		fui:  fui,
+		ai:   ai,
+		ti:   ti,
+	}
+}
+
+// Options tells the clients which version and extensions of the protocol are supported
+func (h *TusHandler) Options(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(entity.MaxUploadSizeOfAll(), 10))
+	c.Status(http.StatusNoContent)
+}
+
+// CreateUpload starts an upload of Upload-Length bytes. The Upload-Metadata header should have the filetype,
+// one of the image or video types we accept, it tells how large the file can be. The url of the upload is in the Location header.
+func (h *TusHandler) CreateUpload(c *gin.Context) {
+	if !tusRequest(c) {
+		return
+	}
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		c.JSON(http.StatusBadRequest, "the length of the upload should be given with Upload-Length")
+		return
+	}
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		c.JSON(http.StatusBadRequest, "Upload-Length should be the size of the file")
+		return
+	}
+	uploadMetadata, err := parseTusMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	maxSize := entity.MaxUploadSizeOfAll()
+	if policy, ok := entity.UploadPolicyFor(uploadMetadata["filetype"]); ok {
+		maxSize = policy.MaxSize
+	}
+	if length > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, fmt.Sprintf("the file should be at most %d bytes", maxSize))
+		return
+	}
+	upload, err := entity.NewResumableUpload(uId, uploadMetadata["filetype"], length, c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, "cannot create the upload")
+		return
+	}
+	uploadErr := upload.Validate()
+	if len(uploadErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, uploadErr)
+		return
+	}
+	savedUpload, saveErr := h.upAi.SaveUpload(upload)
+	if saveErr != nil {
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/tus/%d", savedUpload.ID))
+	c.Header("Upload-Expires", savedUpload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// GetUploadOffset tells the client where to resume the upload from
+func (h *TusHandler) GetUploadOffset(c *gin.Context) {
+	upload, ok := h.ownUpload(c)
+	if !ok {
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.MaxSize, 10))
+	if upload.Metadata != "" {
+		c.Header("Upload-Metadata", upload.Metadata)
+	}
+	if upload.Status == entity.UploadStatusPending {
+		c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	c.Status(http.StatusOK)
+}
+
+// PatchUpload adds a chunk at Upload-Offset, which should be where the upload is at. The chunk is streamed to the
+// storage, when the connection drops it is sent again from the offset the client asks for.
+// The last chunk turns the file into an image, the same way as UploadFile. When the file cannot be scanned,
+// the upload stays at its last offset and the last chunk, even empty, can be sent again.
+func (h *TusHandler) PatchUpload(c *gin.Context) {
+	upload, ok := h.ownUpload(c)
+	if !ok {
+		return
+	}
+	if c.ContentType() != tusChunkContentType {
+		c.JSON(http.StatusUnsupportedMediaType, "the chunk should be sent as "+tusChunkContentType)
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, "Upload-Offset should be where the upload is at")
+		return
+	}
+	if offset != upload.Offset {
+		c.JSON(http.StatusConflict, fmt.Sprintf("the upload is at offset %d", upload.Offset))
+		return
+	}
+	if upload.Status != entity.UploadStatusPending {
+		tusOffsetHeaders(c, upload)
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	// the chunk is streamed to the storage, its length is needed up front
+	size := c.Request.ContentLength
+	if size < 0 {
+		c.JSON(http.StatusLengthRequired, "the chunk should be sent with a Content-Length")
+		return
+	}
+	if size > upload.MaxSize-upload.Offset {
+		c.JSON(http.StatusRequestEntityTooLarge, "the chunk goes past Upload-Length")
+		return
+	}
+	if size > 0 {
+		// the bytes are claimed before they are stored, so two chunks sent at once do not both get them
+		from := upload.Offset
+		upload.AddChunk(size)
+		err := h.upAi.UpdateUploadOffset(upload, from)
+		if errors.Is(err, entity.ErrUploadOffset) {
+			c.JSON(http.StatusConflict, "the upload offset changed, ask for it again")
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+		chunkKey := upload.ChunkKey(upload.Chunks - 1)
+		// no more than the claimed bytes are read, a connection that drops before them fails the chunk
+		if err := h.fui.SaveFile(chunkKey, io.LimitReader(c.Request.Body, size), size, "application/octet-stream"); err != nil {
+			log.Printf("cannot store chunk %s: %v", chunkKey, err)
+			// give the bytes back, the client sends them again
+			claimed := upload.Offset
+			upload.Offset = from
+			upload.Chunks--
+			if err := h.upAi.UpdateUploadOffset(upload, claimed); err != nil {
+				log.Printf("cannot give back the chunk %s: %v", chunkKey, err)
+			}
+			c.JSON(http.StatusInternalServerError, "the chunk could not be stored")
+			return
+		}
+	}
+	if upload.IsComplete() {
+		err := h.finish(upload)
+		if errors.Is(err, fileupload.ErrScanUnavailable) {
+			// the upload stays pending, the client sends the last PATCH again a bit later
+			c.Header("Retry-After", "60")
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"upload_error": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"upload_error": err.Error(),
+			})
+			return
+		}
+	}
+	tusOffsetHeaders(c, upload)
+	c.Status(http.StatusNoContent)
+}
+
+// TerminateUpload stops an upload and removes what was received. An image already attached to a food stays.
+func (h *TusHandler) TerminateUpload(c *gin.Context) {
+	upload, ok := h.ownUpload(c)
+	if !ok {
+		return
+	}
+	if upload.Status == entity.UploadStatusAttached {
+		c.JSON(http.StatusConflict, "the upload is used by a food")
+		return
+	}
+	h.remove(upload)
+	c.Status(http.StatusNoContent)
+}
+
+// finish joins the chunks and checks the file. An invalid file is removed with its upload, the client starts over.
+// A file that cannot be scanned keeps its upload pending.
+func (h *TusHandler) finish(upload *entity.Upload) error {
+	if err := h.fui.AssembleUpload(upload.Key, upload.ChunkKeys(), upload.MaxSize, upload.ContentType); err != nil {
+		log.Printf("cannot assemble upload %d: %v", upload.ID, err)
+		return errors.New("something went wrong")
+	}
+	media, err := h.fui.ProcessUpload(upload.Key, upload.ContentType, upload.MaxSize)
+	if errors.Is(err, fileupload.ErrScanUnavailable) {
+		// the joined file is kept, the upload is finished again once the scanner is back
+		return err
+	}
+	if err != nil {
+		if err := h.upAi.DeleteUpload(upload.ID); err != nil {
+			log.Printf("cannot delete upload %d: %v", upload.ID, err)
+		}
+		return err
+	}
+	upload.Confirm(media)
+	if err := h.upAi.UpdateUpload(upload); err != nil {
+		log.Printf("cannot confirm upload %d: %v", upload.ID, err)
+		return errors.New("something went wrong")
+	}
+	return nil
+}
+
+// remove deletes the upload with its chunks, or its image and its video once confirmed.
+// The upload goes first, a confirmed upload is one of the users of its files.
+func (h *TusHandler) remove(upload *entity.Upload) {
+	if err := h.upAi.DeleteUpload(upload.ID); err != nil {
+		log.Printf("cannot delete upload %d: %v", upload.ID, err)
+		return
+	}
+	files := upload.ChunkKeys()
+	if upload.Status != entity.UploadStatusPending {
+		files = upload.Files()
+	}
+	for _, file := range files {
+		if err := h.fui.DeleteFile(file); err != nil {
+			log.Printf("cannot delete %s of upload %d: %v", file, upload.ID, err)
+		}
+	}
+}
+
+// ownUpload returns the resumable upload of the url. The uploads of other users are not found,
+// and the expired ones are gone, they are removed on the way.
+func (h *TusHandler) ownUpload(c *gin.Context) (*entity.Upload, bool) {
+	if !tusRequest(c) {
+		return nil, false
+	}
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	uploadId, err := strconv.ParseUint(c.Param("upload_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return nil, false
+	}
+	upload, err := h.upAi.GetUpload(uploadId)
+	if err != nil || upload.UserID != uId || !upload.Resumable {
+		c.JSON(http.StatusNotFound, "upload not found")
+		return nil, false
+	}
+	if upload.IsExpired(time.Now()) {
+		h.remove(upload)
+		c.JSON(http.StatusGone, "the upload expired")
+		return nil, false
+	}
+	return upload, true
+}
+
+// tusRequest checks the version of the protocol the client speaks
+func tusRequest(c *gin.Context) bool {
+	c.Header("Tus-Resumable", tusVersion)
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.JSON(http.StatusPreconditionFailed, "the Tus-Resumable header should be "+tusVersion)
+		return false
+	}
+	return true
+}
+
+func tusOffsetHeaders(c *gin.Context, upload *entity.Upload) {
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if upload.Status == entity.UploadStatusPending {
+		c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// parseTusMetadata reads the Upload-Metadata header, comma separated keys each followed by its value in base64,
+// e.g "filename cGFuY2FrZXMuanBn,filetype aW1hZ2UvanBlZw=="
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.Fields(pair)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, errors.New("invalid Upload-Metadata")
+		}
+		if _, ok := metadata[parts[0]]; ok {
+			return nil, fmt.Errorf("the Upload-Metadata key %s is given twice", parts[0])
+		}
+		metadata[parts[0]] = ""
+		if len(parts) == 2 {
+			value, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("the Upload-Metadata value of %s should be base64", parts[0])
+			}
+			metadata[parts[0]] = string(value)
+		}
+	}
+	return metadata, nil
+}
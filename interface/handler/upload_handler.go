@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"fmt"
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/auth"
+	"learning-golang-ddd/interface/fileupload"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UploadHandler struct {
+	upAi application.UploadAppInterface
+	fui  fileupload.UploadFileInterface
+	ai   auth.AuthInterface
+	ti   auth.TokenInterface
+}
+
+// UploadHandler constructor
+func NewUploadHandler(
+	upAi application.UploadAppInterface,
+	fui fileupload.UploadFileInterface,
+	ai auth.AuthInterface,
+	ti auth.TokenInterface,
+) *UploadHandler {
+	return &UploadHandler{
+		upAi: upAi,
+		fui:  fui,
+		ai:   ai,
+		ti:   ti,
+	}
+}
+
+// SaveUpload hands out a presigned url the client PUTs the image, or the video, to, straight to the storage:
+// {"content_type": "image/jpeg", "size": 1048576}. The size is the most the file can weigh.
+// Once uploaded, ConfirmUpload checks the file, and its id can then be given as the upload_id of a food, or of a clip.
+func (h *UploadHandler) SaveUpload(c *gin.Context) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var input struct {
+		ContentType string `json:"content_type"`
+		Size        int64  `json:"size"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"invalid_json": "invalid json",
+		})
+		return
+	}
+	upload, err := entity.NewUpload(uId, input.ContentType, input.Size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, "cannot create the upload")
+		return
+	}
+	uploadErr := upload.Validate()
+	if len(uploadErr) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, uploadErr)
+		return
+	}
+	uploadURL, err := h.fui.PresignUpload(upload.Key, upload.ContentType, entity.UploadURLTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, "cannot create the upload url")
+		return
+	}
+	savedUpload, saveErr := h.upAi.SaveUpload(upload)
+	if saveErr != nil {
+		c.JSON(http.StatusInternalServerError, saveErr)
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/uploads/%d", savedUpload.ID))
+	c.JSON(http.StatusCreated, gin.H{
+		"upload":     savedUpload.SignURLs(h.fui.MediaURL),
+		"upload_url": uploadURL,
+		"method":     http.MethodPut,
+		// the file has to be sent with this content type, or it is refused when confirmed
+		"headers": gin.H{"Content-Type": savedUpload.ContentType},
+	})
+}
+
+func (h *UploadHandler) GetUpload(c *gin.Context) {
+	upload, ok := h.ownUpload(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, upload.SignURLs(h.fui.MediaURL))
+}
+
+// ConfirmUpload checks that the file was uploaded, that it is not larger than announced, and that it is a valid image or video.
+// The image is then resized like the ones sent to SaveFood, a video gets a poster. Confirming twice returns the upload as it is.
+func (h *UploadHandler) ConfirmUpload(c *gin.Context) {
+	upload, ok := h.ownUpload(c)
+	if !ok {
+		return
+	}
+	if upload.Status != entity.UploadStatusPending {
+		c.JSON(http.StatusOK, upload.SignURLs(h.fui.MediaURL))
+		return
+	}
+	media, err := h.fui.ProcessUpload(upload.Key, upload.ContentType, upload.MaxSize)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"upload_error": err.Error(),
+		})
+		return
+	}
+	upload.Confirm(media)
+	if err := h.upAi.UpdateUpload(upload); err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, upload.SignURLs(h.fui.MediaURL))
+}
+
+// ownUpload returns the upload of the url, the uploads of other users are not found
+func (h *UploadHandler) ownUpload(c *gin.Context) (*entity.Upload, bool) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	uploadId, err := strconv.ParseUint(c.Param("upload_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "invalid request")
+		return nil, false
+	}
+	upload, err := h.upAi.GetUpload(uploadId)
+	if err != nil || upload.UserID != uId {
+		c.JSON(http.StatusNotFound, "upload not found")
+		return nil, false
+	}
+	return upload, true
+}
+
+// attachUpload returns the image of a confirmed upload, given as the upload_id field of a form,
+// and marks the upload as used
+func attachUpload(upAi application.UploadAppInterface, userId uint64, rawId string) (string, map[string]string) {
+	uploadId, err := strconv.ParseUint(rawId, 10, 64)
+	if err != nil {
+		return "", map[string]string{"invalid_upload": "upload_id should be the id of an upload"}
+	}
+	upload, attachErr := upAi.AttachUpload(uploadId, userId, entity.MediaImage)
+	if attachErr != nil {
+		return "", attachErr
+	}
+	return upload.ImagePath, nil
+}
+
+// detachUpload gives back the upload of rawId when what it was attached to could not be saved
+func detachUpload(upAi application.UploadAppInterface, rawId string) {
+	uploadId, err := strconv.ParseUint(rawId, 10, 64)
+	if err != nil {
+		return
+	}
+	if err := upAi.DetachUpload(uploadId); err != nil {
+		log.Printf("cannot detach upload %d: %v", uploadId, err)
+	}
+}
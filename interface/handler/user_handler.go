@@ -81,3 +81,78 @@ func (h *UsersHandler) GetUser(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, user.PublicUser())
 }
+
+// DeleteUser moves the user and their foods to the trash. Users can delete themselves, admins can delete anyone.
+func (h *UsersHandler) DeleteUser(c *gin.Context) {
+	requester, ok := h.authenticatedUser(c)
+	if !ok {
+		return
+	}
+	uId, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	if uint64(requester.ID) != uId && !requester.IsAdmin {
+		c.JSON(http.StatusUnauthorized, "you can only delete your own account")
+		return
+	}
+	if _, err := h.uAi.GetUser(uId); err != nil {
+		c.JSON(http.StatusNotFound, "user not found")
+		return
+	}
+	err = h.uAi.DeleteUser(uId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, "user deleted")
+}
+
+// RestoreUser brings back a user from the trash, with the foods that were deleted along with them. Admins only.
+func (h *UsersHandler) RestoreUser(c *gin.Context) {
+	requester, ok := h.authenticatedUser(c)
+	if !ok {
+		return
+	}
+	if !requester.IsAdmin {
+		c.JSON(http.StatusUnauthorized, "only admins can restore users")
+		return
+	}
+	uId, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	err = h.uAi.RestoreUser(uId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+	user, err := h.uAi.GetUser(uId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, user.PublicUser())
+}
+
+// authenticatedUser loads the user making the request. When it returns false, the response was already written.
+func (h *UsersHandler) authenticatedUser(c *gin.Context) (*entity.User, bool) {
+	metadata, err := h.ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	uId, err := h.ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "unauthorized")
+		return nil, false
+	}
+	user, err := h.uAi.GetUser(uId)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, "user not found, unauthorized")
+		return nil, false
+	}
+	return user, true
+}
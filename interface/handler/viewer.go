@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"learning-golang-ddd/infrastructure/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// viewerId returns the id of the authenticated user, or 0 for anonymous requests.
+// It is used on public routes whose response depends on who is asking.
+func viewerId(c *gin.Context, ti auth.TokenInterface, ai auth.AuthInterface) uint64 {
+	if auth.ExtractToken(c.Request) == "" {
+		return 0
+	}
+	metadata, err := ti.ExtractTokenMetadata(c.Request)
+	if err != nil {
+		return 0
+	}
+	uId, err := ai.FetchAuth(metadata.TokenUuid)
+	if err != nil {
+		return 0
+	}
+	return uId
+}
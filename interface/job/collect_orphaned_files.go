@@ -0,0 +1,137 @@
+package job
+
+import (
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/storage"
+	"learning-golang-ddd/interface/fileupload"
+	"log"
+	"strings"
+	"time"
+)
+
+// OrphanReport is what a run of CollectOrphanedFiles found
+type OrphanReport struct {
+	DryRun  bool
+	Scanned int
+	Orphans []storage.ObjectInfo
+	Bytes   int64
+	Deleted int
+}
+
+// CollectOrphanedFiles compares the stored files with what the database still uses, and deletes the files nothing uses:
+// e.g the image of a food that failed to save, a raw upload never confirmed, or an archive whose export is gone.
+// The files younger than the grace period are left alone, they may belong to a request still running.
+// In dry run, the orphans are only reported.
+type CollectOrphanedFiles struct {
+	gAi    application.GalleryAppInterface
+	upAi   application.UploadAppInterface
+	eAi    application.DataExportAppInterface
+	fui    fileupload.UploadFileInterface
+	grace  time.Duration
+	dryRun bool
+}
+
+func NewCollectOrphanedFiles(
+	gAi application.GalleryAppInterface,
+	upAi application.UploadAppInterface,
+	eAi application.DataExportAppInterface,
+	fui fileupload.UploadFileInterface,
+	grace time.Duration,
+	dryRun bool,
+) *CollectOrphanedFiles {
+	return &CollectOrphanedFiles{
+		gAi:    gAi,
+		upAi:   upAi,
+		eAi:    eAi,
+		fui:    fui,
+		grace:  grace,
+		dryRun: dryRun,
+	}
+}
+
+func (j *CollectOrphanedFiles) Run(now time.Time) error {
+	report, err := j.Collect(now)
+	if err != nil {
+		return err
+	}
+	if j.dryRun {
+		for _, orphan := range report.Orphans {
+			log.Printf("orphaned file %s, %d bytes, last modified %s", orphan.Key, orphan.Size, orphan.ModTime.Format(time.RFC3339))
+		}
+		log.Printf("dry run: found %d orphaned files out of %d, %d bytes", len(report.Orphans), report.Scanned, report.Bytes)
+		return nil
+	}
+	if len(report.Orphans) > 0 {
+		log.Printf("deleted %d of %d orphaned files out of %d, %d bytes", report.Deleted, len(report.Orphans), report.Scanned, report.Bytes)
+	}
+	return nil
+}
+
+// Collect lists the stored files and deletes the orphans, unless in dry run
+func (j *CollectOrphanedFiles) Collect(now time.Time) (*OrphanReport, error) {
+	report := &OrphanReport{DryRun: j.dryRun}
+	before := now.Add(-j.grace)
+	// the renditions of an image share its references, they are counted once
+	used := map[string]bool{}
+	err := j.fui.ListFiles("", func(file storage.ObjectInfo) error {
+		report.Scanned++
+		if file.ModTime.After(before) {
+			return nil
+		}
+		orphan, err := j.isOrphan(file.Key, now, used)
+		if err != nil || !orphan {
+			return err
+		}
+		report.Orphans = append(report.Orphans, file)
+		report.Bytes += file.Size
+		if j.dryRun {
+			return nil
+		}
+		// DeleteFile counts the references of the image again, one used in the meantime is kept with all its renditions
+		if err := j.fui.DeleteFile(j.fui.URL(entity.ImageSetKey(file.Key))); err != nil {
+			log.Printf("cannot delete orphaned file %s: %v", file.Key, err)
+			return nil
+		}
+		report.Deleted++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// isOrphan tells from the key what kind of file it is, and whether the database still uses it
+func (j *CollectOrphanedFiles) isOrphan(key string, now time.Time, used map[string]bool) (bool, error) {
+	switch {
+	case strings.HasPrefix(key, "incoming/"):
+		// a raw upload, or a chunk of a resumable one, is used until its upload is confirmed or expires
+		uploadKey := key
+		if i := strings.LastIndex(key, ".part"); i >= 0 {
+			uploadKey = key[:i]
+		}
+		upload, err := j.upAi.GetUploadByKey(uploadKey)
+		if err != nil {
+			return false, err
+		}
+		return upload == nil || upload.Status != entity.UploadStatusPending || upload.IsExpired(now), nil
+	case strings.HasPrefix(key, "exports/"):
+		export, err := j.eAi.GetDataExportByPath(key)
+		if err != nil {
+			return false, err
+		}
+		return export == nil, nil
+	}
+
+	imageKey := entity.ImageSetKey(key)
+	if isUsed, ok := used[imageKey]; ok {
+		return !isUsed, nil
+	}
+	refs, err := j.gAi.CountImageRefsByKey(imageKey)
+	if err != nil {
+		return false, err
+	}
+	used[imageKey] = refs > 0
+	return refs == 0, nil
+}
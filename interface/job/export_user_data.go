@@ -0,0 +1,240 @@
+package job
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/interface/fileupload"
+	"log"
+	"os"
+	"path"
+	"time"
+)
+
+// exportProgressInterval is how often a running export saves its progress, well within entity.RunningExportTimeout
+const exportProgressInterval = time.Minute
+
+// ExportUserData builds the pending data exports: a zip archive with the user's profile, foods, favorites,
+// collections and the images and clips of their foods. The expired archives are deleted on each run.
+type ExportUserData struct {
+	eAi  application.DataExportAppInterface
+	uAi  application.UserAppInterface
+	fAi  application.FoodAppInterface
+	faAi application.FavoriteAppInterface
+	cAi  application.CollectionAppInterface
+	fui  fileupload.UploadFileInterface
+	ttl  time.Duration
+}
+
+func NewExportUserData(
+	eAi application.DataExportAppInterface,
+	uAi application.UserAppInterface,
+	fAi application.FoodAppInterface,
+	faAi application.FavoriteAppInterface,
+	cAi application.CollectionAppInterface,
+	fui fileupload.UploadFileInterface,
+	ttl time.Duration,
+) *ExportUserData {
+	return &ExportUserData{
+		eAi:  eAi,
+		uAi:  uAi,
+		fAi:  fAi,
+		faAi: faAi,
+		cAi:  cAi,
+		fui:  fui,
+		ttl:  ttl,
+	}
+}
+
+func (j *ExportUserData) Run(now time.Time) error {
+	expired, err := j.eAi.DeleteExpiredDataExports(now)
+	if err != nil {
+		return err
+	}
+	for _, export := range expired {
+		if export.Path == "" {
+			continue
+		}
+		if err := j.fui.DeleteFile(export.Path); err != nil {
+			log.Printf("cannot delete the archive of export %d: %v", export.ID, err)
+		}
+	}
+
+	for {
+		export, err := j.eAi.ClaimDataExport()
+		if err != nil {
+			return err
+		}
+		if export == nil {
+			return nil
+		}
+		archivePath, err := j.build(export)
+		if err != nil {
+			log.Printf("cannot build export %d: %v", export.ID, err)
+			export.Fail("the export could not be built, please try again", j.ttl)
+		} else {
+			export.Ready(archivePath, j.ttl)
+		}
+		err = j.eAi.UpdateDataExport(export)
+		if err != nil && export.Status == entity.DataExportStatusReady {
+			log.Printf("cannot save export %d: %v", export.ID, err)
+			// the archive cannot be downloaded without its export
+			if err := j.fui.DeleteFile(archivePath); err != nil {
+				log.Printf("cannot delete the archive of export %d: %v", export.ID, err)
+			}
+			export.Path = ""
+			export.Fail("the export could not be built, please try again", j.ttl)
+			err = j.eAi.UpdateDataExport(export)
+		}
+		if err != nil {
+			// it is built again once RunningExportTimeout passed, see ClaimDataExport
+			log.Printf("cannot save export %d: %v", export.ID, err)
+		}
+	}
+}
+
+// build writes the archive to a temporary file, then stores it privately and returns its path
+func (j *ExportUserData) build(export *entity.DataExport) (string, error) {
+	user, err := j.uAi.GetUser(export.UserID)
+	if err != nil {
+		return "", err
+	}
+	foods, err := j.fAi.GetFoodsByUser(export.UserID)
+	if err != nil {
+		return "", err
+	}
+	trash, err := j.fAi.GetDeletedFoodsByUser(export.UserID)
+	if err != nil {
+		return "", err
+	}
+	favorites, err := j.faAi.GetFavoritesByUser(export.UserID)
+	if err != nil {
+		return "", err
+	}
+	collections, err := j.cAi.GetCollectionsByUser(export.UserID, false)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := ioutil.TempFile("", "export-*.zip")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	archive := zip.NewWriter(tmp)
+	documents := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user.ExportProfile()},
+		{"foods.json", foods},
+		{"trash.json", trash},
+		{"favorites.json", favorites},
+		{"collections.json", collections},
+	}
+	for _, document := range documents {
+		if err := writeJSON(archive, document.name, document.data); err != nil {
+			return "", err
+		}
+	}
+	// a file that cannot be read does not fail the export, it is listed instead
+	missing := []string{}
+	lastSaved := time.Now()
+	for _, food := range append(foods, trash...) {
+		for i, file := range foodMedia(&food) {
+			if time.Since(lastSaved) > exportProgressInterval {
+				// the saved progress tells the other instances the export is still running
+				if err := j.eAi.UpdateDataExport(export); err != nil {
+					log.Printf("cannot save the progress of export %d: %v", export.ID, err)
+				}
+				lastSaved = time.Now()
+			}
+			// the resized images all end with the name of their rendition, they are numbered to stay apart
+			name := fmt.Sprintf("%s/%d/%d-%s", file.dir, food.ID, i+1, path.Base(file.path))
+			if err := j.copyFile(archive, name, file.path); err != nil {
+				log.Printf("cannot add file %s to export %d: %v", file.path, export.ID, err)
+				missing = append(missing, file.path)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		if err := writeJSON(archive, "missing_images.json", missing); err != nil {
+			return "", err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return "", err
+	}
+
+	info, err := tmp.Stat()
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	archivePath := fmt.Sprintf("exports/%d/%d-%s.zip", export.UserID, export.ID, export.Token[:8])
+	if err := j.fui.SaveFile(archivePath, tmp, info.Size(), "application/zip"); err != nil {
+		return "", err
+	}
+	return archivePath, nil
+}
+
+func (j *ExportUserData) copyFile(archive *zip.Writer, name string, filePath string) error {
+	file, err := j.fui.GetFile(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// the images and the videos are already compressed
+	w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, file)
+	return err
+}
+
+func writeJSON(archive *zip.Writer, name string, data interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+// exportedFile is a stored file of a food and the directory of the archive it is written to
+type exportedFile struct {
+	dir  string
+	path string
+}
+
+// foodMedia returns the cover, the gallery images, and the clips with their posters of the food, each once.
+// The videos go to the clips directory, the images to the images one.
+func foodMedia(food *entity.Food) []exportedFile {
+	seen := map[string]bool{}
+	files := []exportedFile{}
+	add := func(dir string, filePath string) {
+		if filePath != "" && !seen[filePath] {
+			seen[filePath] = true
+			files = append(files, exportedFile{dir: dir, path: filePath})
+		}
+	}
+	add("images", food.FoodImage)
+	for _, image := range food.Images {
+		add("images", image.Path)
+	}
+	for _, clip := range food.Clips {
+		add("clips", clip.Path)
+		add("images", clip.PosterPath)
+	}
+	return files
+}
@@ -0,0 +1,193 @@
+package job
+
+import (
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/event"
+	"learning-golang-ddd/interface/fileupload"
+	"learning-golang-ddd/interface/foodimport"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importProgressInterval is how often a running import saves its progress, well within entity.RunningImportTimeout
+const importProgressInterval = time.Minute
+
+// ImportFood runs the pending food imports, one row at a time.
+// A row that fails is reported with the import and does not stop the other rows.
+type ImportFood struct {
+	iAi application.FoodImportAppInterface
+	fAi application.FoodAppInterface
+	gAi application.GalleryAppInterface
+	fui fileupload.UploadFileInterface
+	pi  event.PublisherInterface
+	mAi application.ModerationAppInterface
+}
+
+func NewImportFood(
+	iAi application.FoodImportAppInterface,
+	fAi application.FoodAppInterface,
+	gAi application.GalleryAppInterface,
+	fui fileupload.UploadFileInterface,
+	pi event.PublisherInterface,
+	mAi application.ModerationAppInterface,
+) *ImportFood {
+	return &ImportFood{
+		iAi: iAi,
+		fAi: fAi,
+		gAi: gAi,
+		fui: fui,
+		pi:  pi,
+		mAi: mAi,
+	}
+}
+
+func (j *ImportFood) Run(now time.Time) error {
+	for {
+		foodImport, err := j.iAi.ClaimFoodImport()
+		if err != nil {
+			return err
+		}
+		if foodImport == nil {
+			return nil
+		}
+		j.runImport(foodImport)
+		if err := j.iAi.UpdateFoodImport(foodImport); err != nil {
+			return err
+		}
+		log.Printf("import %d %s: %d imported, %d failed", foodImport.ID, foodImport.Status, foodImport.Imported, foodImport.Failed)
+	}
+}
+
+func (j *ImportFood) runImport(foodImport *entity.FoodImport) {
+	rows, err := foodimport.Parse(foodImport.Format, []byte(foodImport.Payload))
+	if err != nil {
+		// the file was parsed when the import was created, so this should not happen
+		foodImport.RowErrors = []entity.ImportRowError{{Errors: map[string]string{"invalid_file": err.Error()}}}
+		foodImport.Finish(entity.ImportStatusFailed)
+		return
+	}
+	foodImport.Total = len(rows)
+
+	// the titles of the file must be unique among themselves too
+	titles := map[string]int{}
+	lastSaved := time.Now()
+	for i := range rows {
+		if time.Since(lastSaved) > importProgressInterval {
+			// the saved progress tells the other instances the import is still running
+			if err := j.iAi.UpdateFoodImport(foodImport); err != nil {
+				log.Printf("cannot save the progress of import %d: %v", foodImport.ID, err)
+			}
+			lastSaved = time.Now()
+		}
+		row := &rows[i]
+		if len(row.Errors) > 0 {
+			foodImport.AddRowError(row, row.Errors)
+			continue
+		}
+		title := strings.TrimSpace(row.Title)
+		if previous, ok := titles[title]; ok && title != "" {
+			foodImport.AddRowError(row, map[string]string{"unique_title": "food title already used by row " + strconv.Itoa(previous)})
+			continue
+		}
+		titles[title] = row.Row
+
+		if rowErr := j.importRow(foodImport, row); len(rowErr) > 0 {
+			foodImport.AddRowError(row, rowErr)
+			continue
+		}
+		foodImport.Imported++
+	}
+	foodImport.Finish(entity.ImportStatusFinished)
+}
+
+// importRow validates the row and saves it as a food, unless the import is a dry run
+func (j *ImportFood) importRow(foodImport *entity.FoodImport, row *entity.ImportRow) map[string]string {
+	food := entity.Food{}
+	food.UserID = foodImport.UserID
+	food.UpdatedBy = foodImport.UserID
+	food.Title = row.Title
+	food.Description = row.Description
+	food.Ingredients = row.Ingredients
+	for i := range food.Ingredients {
+		food.Ingredients[i].Prepare()
+	}
+	if errs := food.Validate(""); len(errs) > 0 {
+		return errs
+	}
+
+	status := entity.FoodStatus(strings.ToLower(strings.TrimSpace(row.Status)))
+	if status == "" {
+		status = entity.FoodStatusPublished
+	}
+	var publishAt *time.Time
+	if row.PublishAt != "" {
+		parsed, err := time.Parse(time.RFC3339, row.PublishAt)
+		if err != nil {
+			return map[string]string{"invalid_publish_at": "publish_at should be a RFC 3339 date"}
+		}
+		publishAt = &parsed
+	}
+	if errs := food.TransitionTo(status, publishAt); len(errs) > 0 {
+		return errs
+	}
+
+	if row.Image == "" {
+		return map[string]string{"invalid_file": "a valid file is required"}
+	}
+	remote := strings.HasPrefix(row.Image, "http://") || strings.HasPrefix(row.Image, "https://")
+	storageURL := j.fui.URL("")
+	if !remote || (storageURL != "" && strings.HasPrefix(row.Image, storageURL)) {
+		// a reference to an image the user already uploaded to one of their foods
+		imageURL := j.fui.URL(strings.TrimPrefix(row.Image, storageURL))
+		found, err := j.gAi.UserHasImage(foodImport.UserID, imageURL)
+		if err != nil {
+			return map[string]string{"db_error": "database error"}
+		}
+		if !found {
+			return map[string]string{"invalid_file": "the image was not uploaded by you"}
+		}
+		food.FoodImage = imageURL
+		remote = false
+	}
+
+	if foodImport.DryRun {
+		// nothing is saved on a dry run, but the title is still checked
+		taken, err := j.fAi.FoodTitleExists(food.Title)
+		if err != nil {
+			return map[string]string{"db_error": "database error"}
+		}
+		if taken {
+			return map[string]string{"unique_title": "food title already taken"}
+		}
+		return nil
+	}
+
+	if remote {
+		uploadedFile, err := j.fui.UploadFromURL(row.Image)
+		if err != nil {
+			return map[string]string{"upload_error": err.Error()}
+		}
+		food.FoodImage = uploadedFile
+	}
+	savedFood, saveErr := j.fAi.SaveFood(&food)
+	if saveErr != nil {
+		if remote {
+			// the image was only uploaded for this food
+			if err := j.fui.DeleteFile(food.FoodImage); err != nil {
+				log.Printf("cannot delete image %s of import %d: %v", food.FoodImage, foodImport.ID, err)
+			}
+		}
+		return saveErr
+	}
+	j.mAi.AutoFlag(entity.ReportTargetFood, savedFood.ID, savedFood.UserID, savedFood.Title, savedFood.Description)
+	j.fui.SyncImageACL(savedFood.FoodImage)
+	if savedFood.Status == entity.FoodStatusPublished {
+		if err := j.pi.Publish(entity.NewFoodPublishedEvent(savedFood)); err != nil {
+			log.Printf("cannot publish %s event for food %d: %v", entity.EventFoodPublished, savedFood.ID, err)
+		}
+	}
+	return nil
+}
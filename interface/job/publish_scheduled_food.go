@@ -0,0 +1,45 @@
+package job
+
+import (
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
+	"learning-golang-ddd/infrastructure/event"
+	"learning-golang-ddd/interface/fileupload"
+	"log"
+	"time"
+)
+
+// PublishScheduledFood flips the scheduled foods to published once their publish_at has passed
+type PublishScheduledFood struct {
+	fAi application.FoodAppInterface
+	pi  event.PublisherInterface
+	fui fileupload.UploadFileInterface
+}
+
+func NewPublishScheduledFood(fAi application.FoodAppInterface, pi event.PublisherInterface, fui fileupload.UploadFileInterface) *PublishScheduledFood {
+	return &PublishScheduledFood{
+		fAi: fAi,
+		pi:  pi,
+		fui: fui,
+	}
+}
+
+func (j *PublishScheduledFood) Run(now time.Time) error {
+	foods, err := j.fAi.PublishScheduledFoods(now)
+	if err != nil {
+		return err
+	}
+	for i := range foods {
+		food := &foods[i]
+		// the food is already published, a lost event should not make the job fail
+		err := j.pi.Publish(entity.NewFoodPublishedEvent(food))
+		if err != nil {
+			log.Printf("cannot publish %s event for food %d: %v", entity.EventFoodPublished, food.ID, err)
+		}
+		// the images are made public with the food, the gallery is loaded with it
+		if published, err := j.fAi.GetFood(food.ID); err == nil {
+			j.fui.SyncImageACL(published.ImagePaths()...)
+		}
+	}
+	return nil
+}
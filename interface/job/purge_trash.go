@@ -0,0 +1,70 @@
+package job
+
+import (
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/interface/fileupload"
+	"log"
+	"time"
+)
+
+// PurgeTrash permanently deletes the users and foods that have been in the trash for longer
+// than the retention window, along with the images and the clips of the foods
+type PurgeTrash struct {
+	fAi       application.FoodAppInterface
+	uAi       application.UserAppInterface
+	fui       fileupload.UploadFileInterface
+	retention time.Duration
+}
+
+func NewPurgeTrash(
+	fAi application.FoodAppInterface,
+	uAi application.UserAppInterface,
+	fui fileupload.UploadFileInterface,
+	retention time.Duration,
+) *PurgeTrash {
+	return &PurgeTrash{
+		fAi:       fAi,
+		uAi:       uAi,
+		fui:       fui,
+		retention: retention,
+	}
+}
+
+func (j *PurgeTrash) Run(now time.Time) error {
+	before := now.Add(-j.retention)
+
+	users, err := j.uAi.PurgeDeletedUsers(before)
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		log.Printf("purged %d users from the trash", len(users))
+	}
+
+	// foods are purged in batches, keep going until the trash is empty
+	for {
+		foods, err := j.fAi.PurgeDeletedFoods(before)
+		if err != nil {
+			return err
+		}
+		if len(foods) == 0 {
+			return nil
+		}
+		log.Printf("purged %d foods from the trash", len(foods))
+
+		for _, food := range foods {
+			paths := map[string]bool{}
+			for _, path := range food.ImagePaths() {
+				if path != "" {
+					paths[path] = true
+				}
+			}
+			// the rows are already gone, a file that cannot be deleted now is only logged
+			for path := range paths {
+				if err := j.fui.DeleteFile(path); err != nil {
+					log.Printf("cannot delete image %s of purged food %d: %v", path, food.ID, err)
+				}
+			}
+		}
+	}
+}
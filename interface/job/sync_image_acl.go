@@ -0,0 +1,48 @@
+package job
+
+import (
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/interface/fileupload"
+	"log"
+	"time"
+)
+
+// the first run after a start looks this far back, the foods changed while the api was down included
+const imageACLFirstLookback = 24 * time.Hour
+
+// the runs overlap a little, a food saved while the previous run was reading the changes is not missed
+const imageACLOverlap = time.Minute
+
+// SyncImageACL makes the images of the foods changed since its last run public when the food is published,
+// and private otherwise. The acl is set as the foods change, this catches what failed then, e.g when the storage was down.
+type SyncImageACL struct {
+	fAi     application.FoodAppInterface
+	fui     fileupload.UploadFileInterface
+	lastRun time.Time
+}
+
+func NewSyncImageACL(fAi application.FoodAppInterface, fui fileupload.UploadFileInterface) *SyncImageACL {
+	return &SyncImageACL{fAi: fAi, fui: fui}
+}
+
+func (j *SyncImageACL) Run(now time.Time) error {
+	since := now.Add(-imageACLFirstLookback)
+	if !j.lastRun.IsZero() {
+		since = j.lastRun.Add(-imageACLOverlap)
+	}
+	foods, err := j.fAi.GetFoodsChangedSince(since)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for i := range foods {
+		if err := j.fui.SyncImageACL(foods[i].ImagePaths()...); err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		log.Printf("cannot sync the acl of the images of %d foods out of %d", failed, len(foods))
+	}
+	j.lastRun = now
+	return nil
+}
@@ -2,7 +2,9 @@ package middleware
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
+	"learning-golang-ddd/application"
 	"learning-golang-ddd/infrastructure/auth"
 	"net/http"
 
@@ -24,14 +26,43 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// NotBannedMiddleware stops the users banned by the moderators, it goes after AuthMiddleware on the routes
+// that publish or change content. A banned user can still read and delete.
+func NotBannedMiddleware(uAi application.UserAppInterface, ai auth.AuthInterface, ti auth.TokenInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		metadata, err := ti.ExtractTokenMetadata(c.Request)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		uId, err := ai.FetchAuth(metadata.TokenUuid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		user, err := uAi.GetUser(uId)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, "user not found, unauthorized")
+			return
+		}
+		if user.IsBanned() {
+			c.AbortWithStatusJSON(http.StatusForbidden, "you are banned from posting")
+			return
+		}
+		c.Next()
+	}
+}
+
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, If-Match, If-None-Match, If-Modified-Since, If-Range, Range, Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata, Upload-Defer-Length")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, Location, Accept-Ranges, Content-Range, Last-Modified, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Upload-Length, Upload-Offset, Upload-Metadata, Upload-Expires")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, HEAD, PUT, PATCH, DELETE")
 
-		if c.Request.Method == "OPTIONS" {
+		// only the preflight requests stop here, a tus client asks the server what it supports with a plain OPTIONS
+		if c.Request.Method == "OPTIONS" && c.GetHeader("Access-Control-Request-Method") != "" {
 			c.AbortWithStatus(204)
 			return
 		}
@@ -40,7 +71,7 @@ func CORSMiddleware() gin.HandlerFunc {
 }
 
 // Avoid a large file from lading into memory
-// if the file size is greater than 8MB dont allow it to even load into
+// if the request is greater than n bytes dont allow it to even load into
 // memory and waste our time
 func MaxSizeAllowed(n int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -49,7 +80,7 @@ func MaxSizeAllowed(n int64) gin.HandlerFunc {
 		if errRead != nil {
 			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
 				"status":       http.StatusRequestEntityTooLarge,
-				"upload_error": "too large: upload an image less than 8MB",
+				"upload_error": fmt.Sprintf("too large: the request should be less than %dKB", n>>10),
 			})
 			c.Abort()
 			return
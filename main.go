@@ -1,19 +1,35 @@
 package main
 
 import (
+	"context"
+	"learning-golang-ddd/application"
+	"learning-golang-ddd/domain/entity"
 	"learning-golang-ddd/infrastructure/auth"
+	"learning-golang-ddd/infrastructure/event"
+	"learning-golang-ddd/infrastructure/nutrition"
 	"learning-golang-ddd/infrastructure/persistence"
+	"learning-golang-ddd/infrastructure/scanner"
+	"learning-golang-ddd/infrastructure/scheduler"
+	"learning-golang-ddd/infrastructure/storage"
+	"learning-golang-ddd/infrastructure/video"
 	"learning-golang-ddd/interface/fileupload"
 	"learning-golang-ddd/interface/handler"
+	"learning-golang-ddd/interface/job"
 	middleware "learning-golang-ddd/interface/middeware"
 
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
+// the room left in a request for the form fields and the multipart headers sent along its file
+const multipartOverhead = 1 << 20
+
 func init() {
 	// to load our environment variables
 	if err := godotenv.Load(); err != nil {
@@ -51,28 +67,247 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// the bundled dataset is used when no path is given
+	nutritionDataset, err := nutrition.NewDataset(os.Getenv("NUTRITION_DATASET"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	foodApp := application.NewFoodApp(services.Food, nutritionDataset)
+	ratingApp := application.NewRatingApp(services.Rating)
+	reviewApp := application.NewReviewApp(services.Review)
+	commentApp := application.NewCommentApp(services.Comment)
+	favoriteApp := application.NewFavoriteApp(services.Favorite)
+	collectionApp := application.NewCollectionApp(services.Collection)
+	foodRevisionApp := application.NewFoodRevisionApp(services.FoodRevision)
+	galleryApp := application.NewGalleryApp(services.Gallery)
+	foodClipApp := application.NewFoodClipApp(services.FoodClip)
+	foodImportApp := application.NewFoodImportApp(services.FoodImport)
+	dataExportApp := application.NewDataExportApp(services.DataExport)
+	mealPlanApp := application.NewMealPlanApp(services.MealPlan)
+	shoppingListApp := application.NewShoppingListApp(services.ShoppingList, nutritionDataset)
+	// the content containing one of these comma separated words is put in the moderation queue
+	moderationApp := application.NewModerationApp(services.Report, entity.NewKeywordFilter(strings.Split(os.Getenv("MODERATION_KEYWORDS"), ",")))
+	notificationApp := application.NewNotificationApp(services.Notification)
+	uploadApp := application.NewUploadApp(services.Upload)
+
+	// the uploads go to the local disk, the memory or an S3 compatible bucket, see STORAGE_DRIVER
+	store, err := storage.New(storage.ConfigFromEnv())
+	if err != nil {
+		log.Fatal(err)
+	}
+	// the uploads are scanned for malware by clamd, see CLAMD_ADDRESS
+	scan, err := scanner.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the size of the images, see IMAGE_MAX_FILE_SIZE, and of the files uploaded straight to the storage
+	imageLimits, err := fileupload.LimitsFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if importMaxSize, err := strconv.ParseInt(os.Getenv("IMPORT_MAX_SIZE"), 10, 64); err == nil && importMaxSize > 0 {
+		entity.MaxImportSize = importMaxSize
+	}
+	if uploadMaxSize, err := strconv.ParseInt(os.Getenv("UPLOAD_MAX_SIZE"), 10, 64); err == nil && uploadMaxSize > 0 {
+		entity.MaxUploadSize = uploadMaxSize
+	}
+	// the size and the duration of the clips, and the ffmpeg their posters are taken with, see FFMPEG_PATH
+	if videoMaxSize, err := strconv.ParseInt(os.Getenv("VIDEO_MAX_SIZE"), 10, 64); err == nil && videoMaxSize > 0 {
+		entity.MaxVideoSize = videoMaxSize
+	}
+	if videoMaxDuration, err := time.ParseDuration(os.Getenv("VIDEO_MAX_DURATION")); err == nil && videoMaxDuration > 0 {
+		entity.MaxVideoDuration = videoMaxDuration
+	}
+	frames, err := video.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	ti := auth.NewToken()
-	fileUpload := fileupload.NewFileUpload()
+	fileUpload := fileupload.NewFileUpload(store, galleryApp, scan, imageLimits, frames)
+	publisher := event.NewRedisPublisher(redisService.Client)
 
 	users := handler.NewUsersHandler(services.User, redisService.Auth, ti)
-	foods := handler.NewFoodHandler(services.Food, services.User, fileUpload, redisService.Auth, ti)
+	foods := handler.NewFoodHandler(foodApp, services.User, fileUpload, redisService.Auth, ti, publisher, moderationApp, uploadApp)
+	reviews := handler.NewReviewHandler(ratingApp, reviewApp, foodApp, redisService.Auth, ti)
+	comments := handler.NewCommentHandler(commentApp, foodApp, services.User, redisService.Auth, ti, moderationApp)
+	collections := handler.NewCollectionHandler(collectionApp, favoriteApp, foodApp, redisService.Auth, ti, fileUpload)
+	revisions := handler.NewFoodRevisionHandler(foodRevisionApp, foodApp, redisService.Auth, ti, fileUpload)
+	gallery := handler.NewGalleryHandler(galleryApp, foodApp, fileUpload, redisService.Auth, ti, uploadApp)
+	clips := handler.NewFoodClipHandler(foodClipApp, foodApp, uploadApp, fileUpload, redisService.Auth, ti)
+	imports := handler.NewFoodImportHandler(foodImportApp, redisService.Auth, ti)
+	exports := handler.NewDataExportHandler(dataExportApp, fileUpload, redisService.Auth, ti)
+	mealPlans := handler.NewMealPlanHandler(mealPlanApp, foodApp, redisService.Auth, ti, fileUpload)
+	shoppingLists := handler.NewShoppingListHandler(shoppingListApp, foodApp, mealPlanApp, redisService.Auth, ti)
+	moderation := handler.NewModerationHandler(moderationApp, foodApp, commentApp, services.User, redisService.Auth, ti, publisher, fileUpload)
+	notifications := handler.NewNotificationHandler(notificationApp, redisService.Auth, ti)
+	files := handler.NewFileHandler(store)
+	media := handler.NewMediaHandler(store, galleryApp, redisService.Auth, ti)
+	uploads := handler.NewUploadHandler(uploadApp, fileUpload, redisService.Auth, ti)
+	tus := handler.NewTusHandler(uploadApp, fileUpload, redisService.Auth, ti)
 	auth := handler.NewAuthenticateHandler(services.User, redisService.Auth, ti)
 
+	//background jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scheduler.Every(ctx, time.Minute, "publish scheduled foods", job.NewPublishScheduledFood(foodApp, publisher, fileUpload).Run)
+	trashRetention, err := time.ParseDuration(os.Getenv("TRASH_RETENTION"))
+	if err != nil {
+		trashRetention = 30 * 24 * time.Hour
+	}
+	scheduler.Every(ctx, time.Hour, "purge trash", job.NewPurgeTrash(foodApp, services.User, fileUpload, trashRetention).Run)
+
+	scheduler.Every(ctx, 5*time.Second, "import foods", job.NewImportFood(foodImportApp, foodApp, galleryApp, fileUpload, publisher, moderationApp).Run)
+	exportTTL, err := time.ParseDuration(os.Getenv("EXPORT_TTL"))
+	if err != nil {
+		exportTTL = 48 * time.Hour
+	}
+	scheduler.Every(ctx, 10*time.Second, "export user data", job.NewExportUserData(dataExportApp, services.User, foodApp, favoriteApp, collectionApp, fileUpload, exportTTL).Run)
+	orphanGrace, err := time.ParseDuration(os.Getenv("ORPHAN_GC_GRACE"))
+	if err != nil {
+		orphanGrace = 24 * time.Hour
+	}
+	// in dry run, the orphaned files are only logged
+	orphanDryRun, _ := strconv.ParseBool(os.Getenv("ORPHAN_GC_DRY_RUN"))
+	scheduler.Every(ctx, 24*time.Hour, "collect orphaned files", job.NewCollectOrphanedFiles(galleryApp, uploadApp, dataExportApp, fileUpload, orphanGrace, orphanDryRun).Run)
+	scheduler.Every(ctx, time.Hour, "sync image acl", job.NewSyncImageACL(foodApp, fileUpload).Run)
+
 	r := gin.Default()
 	r.Use(middleware.CORSMiddleware()) // For CORS
+	// the users banned by the moderators cannot publish, share, report or change content anymore, they can still delete theirs
+	notBanned := middleware.NotBannedMiddleware(services.User, redisService.Auth, ti)
+	// the requests are bounded by the file they carry, the file itself is checked against its limit by the handler
+	imageBodySize := imageLimits.MaxFileSize + multipartOverhead
+	importBodySize := entity.MaxImportSize + multipartOverhead
 
 	//user routes
 	r.POST("/users", users.SaveUser)
 	r.GET("/users", users.GetUsers)
 	r.GET("/users/:user_id", users.GetUser)
+	r.DELETE("/users/:user_id", middleware.AuthMiddleware(), users.DeleteUser)
+	r.POST("/users/:user_id/restore", middleware.AuthMiddleware(), users.RestoreUser)
+	r.GET("/users/:user_id/collections", collections.GetUserCollections)
+
+	//data export routes
+	r.POST("/users/me/export", middleware.AuthMiddleware(), exports.SaveDataExport)
+	r.GET("/users/me/exports/:export_id", middleware.AuthMiddleware(), exports.GetDataExport)
+	r.GET("/exports/:export_id/download", exports.DownloadDataExport)
 
 	//post routes
-	r.POST("/food", middleware.AuthMiddleware(), middleware.MaxSizeAllowed(8192000), foods.SaveFood)
-	r.PUT("/food/:food_id", middleware.AuthMiddleware(), middleware.MaxSizeAllowed(8192000), foods.UpdateFood)
+	r.POST("/food", middleware.AuthMiddleware(), notBanned, middleware.MaxSizeAllowed(imageBodySize), foods.SaveFood)
+	r.PUT("/food/:food_id", middleware.AuthMiddleware(), notBanned, middleware.MaxSizeAllowed(imageBodySize), foods.UpdateFood)
+	r.PATCH("/food/:food_id", middleware.AuthMiddleware(), notBanned, foods.PatchFood)
 	r.GET("/food/:food_id", foods.GetFoodAndCreator)
 	r.DELETE("/food/:food_id", middleware.AuthMiddleware(), foods.DeleteFood)
+	r.POST("/food/:food_id/status", middleware.AuthMiddleware(), notBanned, foods.UpdateFoodStatus)
+	r.GET("/food/trash", middleware.AuthMiddleware(), foods.GetTrash)
+	r.POST("/food/:food_id/restore", middleware.AuthMiddleware(), notBanned, foods.RestoreFood)
 	r.GET("/food", foods.GetAllFood)
 
+	//import routes
+	r.POST("/food/import", middleware.AuthMiddleware(), notBanned, middleware.MaxSizeAllowed(importBodySize), imports.SaveFoodImport)
+	r.GET("/food/import/:import_id", middleware.AuthMiddleware(), imports.GetFoodImport)
+
+	//gallery routes
+	r.GET("/food/:food_id/images", gallery.GetImages)
+	r.POST("/food/:food_id/images", middleware.AuthMiddleware(), notBanned, middleware.MaxSizeAllowed(imageBodySize), gallery.AddImage)
+	r.PUT("/food/:food_id/images", middleware.AuthMiddleware(), notBanned, gallery.ReorderImages)
+	r.PUT("/food/:food_id/images/:image_id", middleware.AuthMiddleware(), notBanned, gallery.UpdateImage)
+	r.DELETE("/food/:food_id/images/:image_id", middleware.AuthMiddleware(), gallery.DeleteImage)
+
+	//clip routes
+	r.GET("/food/:food_id/clips", clips.GetClips)
+	r.POST("/food/:food_id/clips", middleware.AuthMiddleware(), notBanned, clips.AddClip)
+	r.PUT("/food/:food_id/clips", middleware.AuthMiddleware(), notBanned, clips.ReorderClips)
+	r.PUT("/food/:food_id/clips/:clip_id", middleware.AuthMiddleware(), notBanned, clips.UpdateClip)
+	r.DELETE("/food/:food_id/clips/:clip_id", middleware.AuthMiddleware(), clips.DeleteClip)
+
+	//revision routes
+	r.GET("/food/:food_id/revisions", revisions.GetRevisions)
+	r.GET("/food/:food_id/revisions/:rev/diff", revisions.DiffRevisions)
+	r.POST("/food/:food_id/revisions/:rev/restore", middleware.AuthMiddleware(), notBanned, revisions.RestoreRevision)
+
+	//rating and review routes
+	r.PUT("/food/:food_id/rating", middleware.AuthMiddleware(), notBanned, reviews.RateFood)
+	r.DELETE("/food/:food_id/rating", middleware.AuthMiddleware(), reviews.DeleteRating)
+	r.POST("/food/:food_id/reviews", middleware.AuthMiddleware(), notBanned, reviews.SaveReview)
+	r.GET("/food/:food_id/reviews", reviews.GetReviews)
+	r.PUT("/food/:food_id/reviews/:review_id", middleware.AuthMiddleware(), notBanned, reviews.UpdateReview)
+	r.DELETE("/food/:food_id/reviews/:review_id", middleware.AuthMiddleware(), reviews.DeleteReview)
+
+	//comment routes
+	r.POST("/food/:food_id/comments", middleware.AuthMiddleware(), notBanned, comments.SaveComment)
+	r.GET("/food/:food_id/comments", comments.GetComments)
+	r.PUT("/food/:food_id/comments/:comment_id", middleware.AuthMiddleware(), notBanned, comments.UpdateComment)
+	r.DELETE("/food/:food_id/comments/:comment_id", middleware.AuthMiddleware(), comments.DeleteComment)
+	r.POST("/food/:food_id/comments/:comment_id/hide", middleware.AuthMiddleware(), comments.HideComment)
+	r.POST("/food/:food_id/comments/:comment_id/unhide", middleware.AuthMiddleware(), comments.UnhideComment)
+
+	//favorite and collection routes
+	r.GET("/favorites", middleware.AuthMiddleware(), collections.GetFavorites)
+	r.POST("/food/:food_id/favorite", middleware.AuthMiddleware(), notBanned, collections.SaveFavorite)
+	r.DELETE("/food/:food_id/favorite", middleware.AuthMiddleware(), collections.DeleteFavorite)
+	r.POST("/collections", middleware.AuthMiddleware(), notBanned, collections.SaveCollection)
+	r.GET("/collections/:collection_id", collections.GetCollection)
+	r.PUT("/collections/:collection_id", middleware.AuthMiddleware(), notBanned, collections.UpdateCollection)
+	r.DELETE("/collections/:collection_id", middleware.AuthMiddleware(), collections.DeleteCollection)
+	r.POST("/collections/:collection_id/items", middleware.AuthMiddleware(), notBanned, collections.AddCollectionItem)
+	r.PUT("/collections/:collection_id/items", middleware.AuthMiddleware(), notBanned, collections.ReorderCollectionItems)
+	r.DELETE("/collections/:collection_id/items/:food_id", middleware.AuthMiddleware(), collections.RemoveCollectionItem)
+
+	//meal plan routes
+	r.GET("/meal-plan", middleware.AuthMiddleware(), mealPlans.GetMealPlan)
+	r.POST("/meal-plan/entries", middleware.AuthMiddleware(), notBanned, mealPlans.SaveMealPlanEntry)
+	r.PUT("/meal-plan/entries/:entry_id", middleware.AuthMiddleware(), notBanned, mealPlans.UpdateMealPlanEntry)
+	r.DELETE("/meal-plan/entries/:entry_id", middleware.AuthMiddleware(), mealPlans.DeleteMealPlanEntry)
+	r.POST("/meal-plan/copy", middleware.AuthMiddleware(), notBanned, mealPlans.CopyMealPlanWeek)
+	r.POST("/meal-plan/feed", middleware.AuthMiddleware(), notBanned, mealPlans.SaveCalendarFeed)
+	r.GET("/calendar/:token/meal-plan.ics", mealPlans.GetCalendar)
+
+	//shopping list routes
+	r.POST("/shopping-lists", middleware.AuthMiddleware(), notBanned, shoppingLists.SaveShoppingList)
+	r.GET("/shopping-lists", middleware.AuthMiddleware(), shoppingLists.GetShoppingLists)
+	r.GET("/shopping-lists/:list_id", middleware.AuthMiddleware(), shoppingLists.GetShoppingList)
+	r.DELETE("/shopping-lists/:list_id", middleware.AuthMiddleware(), shoppingLists.DeleteShoppingList)
+	r.POST("/shopping-lists/:list_id/items", middleware.AuthMiddleware(), notBanned, shoppingLists.SaveShoppingListItem)
+	r.PUT("/shopping-lists/:list_id/items/:item_id", middleware.AuthMiddleware(), notBanned, shoppingLists.UpdateShoppingListItem)
+	r.DELETE("/shopping-lists/:list_id/items/:item_id", middleware.AuthMiddleware(), shoppingLists.DeleteShoppingListItem)
+	r.POST("/shopping-lists/:list_id/share", middleware.AuthMiddleware(), notBanned, shoppingLists.ShareShoppingList)
+	r.DELETE("/shopping-lists/:list_id/share", middleware.AuthMiddleware(), shoppingLists.UnshareShoppingList)
+	r.GET("/shared/shopping-lists/:list_id", shoppingLists.GetSharedShoppingList)
+
+	//moderation routes
+	r.POST("/food/:food_id/reports", middleware.AuthMiddleware(), notBanned, moderation.ReportFood)
+	r.POST("/food/:food_id/comments/:comment_id/reports", middleware.AuthMiddleware(), notBanned, moderation.ReportComment)
+	r.GET("/admin/reports", middleware.AuthMiddleware(), moderation.GetReports)
+	r.POST("/admin/reports/:report_id/approve", middleware.AuthMiddleware(), moderation.ApproveReport)
+	r.POST("/admin/reports/:report_id/hide", middleware.AuthMiddleware(), moderation.HideReported)
+	r.POST("/admin/reports/:report_id/ban", middleware.AuthMiddleware(), moderation.BanReported)
+
+	//file routes
+	r.GET("/files/*key", files.GetFile)
+	r.PUT("/files/*key", files.PutFile)
+	r.GET("/media/*key", media.GetMedia)
+	r.HEAD("/media/*key", media.GetMedia)
+
+	//upload routes
+	r.POST("/uploads", middleware.AuthMiddleware(), notBanned, uploads.SaveUpload)
+	r.GET("/uploads/:upload_id", middleware.AuthMiddleware(), uploads.GetUpload)
+	r.POST("/uploads/:upload_id/confirm", middleware.AuthMiddleware(), notBanned, uploads.ConfirmUpload)
+
+	//resumable upload routes, tus 1.0
+	r.OPTIONS("/tus", tus.Options)
+	r.OPTIONS("/tus/:upload_id", tus.Options)
+	r.POST("/tus", middleware.AuthMiddleware(), notBanned, tus.CreateUpload)
+	r.HEAD("/tus/:upload_id", middleware.AuthMiddleware(), tus.GetUploadOffset)
+	r.PATCH("/tus/:upload_id", middleware.AuthMiddleware(), notBanned, tus.PatchUpload)
+	r.DELETE("/tus/:upload_id", middleware.AuthMiddleware(), tus.TerminateUpload)
+
+	//notification routes
+	r.GET("/notifications", middleware.AuthMiddleware(), notifications.GetNotifications)
+	r.POST("/notifications/:notification_id/read", middleware.AuthMiddleware(), notifications.ReadNotification)
+
 	//authentication routes
 	r.POST("/auth/login", auth.Login)
 	r.POST("/auth/logout", auth.Logout)